@@ -108,9 +108,11 @@ func (bs *BlockStatement) String() string {
 }
 
 type AssignStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Keyword string // "let" or "const" when the statement declares a variable, like let x = 1. empty for a plain assignment.
+	Name    *Identifier
+	Type    *TypeAnnotation // nil when the variable isn't annotated, like x = 1 instead of x: int = 1
+	Value   Expression
 }
 
 func (as *AssignStatement) statementNode() {}
@@ -127,7 +129,278 @@ func (as *AssignStatement) Position() token.Position {
 	return *pos
 }
 func (as *AssignStatement) String() string {
-	return fmt.Sprintf("%s = %s", as.Name.String(), as.Value.String())
+	ret := as.Name.String()
+	if as.Keyword != "" {
+		ret = as.Keyword + " " + ret
+	}
+	if as.Type != nil {
+		ret += ": " + as.Type.String()
+	}
+	return fmt.Sprintf("%s = %s", ret, as.Value.String())
+}
+
+// sets a field of a document, like $dest.user.name = "a". maps along the path are created when they don't exist.
+type FieldAssignStatement struct {
+	Token  token.Token // the '=' token
+	Object *Identifier
+	Path   []*Identifier
+	Value  Expression
+}
+
+func (fa *FieldAssignStatement) statementNode() {}
+func (fa *FieldAssignStatement) GetToken() token.Token {
+	return fa.Token
+}
+func (fa *FieldAssignStatement) Position() token.Position {
+	start, _ := getNodePositions(fa.Object)
+	_, end := getNodePositions(fa.Value)
+	return newPosition(start, end)
+}
+func (fa *FieldAssignStatement) String() string {
+	names := []string{fa.Object.String()}
+	for _, name := range fa.Path {
+		names = append(names, name.String())
+	}
+	return fmt.Sprintf("%s = %s", strings.Join(names, "."), fa.Value.String())
+}
+
+//
+
+// pipe name(params) { body } defines a named set of statements that runs against the current document
+type PipeDefinition struct {
+	Token  token.Token // the 'pipe' token
+	Name   *Identifier
+	Params []*Parameter
+	Body   *BlockStatement
+}
+
+func (pd *PipeDefinition) statementNode() {}
+func (pd *PipeDefinition) GetToken() token.Token {
+	return pd.Token
+}
+func (pd *PipeDefinition) Position() token.Position {
+	first, _ := pd.Token.Position.GetPosition()
+	_, last := getNodePositions(pd.Body)
+	return newPosition(first, last)
+}
+func (pd *PipeDefinition) String() string {
+	params := []string{}
+	for _, p := range pd.Params {
+		params = append(params, p.String())
+	}
+	return fmt.Sprintf("pipe %s(%s) { %s }", pd.Name.String(), strings.Join(params, ", "), pd.Body.String())
+}
+
+//
+
+// fn name(params) { body } defines a function that returns the value of its body, and is called like a builtin: name(args)
+type FunctionDefinition struct {
+	Token  token.Token // the 'fn' token
+	Name   *Identifier
+	Params []*Parameter
+	Body   *BlockStatement
+}
+
+func (fd *FunctionDefinition) statementNode() {}
+func (fd *FunctionDefinition) GetToken() token.Token {
+	return fd.Token
+}
+func (fd *FunctionDefinition) Position() token.Position {
+	first, _ := fd.Token.Position.GetPosition()
+	_, last := getNodePositions(fd.Body)
+	return newPosition(first, last)
+}
+func (fd *FunctionDefinition) String() string {
+	params := []string{}
+	for _, p := range fd.Params {
+		params = append(params, p.String())
+	}
+	return fmt.Sprintf("fn %s(%s) { %s }", fd.Name.String(), strings.Join(params, ", "), fd.Body.String())
+}
+
+//
+
+// | name(args) runs a defined pipe as a statement
+type PipeInvocation struct {
+	Token  token.Token // the '|' token
+	Module *Identifier // the alias of the imported file the pipe is from, like norm in | norm.clean(). nil for pipes defined in the same file.
+	Call   *CallExpression
+}
+
+func (pi *PipeInvocation) statementNode() {}
+func (pi *PipeInvocation) GetToken() token.Token {
+	return pi.Token
+}
+func (pi *PipeInvocation) Position() token.Position {
+	first, _ := pi.Token.Position.GetPosition()
+	_, last := getNodePositions(pi.Call)
+	return newPosition(first, last)
+}
+func (pi *PipeInvocation) String() string {
+	if pi.Module != nil {
+		return fmt.Sprintf("| %s.%s", pi.Module.String(), pi.Call.String())
+	}
+	return fmt.Sprintf("| %s", pi.Call.String())
+}
+
+//
+
+// import "path" as alias makes the pipes and constants of another file available as members of alias, like alias.name
+type ImportStatement struct {
+	Token token.Token // the 'import' token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) GetToken() token.Token {
+	return is.Token
+}
+func (is *ImportStatement) Position() token.Position {
+	first, _ := is.Token.Position.GetPosition()
+	_, last := getNodePositions(is.Alias)
+	return newPosition(first, last)
+}
+func (is *ImportStatement) String() string {
+	return fmt.Sprintf("import %q as %s", is.Path.Value, is.Alias.String())
+}
+
+//
+
+// schema $src { fields } declares the fields a document is expected to have
+type SchemaStatement struct {
+	Token      token.Token // the 'schema' token
+	Target     *Identifier // the document the schema describes, like $src
+	Fields     []*SchemaField
+	CloseToken token.Token
+}
+
+func (ss *SchemaStatement) statementNode() {}
+func (ss *SchemaStatement) GetToken() token.Token {
+	return ss.Token
+}
+func (ss *SchemaStatement) Position() token.Position {
+	first, _ := ss.Token.Position.GetPosition()
+	_, last := ss.CloseToken.Position.GetPosition()
+	return newPosition(first, last)
+}
+func (ss *SchemaStatement) String() string {
+	return fmt.Sprintf("schema %s { %s }", ss.Target.String(), joinSchemaFields(ss.Fields))
+}
+
+// a field in a schema, like name: string, or user: { ... } for a map with fields of its own
+type SchemaField struct {
+	Name        *Identifier
+	Optional    bool            // written with a ? after the name, like nickname?: string
+	Type        *TypeAnnotation // nil when the field has nested fields
+	Fields      []*SchemaField
+	Constraints []*SchemaConstraint // rules besides the type, like maxlen(256)
+	EndPos      int
+}
+
+func (sf *SchemaField) GetToken() token.Token { return sf.Name.Token }
+func (sf *SchemaField) Position() token.Position {
+	start, _ := getNodePositions(sf.Name)
+	return newPosition(start, sf.EndPos)
+}
+func (sf *SchemaField) String() string {
+	name := sf.Name.String()
+	if sf.Optional {
+		name += "?"
+	}
+	if sf.Type == nil {
+		return fmt.Sprintf("%s: { %s }", name, joinSchemaFields(sf.Fields))
+	}
+	ret := fmt.Sprintf("%s: %s", name, sf.Type.String())
+	for _, c := range sf.Constraints {
+		ret += " " + c.String()
+	}
+	return ret
+}
+
+// a rule a field's value must follow besides its type, like enum("info", "warn") or maxlen(256)
+type SchemaConstraint struct {
+	Name   *Identifier
+	Args   []Expression
+	EndPos int
+}
+
+func (sc *SchemaConstraint) GetToken() token.Token { return sc.Name.Token }
+func (sc *SchemaConstraint) Position() token.Position {
+	start, _ := getNodePositions(sc.Name)
+	return newPosition(start, sc.EndPos)
+}
+func (sc *SchemaConstraint) String() string {
+	args := []string{}
+	for _, arg := range sc.Args {
+		args = append(args, arg.String())
+	}
+	return fmt.Sprintf("%s(%s)", sc.Name.String(), strings.Join(args, ", "))
+}
+
+func joinSchemaFields(fields []*SchemaField) string {
+	ret := []string{}
+	for _, f := range fields {
+		ret = append(ret, f.String())
+	}
+	return strings.Join(ret, ", ")
+}
+
+//
+
+// a param in a definition, like threshold: int
+type Parameter struct {
+	Name     *Identifier
+	Type     *TypeAnnotation // nil when the param takes any value
+	Default  Expression      // nil for required params, like value in: pipe tag(name, value = "true")
+	Variadic bool            // collects the remaining positional args into an array, like parts in: fn concat(...parts)
+}
+
+func (p *Parameter) GetToken() token.Token { return p.Name.Token }
+func (p *Parameter) Position() token.Position {
+	start, end := getNodePositions(p.Name)
+	if p.Type != nil {
+		_, end = getNodePositions(p.Type)
+	}
+	if p.Default != nil {
+		_, end = getNodePositions(p.Default)
+	}
+	return newPosition(start, end)
+}
+func (p *Parameter) String() string {
+	ret := p.Name.String()
+	if p.Variadic {
+		ret = "..." + ret
+	}
+	if p.Type != nil {
+		ret += ": " + p.Type.String()
+	}
+	if p.Default != nil {
+		ret += " = " + p.Default.String()
+	}
+	return ret
+}
+
+//
+
+// a type written in a script, like int or array<string>
+type TypeAnnotation struct {
+	Token  token.Token // the type name
+	Name   string
+	Elem   *TypeAnnotation // the element type in array<T>, or the value type in map<T>
+	EndPos int
+}
+
+func (ta *TypeAnnotation) GetToken() token.Token { return ta.Token }
+func (ta *TypeAnnotation) Position() token.Position {
+	start, _ := ta.Token.Position.GetPosition()
+	return newPosition(start, ta.EndPos)
+}
+func (ta *TypeAnnotation) String() string {
+	if ta.Elem != nil {
+		return fmt.Sprintf("%s<%s>", ta.Name, ta.Elem.String())
+	}
+	return ta.Name
 }
 
 //
@@ -136,7 +409,7 @@ func (as *AssignStatement) String() string {
 
 type IntegerLiteral struct {
 	Token token.Token
-	Value int
+	Value int64
 }
 
 func (il *IntegerLiteral) expressionNode() {}
@@ -204,6 +477,23 @@ func (b *Boolean) String() string {
 
 //
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode() {}
+func (n *NullLiteral) GetToken() token.Token {
+	return n.Token
+}
+func (n *NullLiteral) Position() token.Position {
+	return n.Token.Position
+}
+func (n *NullLiteral) String() string {
+	return n.Token.Value
+}
+
+//
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -220,6 +510,28 @@ func (s *StringLiteral) String() string {
 
 //
 
+type ArrayLiteral struct {
+	Token    token.Token // the '[' token
+	Elements []Expression
+	EndPos   int
+}
+
+func (al *ArrayLiteral) expressionNode()       {}
+func (al *ArrayLiteral) GetToken() token.Token { return al.Token }
+func (al *ArrayLiteral) Position() token.Position {
+	start, _ := al.Token.Position.GetPosition()
+	return newPosition(start, al.EndPos)
+}
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+//
+
 type ArrowFunctionExpression struct {
 	Token           token.Token
 	Param           *Identifier
@@ -278,8 +590,11 @@ type Argument struct {
 func (a *Argument) expressionNode()       {}
 func (a *Argument) GetToken() token.Token { return a.Token }
 func (a *Argument) Position() token.Position {
-	namePos := a.Name.Position()
-	start, _ := namePos.GetPosition()
+	startPos := a.Value.Position()
+	if a.Name != nil {
+		startPos = a.Name.Position()
+	}
+	start, _ := startPos.GetPosition()
 	valPos := a.Value.Position()
 	_, end := valPos.GetPosition()
 	pos := &token.Position{}
@@ -322,6 +637,26 @@ func (ce *CallExpression) String() string {
 
 //
 
+// value | call(args) passes the value as the first positional argument of the call.
+type PipeExpression struct {
+	Token token.Token // the '|' token
+	Value Expression
+	Call  *CallExpression
+}
+
+func (pe *PipeExpression) expressionNode()       {}
+func (pe *PipeExpression) GetToken() token.Token { return pe.Token }
+func (pe *PipeExpression) Position() token.Position {
+	start, _ := getNodePositions(pe.Value)
+	_, end := getNodePositions(pe.Call)
+	return newPosition(start, end)
+}
+func (pe *PipeExpression) String() string {
+	return fmt.Sprintf("(%s | %s)", pe.Value.String(), pe.Call.String())
+}
+
+//
+
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -357,6 +692,33 @@ func (i *IfExpression) String() string {
 
 //
 
+type TryExpression struct {
+	Token      token.Token
+	Block      *BlockStatement
+	CatchToken token.Token
+	ErrorName  *Identifier // nil when the catch block doesn't bind the error
+	Catch      *BlockStatement
+}
+
+func (t *TryExpression) expressionNode() {}
+func (t *TryExpression) GetToken() token.Token {
+	return t.Token
+}
+func (t *TryExpression) Position() token.Position {
+	first, _ := t.Token.Position.GetPosition()
+	_, last := getNodePositions(t.Catch)
+	return newPosition(first, last)
+}
+func (t *TryExpression) String() string {
+	catch := "catch"
+	if t.ErrorName != nil {
+		catch = fmt.Sprintf("catch %s", t.ErrorName.String())
+	}
+	return fmt.Sprintf("try { %s } %s { %s }", t.Block.String(), catch, t.Catch.String())
+}
+
+//
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -0,0 +1,120 @@
+package ast
+
+import "reflect"
+
+// Inspect traverses the tree depth-first, calling fn on each node before its children.
+// returning false from fn skips the children of that node.
+func Inspect(node Node, fn func(Node) bool) {
+	if isNilNode(node) || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, fn)
+		}
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, fn)
+		}
+	case *ExpressionStatement:
+		Inspect(n.Expression, fn)
+	case *AssignStatement:
+		Inspect(n.Name, fn)
+		Inspect(n.Type, fn)
+		Inspect(n.Value, fn)
+	case *FieldAssignStatement:
+		Inspect(n.Object, fn)
+		for _, name := range n.Path {
+			Inspect(name, fn)
+		}
+		Inspect(n.Value, fn)
+	case *PipeDefinition:
+		Inspect(n.Name, fn)
+		for _, p := range n.Params {
+			Inspect(p, fn)
+		}
+		Inspect(n.Body, fn)
+	case *FunctionDefinition:
+		Inspect(n.Name, fn)
+		for _, p := range n.Params {
+			Inspect(p, fn)
+		}
+		Inspect(n.Body, fn)
+	case *PipeInvocation:
+		Inspect(n.Module, fn)
+		Inspect(n.Call, fn)
+	case *ImportStatement:
+		Inspect(n.Path, fn)
+		Inspect(n.Alias, fn)
+	case *SchemaStatement:
+		Inspect(n.Target, fn)
+		for _, f := range n.Fields {
+			Inspect(f, fn)
+		}
+	case *SchemaField:
+		Inspect(n.Name, fn)
+		Inspect(n.Type, fn)
+		for _, f := range n.Fields {
+			Inspect(f, fn)
+		}
+		for _, c := range n.Constraints {
+			Inspect(c, fn)
+		}
+	case *SchemaConstraint:
+		Inspect(n.Name, fn)
+		for _, arg := range n.Args {
+			Inspect(arg, fn)
+		}
+	case *Parameter:
+		Inspect(n.Name, fn)
+		Inspect(n.Type, fn)
+		Inspect(n.Default, fn)
+	case *TypeAnnotation:
+		Inspect(n.Elem, fn)
+	case *ArrayLiteral:
+		for _, el := range n.Elements {
+			Inspect(el, fn)
+		}
+	case *ArrowFunctionExpression:
+		Inspect(n.Param, fn)
+		Inspect(n.QueryExpression, fn)
+	case *DotAccess:
+		Inspect(n.Object, fn)
+		Inspect(n.Item, fn)
+	case *Argument:
+		Inspect(n.Name, fn)
+		Inspect(n.Value, fn)
+	case *CallExpression:
+		Inspect(n.Name, fn)
+		for _, a := range n.Arguments {
+			Inspect(a, fn)
+		}
+	case *PipeExpression:
+		Inspect(n.Value, fn)
+		Inspect(n.Call, fn)
+	case *IfExpression:
+		Inspect(n.Condition, fn)
+		Inspect(n.Consequence, fn)
+		Inspect(n.Alternative, fn)
+	case *TryExpression:
+		Inspect(n.Block, fn)
+		Inspect(n.ErrorName, fn)
+		Inspect(n.Catch, fn)
+	case *PrefixExpression:
+		Inspect(n.Right, fn)
+	case *InfixExpression:
+		Inspect(n.Left, fn)
+		Inspect(n.Right, fn)
+	}
+}
+
+// catches both nil interfaces and typed nil pointers, like an optional *Identifier that was never set
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
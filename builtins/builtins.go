@@ -0,0 +1,263 @@
+package builtins
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// convenience sets of param types
+var (
+	Number   = []object.ObjectType{object.INTEGER_OBJ, object.UINTEGER_OBJ, object.FLOAT_OBJ, object.DECIMAL_OBJ}
+	String   = []object.ObjectType{object.STRING_OBJ}
+	Integer  = []object.ObjectType{object.INTEGER_OBJ}
+	Boolean  = []object.ObjectType{object.BOOLEAN_OBJ}
+	Array    = []object.ObjectType{object.ARRAY_OBJ}
+	Map      = []object.ObjectType{object.MAP_OBJ}
+	Time     = []object.ObjectType{object.TIME_OBJ}
+	Duration = []object.ObjectType{object.DURATION_OBJ}
+	Func     = []object.ObjectType{object.FUNCTION_OBJ}
+)
+
+type Param struct {
+	Name    string
+	Types   []object.ObjectType // accepted value types; an empty list accepts anything
+	Default object.Object       // nil value for required params
+	// collects every remaining positional arg. only valid on the last param, and can't be passed by name.
+	Variadic bool
+	// optional hook that runs once per string literal passed to this param before any records are evaluated,
+	// so things like regex patterns can be validated and compiled up front instead of on every call.
+	Precompile func(literal string) error
+}
+
+func (p Param) Accepts(obj object.Object) bool {
+	return len(p.Types) == 0 || slices.Contains(p.Types, obj.Type())
+}
+
+func (p Param) IsRequired() bool {
+	return p.Default == nil && !p.Variadic
+}
+
+type BuiltinFunc func(args *Args) (object.Object, error)
+
+type Function struct {
+	Name   string
+	Params []Param
+	// the types a successful call can return, which the static checker uses to infer the call's type.
+	// leave it empty when the result type depends on the args, like abs or min.
+	Returns []object.ObjectType
+	Fn      BuiltinFunc
+}
+
+func (f *Function) Param(name string) (Param, bool) {
+	for _, p := range f.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// returns the trailing variadic param if the function has one
+func (f *Function) VariadicParam() (Param, bool) {
+	if len(f.Params) > 0 && f.Params[len(f.Params)-1].Variadic {
+		return f.Params[len(f.Params)-1], true
+	}
+	return Param{}, false
+}
+
+// describes the function's params for an object.Binder
+func (f *Function) ParamSpecs() []object.ParamSpec {
+	ret := make([]object.ParamSpec, len(f.Params))
+	for idx, p := range f.Params {
+		ret[idx] = object.ParamSpec{Name: p.Name, Variadic: p.Variadic, Required: p.IsRequired()}
+	}
+	return ret
+}
+
+//
+// registry
+//
+
+type Registry struct {
+	functions map[string]*Function
+
+	grok   *grokLibrary  // set when grok is registered, so hosts can add their own patterns
+	tables *lookupTables // set when lookup is registered, so hosts can add their own tables
+}
+
+func NewRegistry() *Registry {
+	return &Registry{functions: make(map[string]*Function)}
+}
+
+func (r *Registry) Register(fn *Function) error {
+	if fn.Name == "" {
+		return fmt.Errorf("register builtin: function name cannot be empty")
+	}
+	if fn.Fn == nil {
+		return fmt.Errorf("register builtin %s: missing function implementation", fn.Name)
+	}
+	if _, found := r.functions[fn.Name]; found {
+		return fmt.Errorf("register builtin %s: a function with that name already exists", fn.Name)
+	}
+	seen := map[string]bool{}
+	for idx, p := range fn.Params {
+		if seen[p.Name] {
+			return fmt.Errorf("register builtin %s: duplicate param name %q", fn.Name, p.Name)
+		}
+		seen[p.Name] = true
+		if p.Variadic && idx != len(fn.Params)-1 {
+			return fmt.Errorf("register builtin %s: variadic param %q must be the last param", fn.Name, p.Name)
+		}
+	}
+	r.functions[fn.Name] = fn
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*Function, bool) {
+	fn, ok := r.functions[name]
+	return fn, ok
+}
+
+// returns a new registry with the core function library registered.
+// hosts can register their own functions on top of it.
+func Default() *Registry {
+	r := NewRegistry()
+	registerStrings(r)
+	registerRegex(r)
+	registerGrok(r)
+	registerTime(r)
+	registerMath(r)
+	registerCollections(r)
+	registerEncoding(r)
+	registerIP(r)
+	registerURL(r)
+	registerUserAgent(r)
+	registerFormats(r)
+	registerSyslog(r)
+	registerCEF(r)
+	registerTypes(r)
+	registerLookup(r)
+	return r
+}
+
+// for registering the core library, where a bad signature is a programming error
+func (r *Registry) mustRegister(fns ...*Function) {
+	for _, fn := range fns {
+		if err := r.Register(fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+//
+// bound arguments
+//
+
+// Args holds the values bound to a function's params for a single call.
+type Args struct {
+	values map[string]object.Object
+	rest   []object.Object
+}
+
+func NewArgs(values map[string]object.Object, rest []object.Object) *Args {
+	return &Args{values: values, rest: rest}
+}
+
+func (a *Args) Get(name string) object.Object {
+	return a.values[name]
+}
+
+// values collected by a variadic param
+func (a *Args) Rest() []object.Object {
+	return a.rest
+}
+
+// typed getters below return the zero value when the arg is missing or of another type.
+// params with declared Types are checked while binding, so these are safe to use for those params.
+
+func (a *Args) String(name string) string {
+	if s, ok := a.values[name].(*object.String); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func (a *Args) Int(name string) int64 {
+	switch v := a.values[name].(type) {
+	case *object.Integer:
+		return v.Value
+	case *object.Float:
+		return int64(v.Value)
+	}
+	return 0
+}
+
+func (a *Args) Float(name string) float64 {
+	return toFloat(a.values[name])
+}
+
+func (a *Args) Bool(name string) bool {
+	if b, ok := a.values[name].(*object.Boolean); ok {
+		return b.Value
+	}
+	return false
+}
+
+func (a *Args) Time(name string) time.Time {
+	if t, ok := a.values[name].(*object.Time); ok {
+		return t.Value
+	}
+	return time.Time{}
+}
+
+func (a *Args) Map(name string) *object.Map {
+	if m, ok := a.values[name].(*object.Map); ok {
+		return m
+	}
+	return nil
+}
+
+func (a *Args) Array(name string) []object.Object {
+	if arr, ok := a.values[name].(*object.Array); ok {
+		return arr.Elements
+	}
+	return nil
+}
+
+func (a *Args) Func(name string) *object.Function {
+	if fn, ok := a.values[name].(*object.Function); ok {
+		return fn
+	}
+	return nil
+}
+
+//
+// object constructors
+//
+
+func newString(val string) *object.String {
+	return &object.String{Value: val}
+}
+
+func newInteger(val int64) *object.Integer {
+	return &object.Integer{Value: val}
+}
+
+func newFloat(val float64) *object.Float {
+	return &object.Float{Value: val}
+}
+
+func newBoolean(val bool) *object.Boolean {
+	return &object.Boolean{Value: val}
+}
+
+func newStringArray(vals []string) *object.Array {
+	elements := make([]object.Object, len(vals))
+	for idx, v := range vals {
+		elements[idx] = newString(v)
+	}
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,73 @@
+package builtins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+func TestRegisterValid(t *testing.T) {
+	r := NewRegistry()
+	fn := &Function{
+		Name: "concat",
+		Params: []Param{
+			{Name: "sep", Types: []object.ObjectType{object.STRING_OBJ}, Default: &object.String{Value: ""}},
+			{Name: "parts", Variadic: true},
+		},
+		Fn: func(args *Args) (object.Object, error) { return nil, nil },
+	}
+	if err := r.Register(fn); err != nil {
+		t.Fatalf("unexpected registration error: %s", err.Error())
+	}
+	got, ok := r.Lookup("concat")
+	if !ok {
+		t.Fatal("expected to find registered function concat")
+	}
+	if _, ok := got.VariadicParam(); !ok {
+		t.Errorf("expected concat to have a variadic param")
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	noop := func(args *Args) (object.Object, error) { return nil, nil }
+	tests := []struct {
+		fn      *Function
+		wantErr string
+	}{
+		{&Function{Name: "", Fn: noop}, "name cannot be empty"},
+		{&Function{Name: "f"}, "missing function implementation"},
+		{&Function{Name: "f", Fn: noop, Params: []Param{{Name: "a"}, {Name: "a"}}}, `duplicate param name "a"`},
+		{&Function{Name: "f", Fn: noop, Params: []Param{{Name: "a", Variadic: true}, {Name: "b"}}}, `variadic param "a" must be the last param`},
+	}
+	for _, tt := range tests {
+		err := NewRegistry().Register(tt.fn)
+		if err == nil {
+			t.Fatalf("expected an error containing %q. got no error", tt.wantErr)
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("expected error to contain %q. got=%s", tt.wantErr, err.Error())
+		}
+	}
+
+	r := NewRegistry()
+	r.Register(&Function{Name: "f", Fn: noop})
+	if err := r.Register(&Function{Name: "f", Fn: noop}); err == nil {
+		t.Errorf("expected an error when registering a duplicate function name. got no error")
+	}
+}
+
+func TestRegexCacheReusesCompiledPattern(t *testing.T) {
+	cache := newRegexCache()
+	if err := cache.precompile(`\d+`); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	first, _ := cache.get(`\d+`)
+	second, _ := cache.get(`\d+`)
+	if first != second {
+		t.Errorf("expected the cached pattern to be reused")
+	}
+	if err := cache.precompile(`(`); err == nil {
+		t.Errorf("expected an error for an invalid pattern. got no error")
+	}
+}
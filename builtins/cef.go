@@ -0,0 +1,178 @@
+package builtins
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+var cefHeaderFields = []string{"device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+var leefHeaderFields = []string{"vendor", "product", "product_version", "event_id"}
+
+// extension values can contain spaces, so a value runs until the next space followed by a key and =
+var cefExtensionKey = regexp.MustCompile(`(?:^|\s)([\w.\[\]-]+)=`)
+
+func registerCEF(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name:    "parse_cef",
+			Params:  []Param{{Name: "value", Types: String}}, // anything before CEF:, like a syslog header, is ignored
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				return parseCEF(args.String("value"))
+			},
+		},
+		&Function{
+			Name:    "parse_leef",
+			Params:  []Param{{Name: "value", Types: String}}, // anything before LEEF:, like a syslog header, is ignored
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				return parseLEEF(args.String("value"))
+			},
+		},
+	)
+}
+
+// like: CEF:0|Vendor|Product|1.0|100|Name|5|src=10.0.0.1 msg=some text
+// returns the header fields along with an extensions map
+func parseCEF(value string) (object.Object, error) {
+	start := strings.Index(value, "CEF:")
+	if start < 0 {
+		return nil, fmt.Errorf("message does not contain a CEF: header")
+	}
+	fields, rest := splitEscaped(value[start+len("CEF:"):], '|', len(cefHeaderFields)+1)
+	if len(fields) < len(cefHeaderFields)+1 {
+		return nil, fmt.Errorf("header has %d of the %d required fields", len(fields), len(cefHeaderFields)+1)
+	}
+
+	ret := object.NewMap()
+	version, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q", fields[0])
+	}
+	ret.Pairs["version"] = newInteger(int64(version))
+	for idx, name := range cefHeaderFields {
+		ret.Pairs[name] = newString(fields[idx+1])
+	}
+	ret.Pairs["extensions"] = parseCEFExtensions(rest)
+	return ret, nil
+}
+
+func parseCEFExtensions(value string) *object.Map {
+	ret := object.NewMap()
+	matches := cefExtensionKey.FindAllStringSubmatchIndex(value, -1)
+	for idx, m := range matches {
+		end := len(value)
+		if idx+1 < len(matches) {
+			end = matches[idx+1][0]
+		}
+		raw := value[m[1]:end]
+		ret.Pairs[value[m[2]:m[3]]] = newString(unescapeCEF(strings.TrimRight(raw, " ")))
+	}
+	return ret
+}
+
+func unescapeCEF(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var sb strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] != '\\' || idx+1 >= len(value) {
+			sb.WriteByte(value[idx])
+			continue
+		}
+		idx++
+		switch value[idx] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(value[idx])
+		}
+	}
+	return sb.String()
+}
+
+// splits on sep until there are count fields, skipping separators escaped with a backslash.
+// returns the unescaped fields and the rest of the value after the last separator.
+func splitEscaped(value string, sep byte, count int) ([]string, string) {
+	fields := []string{}
+	var sb strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		switch {
+		case value[idx] == '\\' && idx+1 < len(value) && (value[idx+1] == sep || value[idx+1] == '\\'):
+			idx++
+			sb.WriteByte(value[idx])
+		case value[idx] == sep:
+			fields = append(fields, sb.String())
+			sb.Reset()
+			if len(fields) == count {
+				return fields, value[idx+1:]
+			}
+		default:
+			sb.WriteByte(value[idx])
+		}
+	}
+	return fields, ""
+}
+
+// like: LEEF:2.0|Vendor|Product|1.0|login|^|src=10.0.0.1^usrName=kim
+// attributes are tab separated in LEEF 1.0, and 2.0 can set its own delimiter as a character or hex code like x09.
+// returns the header fields along with an attributes map.
+func parseLEEF(value string) (object.Object, error) {
+	start := strings.Index(value, "LEEF:")
+	if start < 0 {
+		return nil, fmt.Errorf("message does not contain a LEEF: header")
+	}
+	fields, rest := splitEscaped(value[start+len("LEEF:"):], '|', len(leefHeaderFields)+1)
+	if len(fields) < len(leefHeaderFields)+1 {
+		return nil, fmt.Errorf("header has %d of the %d required fields", len(fields), len(leefHeaderFields)+1)
+	}
+
+	ret := object.NewMap()
+	ret.Pairs["version"] = newString(fields[0])
+	for idx, name := range leefHeaderFields {
+		ret.Pairs[name] = newString(fields[idx+1])
+	}
+
+	delimiter := "\t"
+	if strings.HasPrefix(fields[0], "2") {
+		if field, after, found := strings.Cut(rest, "|"); found && !strings.Contains(field, "=") {
+			d, err := parseLEEFDelimiter(field)
+			if err != nil {
+				return nil, err
+			}
+			delimiter = d
+			rest = after
+		}
+	}
+
+	attributes := object.NewMap()
+	for _, pair := range strings.Split(rest, delimiter) {
+		key, val, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			continue
+		}
+		attributes.Pairs[strings.TrimSpace(key)] = newString(val)
+	}
+	ret.Pairs["attributes"] = attributes
+	return ret, nil
+}
+
+func parseLEEFDelimiter(field string) (string, error) {
+	if len([]rune(field)) == 1 {
+		return field, nil
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(field), "0"), "x")
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid attribute delimiter %q", field)
+	}
+	return string(rune(code)), nil
+}
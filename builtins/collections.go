@@ -0,0 +1,503 @@
+package builtins
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+func registerCollections(r *Registry) {
+	// keys can be passed one by one or as arrays, like pick($src, "a", "b") or pick($src, ["a", "b"])
+	keyNames := Param{Name: "keys", Types: []object.ObjectType{object.STRING_OBJ, object.ARRAY_OBJ}, Variadic: true}
+
+	r.mustRegister(
+		&Function{
+			Name:    "keys",
+			Params:  []Param{{Name: "value", Types: Map}},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				return newStringArray(args.Map("value").Keys()), nil
+			},
+		},
+		&Function{
+			Name:    "values",
+			Params:  []Param{{Name: "value", Types: Map}},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				m := args.Map("value")
+				ret := []object.Object{}
+				for _, k := range m.Keys() {
+					ret = append(ret, m.Pairs[k])
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name:    "entries",
+			Params:  []Param{{Name: "value", Types: Map}},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				m := args.Map("value")
+				ret := []object.Object{}
+				for _, k := range m.Keys() {
+					entry := object.NewMap()
+					entry.Pairs["key"] = newString(k)
+					entry.Pairs["value"] = m.Pairs[k]
+					ret = append(ret, entry)
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name:    "from_entries",
+			Params:  []Param{{Name: "values", Types: Array}}, // {"key": k, "value": v} maps or [k, v] pairs
+			Returns: Map,
+			Fn:      fromEntries,
+		},
+		&Function{
+			Name: "merge",
+			Params: []Param{
+				{Name: "value", Types: Map},
+				{Name: "other", Types: Map}, // wins when both have the same key
+				// merges nested maps key by key instead of replacing them
+				{Name: "deep", Types: Boolean, Default: newBoolean(false)},
+			},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				return mergeMaps(args.Map("value"), args.Map("other"), args.Bool("deep")), nil
+			},
+		},
+		&Function{
+			Name: "flatten",
+			Params: []Param{
+				{Name: "values", Types: Array},
+				{Name: "depth", Types: Integer, Default: newInteger(1)}, // negative flattens every level
+			},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				return &object.Array{Elements: flatten(args.Array("values"), args.Int("depth"))}, nil
+			},
+		},
+		&Function{
+			Name:    "unique",
+			Params:  []Param{{Name: "values", Types: Array}}, // keeps the first of each value
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				seen := map[string]bool{}
+				ret := []object.Object{}
+				for _, el := range args.Array("values") {
+					if key := valueKey(el); !seen[key] {
+						seen[key] = true
+						ret = append(ret, el)
+					}
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name: "sort",
+			Params: []Param{
+				{Name: "values", Types: Array},
+				{Name: "by", Types: Func, Default: &object.Null{}}, // sorts by what the function returns for each value, like: e ~> e.timestamp
+				{Name: "desc", Types: Boolean, Default: newBoolean(false)},
+			},
+			Returns: Array,
+			Fn:      sortValues,
+		},
+		&Function{
+			Name:    "reverse",
+			Params:  []Param{{Name: "value", Types: []object.ObjectType{object.ARRAY_OBJ, object.STRING_OBJ}}},
+			Returns: []object.ObjectType{object.ARRAY_OBJ, object.STRING_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				if s, ok := args.Get("value").(*object.String); ok {
+					runes := []rune(s.Value)
+					slices.Reverse(runes)
+					return newString(string(runes)), nil
+				}
+				ret := slices.Clone(args.Array("value"))
+				slices.Reverse(ret)
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name: "slice",
+			Params: []Param{
+				{Name: "values", Types: Array},
+				{Name: "start", Types: Integer}, // negative counts back from the end
+				// exclusive, and negative counts back from the end. null takes the rest of the array.
+				{Name: "end", Types: []object.ObjectType{object.INTEGER_OBJ, object.NULL_OBJ}, Default: &object.Null{}},
+			},
+			Returns: Array,
+			Fn:      slice,
+		},
+		&Function{
+			Name:    "chunk",
+			Params:  []Param{{Name: "values", Types: Array}, {Name: "size", Types: Integer}},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				size := args.Int("size")
+				if size <= 0 {
+					return nil, fmt.Errorf("size must be greater than 0. got=%d", size)
+				}
+				ret := []object.Object{}
+				for part := range slices.Chunk(args.Array("values"), int(size)) {
+					ret = append(ret, &object.Array{Elements: slices.Clone(part)})
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name:    "zip",
+			Params:  []Param{{Name: "values", Types: Array, Variadic: true}}, // stops at the end of the shortest array
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				arrays := args.Rest()
+				ret := []object.Object{}
+				if len(arrays) == 0 {
+					return &object.Array{Elements: ret}, nil
+				}
+				shortest := len(arrays[0].(*object.Array).Elements)
+				for _, arr := range arrays[1:] {
+					shortest = min(shortest, len(arr.(*object.Array).Elements))
+				}
+				for idx := range shortest {
+					tuple := make([]object.Object, len(arrays))
+					for arrIdx, arr := range arrays {
+						tuple[arrIdx] = arr.(*object.Array).Elements[idx]
+					}
+					ret = append(ret, &object.Array{Elements: tuple})
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name:    "compact",
+			Params:  []Param{{Name: "value", Types: []object.ObjectType{object.ARRAY_OBJ, object.MAP_OBJ}}}, // drops null elements or entries
+			Returns: []object.ObjectType{object.ARRAY_OBJ, object.MAP_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				if m := args.Map("value"); m != nil {
+					ret := object.NewMap()
+					for k, v := range m.Pairs {
+						if v.Type() != object.NULL_OBJ {
+							ret.Pairs[k] = v
+						}
+					}
+					return ret, nil
+				}
+				ret := []object.Object{}
+				for _, el := range args.Array("value") {
+					if el.Type() != object.NULL_OBJ {
+						ret = append(ret, el)
+					}
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+		&Function{
+			Name:    "count_by",
+			Params:  []Param{{Name: "values", Types: Array}, {Name: "by", Types: Func}},
+			Returns: Map,
+			Fn:      countBy,
+		},
+		&Function{
+			Name:    "pick",
+			Params:  []Param{{Name: "value", Types: Map}, keyNames},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				names, err := keyList(args.Rest())
+				if err != nil {
+					return nil, err
+				}
+				m := args.Map("value")
+				ret := object.NewMap()
+				for _, name := range names {
+					if v, ok := m.Pairs[name]; ok {
+						ret.Pairs[name] = v
+					}
+				}
+				return ret, nil
+			},
+		},
+		&Function{
+			Name:    "omit",
+			Params:  []Param{{Name: "value", Types: Map}, keyNames},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				names, err := keyList(args.Rest())
+				if err != nil {
+					return nil, err
+				}
+				ret := object.NewMap()
+				for k, v := range args.Map("value").Pairs {
+					if !slices.Contains(names, k) {
+						ret.Pairs[k] = v
+					}
+				}
+				return ret, nil
+			},
+		},
+		&Function{
+			Name:    "rename_keys",
+			Params:  []Param{{Name: "value", Types: Map}, {Name: "names", Types: Map}}, // maps old names to new ones
+			Returns: Map,
+			Fn:      renameKeys,
+		},
+	)
+}
+
+// identifies equal values for things like unique, where two values are the same if they have the same type and contents.
+// arrays and maps are keyed by the keys of their contents, each prefixed by its length, so ["a, b"] and ["a", "b"] can't share a key.
+func valueKey(obj object.Object) string {
+	var sb strings.Builder
+	writeValueKey(&sb, obj)
+	return sb.String()
+}
+
+func writeValueKey(sb *strings.Builder, obj object.Object) {
+	sb.WriteString(string(obj.Type()))
+	switch obj := obj.(type) {
+	case *object.Array:
+		fmt.Fprintf(sb, "[%d]", len(obj.Elements))
+		for _, el := range obj.Elements {
+			writeValueKey(sb, el)
+		}
+	case *object.Map:
+		fmt.Fprintf(sb, "{%d}", len(obj.Pairs))
+		for _, k := range slices.Sorted(maps.Keys(obj.Pairs)) {
+			fmt.Fprintf(sb, "%d:%s", len(k), k)
+			writeValueKey(sb, obj.Pairs[k])
+		}
+	default:
+		val := obj.Inspect()
+		fmt.Fprintf(sb, "%d:%s", len(val), val)
+	}
+}
+
+// calls a function value with a single arg. a failure inside the function is returned as is so it keeps its own position.
+func callFunc(fn *object.Function, arg object.Object) (object.Object, error) {
+	ret := fn.Call(arg)
+	if errObj, ok := ret.(*object.Error); ok {
+		return nil, errObj
+	}
+	return ret, nil
+}
+
+func fromEntries(args *Args) (object.Object, error) {
+	ret := object.NewMap()
+	for idx, el := range args.Array("values") {
+		var key, value object.Object
+		switch el := el.(type) {
+		case *object.Map:
+			key, value = el.Pairs["key"], el.Pairs["value"]
+		case *object.Array:
+			if len(el.Elements) == 2 {
+				key, value = el.Elements[0], el.Elements[1]
+			}
+		}
+		if key == nil {
+			return nil, fmt.Errorf(`entry at index %d must be a {"key": k, "value": v} map or a [key, value] pair. got=%s`, idx, el.Inspect())
+		}
+		name, ok := key.(*object.String)
+		if !ok {
+			return nil, fmt.Errorf("key of entry at index %d must be a STRING. got=%s", idx, key.Type())
+		}
+		if value == nil {
+			value = &object.Null{}
+		}
+		ret.Pairs[name.Value] = value
+	}
+	return ret, nil
+}
+
+// returns a new map and leaves both inputs untouched
+func mergeMaps(base, other *object.Map, deep bool) *object.Map {
+	ret := object.NewMap()
+	for k, v := range base.Pairs {
+		ret.Pairs[k] = v
+	}
+	for k, v := range other.Pairs {
+		if deep {
+			baseMap, baseIsMap := ret.Pairs[k].(*object.Map)
+			otherMap, otherIsMap := v.(*object.Map)
+			if baseIsMap && otherIsMap {
+				ret.Pairs[k] = mergeMaps(baseMap, otherMap, true)
+				continue
+			}
+		}
+		ret.Pairs[k] = v
+	}
+	return ret
+}
+
+func flatten(values []object.Object, depth int64) []object.Object {
+	ret := []object.Object{}
+	for _, el := range values {
+		if arr, ok := el.(*object.Array); ok && depth != 0 {
+			ret = append(ret, flatten(arr.Elements, depth-1)...)
+			continue
+		}
+		ret = append(ret, el)
+	}
+	return ret
+}
+
+func sortValues(args *Args) (object.Object, error) {
+	values := args.Array("values")
+	keys := values
+	if fn := args.Func("by"); fn != nil {
+		keys = make([]object.Object, len(values))
+		for idx, el := range values {
+			key, err := callFunc(fn, el)
+			if err != nil {
+				return nil, err
+			}
+			keys[idx] = key
+		}
+	}
+
+	order := make([]int, len(values))
+	for idx := range order {
+		order[idx] = idx
+	}
+	var sortErr error
+	slices.SortStableFunc(order, func(a, b int) int {
+		ret, err := compareValues(keys[a], keys[b])
+		if err != nil && sortErr == nil {
+			sortErr = err
+		}
+		if args.Bool("desc") {
+			return -ret
+		}
+		return ret
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	ret := make([]object.Object, len(values))
+	for idx, valueIdx := range order {
+		ret[idx] = values[valueIdx]
+	}
+	return &object.Array{Elements: ret}, nil
+}
+
+// orders numbers, strings, booleans, times and durations against values of the same kind
+func compareValues(a, b object.Object) (int, error) {
+	// every number type converts to an exact rational, so mixed number types compare exactly
+	if aRat, ok := object.ToRat(a); ok {
+		if bRat, ok := object.ToRat(b); ok {
+			return aRat.Cmp(bRat), nil
+		}
+	}
+	switch a := a.(type) {
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return strings.Compare(a.Value, b.Value), nil
+		}
+	case *object.Boolean:
+		if b, ok := b.(*object.Boolean); ok {
+			switch {
+			case a.Value == b.Value:
+				return 0, nil
+			case b.Value:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case *object.Time:
+		if b, ok := b.(*object.Time); ok {
+			return a.Value.Compare(b.Value), nil
+		}
+	case *object.Duration:
+		if b, ok := b.(*object.Duration); ok {
+			return cmp.Compare(a.Value, b.Value), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", a.Type(), b.Type())
+}
+
+func slice(args *Args) (object.Object, error) {
+	values := args.Array("values")
+	resolve := func(idx int64) int {
+		if idx < 0 {
+			idx = max(int64(len(values))+idx, 0)
+		}
+		return int(min(idx, int64(len(values))))
+	}
+	start := resolve(args.Int("start"))
+	end := len(values)
+	if _, ok := args.Get("end").(*object.Integer); ok {
+		end = resolve(args.Int("end"))
+	}
+	if end < start {
+		end = start
+	}
+	return &object.Array{Elements: slices.Clone(values[start:end])}, nil
+}
+
+func countBy(args *Args) (object.Object, error) {
+	ret := object.NewMap()
+	for idx, el := range args.Array("values") {
+		key, err := callFunc(args.Func("by"), el)
+		if err != nil {
+			return nil, err
+		}
+		switch key.Type() {
+		case object.STRING_OBJ, object.INTEGER_OBJ, object.UINTEGER_OBJ, object.FLOAT_OBJ, object.DECIMAL_OBJ, object.BOOLEAN_OBJ, object.NULL_OBJ:
+		default:
+			return nil, fmt.Errorf("key for value at index %d must be a STRING, number, BOOLEAN or NULL. got=%s", idx, key.Type())
+		}
+		count, _ := ret.Pairs[key.Inspect()].(*object.Integer)
+		if count == nil {
+			count = newInteger(0)
+		}
+		ret.Pairs[key.Inspect()] = newInteger(count.Value + 1)
+	}
+	return ret, nil
+}
+
+// flattens the key names passed to pick and omit
+func keyList(values []object.Object) ([]string, error) {
+	ret := []string{}
+	for _, v := range values {
+		switch v := v.(type) {
+		case *object.String:
+			ret = append(ret, v.Value)
+		case *object.Array:
+			for _, el := range v.Elements {
+				s, ok := el.(*object.String)
+				if !ok {
+					return nil, fmt.Errorf("keys must be STRING values. got=%s", el.Type())
+				}
+				ret = append(ret, s.Value)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// renamed keys win over existing keys with the same name
+func renameKeys(args *Args) (object.Object, error) {
+	m := args.Map("value")
+	names := args.Map("names")
+	ret := object.NewMap()
+	for k, v := range m.Pairs {
+		if _, renamed := names.Pairs[k]; !renamed {
+			ret.Pairs[k] = v
+		}
+	}
+	for _, oldName := range names.Keys() {
+		newName, ok := names.Pairs[oldName].(*object.String)
+		if !ok {
+			return nil, fmt.Errorf("new name for %q must be a STRING. got=%s", oldName, names.Pairs[oldName].Type())
+		}
+		if v, found := m.Pairs[oldName]; found {
+			ret.Pairs[newName.Value] = v
+		}
+	}
+	return ret, nil
+}
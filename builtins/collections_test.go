@@ -0,0 +1,110 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func testSource() *object.Map {
+	event := func(status string, ms int64) *object.Map {
+		m := object.NewMap()
+		m.Pairs["status"] = &object.String{Value: status}
+		m.Pairs["ms"] = &object.Integer{Value: ms}
+		return m
+	}
+	geo := object.NewMap()
+	geo.Pairs["country"] = &object.String{Value: "NZ"}
+	geo.Pairs["city"] = &object.String{Value: "Wellington"}
+	override := object.NewMap()
+	override.Pairs["city"] = &object.String{Value: "Auckland"}
+	overrideWrapper := object.NewMap()
+	overrideWrapper.Pairs["geo"] = override
+	overrideWrapper.Pairs["user"] = &object.Null{}
+
+	src := object.NewMap()
+	src.Pairs["geo"] = geo
+	src.Pairs["user"] = &object.String{Value: "kim"}
+	src.Pairs["host"] = &object.String{Value: "web-1"}
+	src.Pairs["override"] = overrideWrapper
+	src.Pairs["events"] = &object.Array{Elements: []object.Object{
+		event("ok", 30), event("fail", 10), event("ok", 20),
+	}}
+	return src
+}
+
+func TestCollectionFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{`keys($src.geo)`, `[city, country]`},
+		{`values($src.geo)`, `[Wellington, NZ]`},
+		{`entries($src.geo)`, `[{"key": city, "value": Wellington}, {"key": country, "value": NZ}]`},
+		{`from_entries(entries($src.geo))`, `{"city": Wellington, "country": NZ}`},
+		{`from_entries([["a", 1], ["b", 2]])`, `{"a": 1, "b": 2}`},
+		{`merge($src.geo, $src.override)`, `{"city": Wellington, "country": NZ, "geo": {"city": Auckland}, "user": null}`},
+		{`pick($src, "geo", "user") | merge($src.override)`, `{"geo": {"city": Auckland}, "user": null}`},
+		{`pick($src, "geo", "user") | merge($src.override, deep: true)`, `{"geo": {"city": Auckland, "country": NZ}, "user": null}`},
+		{`flatten([1, [2, [3, [4]]]])`, `[1, 2, [3, [4]]]`},
+		{`flatten([1, [2, [3, [4]]]], depth: -1)`, `[1, 2, 3, 4]`},
+		{`unique([1, "1", 1, 2.5, 2.5, "a"])`, `[1, 1, 2.5, a]`},
+		{`unique([[1], ["1"], [1]]) | len`, `2`},
+		{`unique([["a, b"], ["a", "b"]]) | len`, `2`},
+		{`unique([parse_json('{"a": [1], "b": "x"}'), parse_json('{"b": "x", "a": [1]}'), parse_json('{"a": ["1"], "b": "x"}')]) | len`, `2`},
+		{`sort([3, 1.5, 2])`, `[1.5, 2, 3]`},
+		{`sort(["b", "c", "a"], desc: true)`, `[c, b, a]`},
+		{`sort($src.events, by: e ~> e.ms) | join(",")`, `{"ms": 10, "status": fail},{"ms": 20, "status": ok},{"ms": 30, "status": ok}`},
+		{`reverse([1, 2, 3])`, `[3, 2, 1]`},
+		{`reverse("abc")`, `cba`},
+		{`slice([1, 2, 3, 4], 1)`, `[2, 3, 4]`},
+		{`slice([1, 2, 3, 4], 1, end: 3)`, `[2, 3]`},
+		{`slice([1, 2, 3, 4], -2)`, `[3, 4]`},
+		{`slice([1, 2, 3, 4], 3, end: 1)`, `[]`},
+		{`chunk([1, 2, 3, 4, 5], 2)`, `[[1, 2], [3, 4], [5]]`},
+		{`zip(["a", "b", "c"], [1, 2])`, `[[a, 1], [b, 2]]`},
+		{`compact([1, null, 2])`, `[1, 2]`},
+		{`compact($src.override)`, `{"geo": {"city": Auckland}}`},
+		{`count_by($src.events, e ~> e.status)`, `{"fail": 1, "ok": 2}`},
+		{`count_by([1, 2, 3], n ~> n > 1)`, `{"false": 1, "true": 2}`},
+		{`omit($src, ["events", "override", "geo"])`, `{"host": web-1, "user": kim}`},
+		{`pick($src, "host", "user") | rename_keys(from_entries([["host", "hostname"], ["missing", "x"]]))`, `{"hostname": web-1, "user": kim}`},
+		{`pick($src, "host", "user") | rename_keys(from_entries([["host", "user"]]))`, `{"user": web-1}`},
+	}
+	for _, tt := range tests {
+		got := evalWithSource(t, tt.input, testSource(), builtins.Default())
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestArrowFunctionClosesOverVariables(t *testing.T) {
+	input := `limit = 15
+	count_by($src.events, e ~> e.ms > limit)`
+	got := evalWithSource(t, input, testSource(), builtins.Default())
+	if isEq, failMsg := testutils.Equal(`{"false": 1, "true": 2}`, got.Inspect()); !isEq {
+		t.Errorf("wrong result: %s", failMsg)
+	}
+}
+
+func TestCollectionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`chunk([1], 0)`, "chunk: size must be greater than 0. got=0"},
+		{`sort([1, "a"])`, "sort: cannot compare STRING with INTEGER"},
+		{`from_entries([1])`, `from_entries: entry at index 0 must be a {"key": k, "value": v} map or a [key, value] pair. got=1`},
+		{`from_entries([[1, 2]])`, "from_entries: key of entry at index 0 must be a STRING. got=INTEGER"},
+		{`count_by([[1]], n ~> n)`, "count_by: key for value at index 0 must be a STRING, number, BOOLEAN or NULL. got=ARRAY"},
+		{`pick($src, [1])`, "pick: keys must be STRING values. got=INTEGER"},
+		// errors inside the arrow body keep their own position
+		{`count_by([1], n ~> n + "a")`, "type mismatch: INTEGER + STRING"},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalWithSource(t, tt.input, testSource(), builtins.Default()), tt.want)
+	}
+}
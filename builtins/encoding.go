@@ -0,0 +1,152 @@
+package builtins
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/url"
+
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/utils/xxhash"
+)
+
+func registerEncoding(r *Registry) {
+	value := Param{Name: "value", Types: String}
+	// use the url and filename safe alphabet (- and _ instead of + and /)
+	urlSafe := Param{Name: "url", Types: Boolean, Default: newBoolean(false)}
+
+	r.mustRegister(
+		&Function{
+			Name:    "base64_encode",
+			Params:  []Param{value, urlSafe},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(base64Encoding(args.Bool("url")).EncodeToString([]byte(args.String("value")))), nil
+			},
+		},
+		&Function{
+			Name:    "base64_decode",
+			Params:  []Param{value, urlSafe}, // padding is optional
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				enc := base64Encoding(args.Bool("url"))
+				if len(args.String("value"))%4 != 0 {
+					enc = enc.WithPadding(base64.NoPadding)
+				}
+				decoded, err := enc.DecodeString(args.String("value"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid base64 input: %w", err)
+				}
+				return newString(string(decoded)), nil
+			},
+		},
+		&Function{
+			Name:    "hex_encode",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(hex.EncodeToString([]byte(args.String("value")))), nil
+			},
+		},
+		&Function{
+			Name:    "hex_decode",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				decoded, err := hex.DecodeString(args.String("value"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid hex input: %w", err)
+				}
+				return newString(string(decoded)), nil
+			},
+		},
+		&Function{
+			Name:    "url_encode",
+			Params:  []Param{value}, // escapes for use in a query string, so spaces become +
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(url.QueryEscape(args.String("value"))), nil
+			},
+		},
+		&Function{
+			Name:    "url_decode",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				decoded, err := url.QueryUnescape(args.String("value"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid url encoded input: %w", err)
+				}
+				return newString(decoded), nil
+			},
+		},
+		// digests are returned as lowercase hex strings
+		&Function{
+			Name:    "md5",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return hexDigest(md5.New(), args.String("value")), nil
+			},
+		},
+		&Function{
+			Name:    "sha1",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return hexDigest(sha1.New(), args.String("value")), nil
+			},
+		},
+		&Function{
+			Name:    "sha256",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return hexDigest(sha256.New(), args.String("value")), nil
+			},
+		},
+		&Function{
+			Name:    "hmac_sha256",
+			Params:  []Param{value, {Name: "key", Types: String}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return hexDigest(hmac.New(sha256.New, []byte(args.String("key"))), args.String("value")), nil
+			},
+		},
+		&Function{
+			Name:    "crc32",
+			Params:  []Param{value}, // IEEE polynomial, returned as an integer
+			Returns: Integer,
+			Fn: func(args *Args) (object.Object, error) {
+				return newInteger(int64(crc32.ChecksumIEEE([]byte(args.String("value"))))), nil
+			},
+		},
+		&Function{
+			Name:    "xxhash",
+			Params:  []Param{value}, // 64 bit xxhash, returned as a 16 character hex string since it doesn't fit in an integer
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				sum := xxhash.Sum64([]byte(args.String("value")), 0)
+				return newString(fmt.Sprintf("%016x", sum)), nil
+			},
+		},
+	)
+}
+
+func base64Encoding(urlSafe bool) *base64.Encoding {
+	if urlSafe {
+		return base64.URLEncoding
+	}
+	return base64.StdEncoding
+}
+
+func hexDigest(h hash.Hash, value string) *object.String {
+	h.Write([]byte(value))
+	return newString(hex.EncodeToString(h.Sum(nil)))
+}
@@ -0,0 +1,51 @@
+package builtins_test
+
+import "testing"
+
+func TestEncodingFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`base64_encode("hello?>")`, "aGVsbG8/Pg=="},
+		{`base64_encode("hello?>", url: true)`, "aGVsbG8_Pg=="},
+		{`base64_decode("aGVsbG8/Pg==")`, "hello?>"},
+		{`base64_decode("aGVsbG8_Pg", url: true)`, "hello?>"},
+		{`"payload" | base64_encode | base64_decode`, "payload"},
+		{`hex_encode("hi!")`, "686921"},
+		{`hex_decode("686921")`, "hi!"},
+		{`url_encode("a b&c=d/é")`, "a+b%26c%3Dd%2F%C3%A9"},
+		{`url_decode("a+b%26c%3Dd%2F%C3%A9")`, "a b&c=d/é"},
+		{`md5("hello")`, "5d41402abc4b2a76b9719d911017c592"},
+		{`sha1("hello")`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{`sha256("hello")`, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{`hmac_sha256("hello", key: "secret")`, "88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b"},
+		// reference values from the xxhash spec
+		{`xxhash("")`, "ef46db3751d8e999"},
+		{`xxhash("a")`, "d24ec4f1a98c6e5b"},
+		{`xxhash("abc")`, "44bc2cf5ad770999"},
+		{`xxhash("Nobody inspects the spammish repetition")`, "fbcea83c8a378bf1"},
+	}
+	for _, tt := range tests {
+		testStringResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	testIntegerResult(t, `crc32("hello")`, evalInput(t, `crc32("hello")`), 907060870)
+}
+
+func TestEncodingErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`base64_decode("***")`, "base64_decode: invalid base64 input: illegal base64 data at input byte 0"},
+		{`hex_decode("zz")`, "hex_decode: invalid hex input: encoding/hex: invalid byte: U+007A 'z'"},
+		{`url_decode("%zz")`, `url_decode: invalid url encoded input: invalid URL escape "%zz"`},
+		{`hmac_sha256("hello")`, `missing required argument "key" for hmac_sha256`},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
@@ -0,0 +1,252 @@
+package builtins
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// the indent is repeated once per nesting level on every line, so it's kept small
+const maxJSONIndent = 16
+
+func registerFormats(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name:   "parse_json",
+			Params: []Param{{Name: "value", Types: String}},
+			Fn: func(args *Args) (object.Object, error) {
+				ret, err := object.DecodeJSON([]byte(args.String("value")))
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSON: %w", err)
+				}
+				return ret, nil
+			},
+		},
+		&Function{
+			Name: "to_json",
+			Params: []Param{
+				{Name: "value"},
+				{Name: "indent", Types: Integer, Default: newInteger(0)}, // spaces per level. 0 writes everything on one line
+			},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				indent := args.Int("indent")
+				if indent < 0 || indent > maxJSONIndent {
+					return nil, fmt.Errorf("indent must be between 0 and %d. got=%d", maxJSONIndent, indent)
+				}
+				encoded, err := object.EncodeJSON(args.Get("value"), strings.Repeat(" ", int(indent)))
+				if err != nil {
+					return nil, err
+				}
+				return newString(string(encoded)), nil
+			},
+		},
+		&Function{
+			Name:    "parse_logfmt",
+			Params:  []Param{{Name: "value", Types: String}},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				return parseLogfmt(args.String("value"))
+			},
+		},
+		&Function{
+			Name:    "to_logfmt",
+			Params:  []Param{{Name: "value", Types: Map}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				m := args.Map("value")
+				pairs := []string{}
+				for _, k := range m.Keys() {
+					if k == "" || strings.ContainsAny(k, " =\"") {
+						return nil, fmt.Errorf("key %q can't be written as logfmt", k)
+					}
+					pairs = append(pairs, k+"="+logfmtValue(m.Pairs[k]))
+				}
+				return newString(strings.Join(pairs, " ")), nil
+			},
+		},
+		&Function{
+			Name: "parse_kv",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "field_sep", Types: String, Default: newString(" ")},
+				{Name: "kv_sep", Types: String, Default: newString("=")},
+			},
+			Returns: Map,
+			Fn:      parseKV,
+		},
+		&Function{
+			Name: "parse_csv_line",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "delimiter", Types: String, Default: newString(",")},
+			},
+			Returns: Array,
+			Fn:      parseCSVLine,
+		},
+	)
+}
+
+// logfmt is space separated key=value pairs, like: level=info msg="hello world" cached.
+// values can be double quoted with backslash escapes, and a key without a value is set to true.
+func parseLogfmt(value string) (object.Object, error) {
+	ret := object.NewMap()
+	runes := []rune(value)
+	idx := 0
+	for idx < len(runes) {
+		if runes[idx] == ' ' || runes[idx] == '\t' {
+			idx++
+			continue
+		}
+
+		start := idx
+		for idx < len(runes) && runes[idx] != '=' && runes[idx] != ' ' && runes[idx] != '\t' {
+			if runes[idx] == '"' {
+				return nil, fmt.Errorf("unexpected quote in key at offset %d", idx)
+			}
+			idx++
+		}
+		key := string(runes[start:idx])
+		if idx >= len(runes) || runes[idx] != '=' {
+			ret.Pairs[key] = newBoolean(true)
+			continue
+		}
+		idx++ // =
+
+		if idx < len(runes) && runes[idx] == '"' {
+			val, end, err := readQuoted(runes, idx)
+			if err != nil {
+				return nil, err
+			}
+			ret.Pairs[key] = newString(val)
+			idx = end
+			continue
+		}
+		start = idx
+		for idx < len(runes) && runes[idx] != ' ' && runes[idx] != '\t' {
+			idx++
+		}
+		ret.Pairs[key] = newString(string(runes[start:idx]))
+	}
+	return ret, nil
+}
+
+// reads a double quoted string starting at runes[start], and returns its unescaped value and the index after the closing quote
+func readQuoted(runes []rune, start int) (string, int, error) {
+	var sb strings.Builder
+	for idx := start + 1; idx < len(runes); idx++ {
+		switch runes[idx] {
+		case '\\':
+			if idx+1 >= len(runes) {
+				continue // reports the missing closing quote below
+			}
+			idx++
+			switch runes[idx] {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			case 'r':
+				sb.WriteRune('\r')
+			default:
+				sb.WriteRune(runes[idx])
+			}
+		case '"':
+			return sb.String(), idx + 1, nil
+		default:
+			sb.WriteRune(runes[idx])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted value starting at offset %d", start)
+}
+
+func logfmtValue(obj object.Object) string {
+	if obj.Type() == object.NULL_OBJ {
+		return ""
+	}
+	val := obj.Inspect()
+	if val == "" || strings.ContainsAny(val, " =\"\\\t\n\r") {
+		return strconv.Quote(val)
+	}
+	return val
+}
+
+// pairs without a kv_sep are skipped. a value wrapped in matching quotes can hold either separator, and is unwrapped.
+func parseKV(args *Args) (object.Object, error) {
+	fieldSep, kvSep := args.String("field_sep"), args.String("kv_sep")
+	if fieldSep == "" || kvSep == "" {
+		return nil, fmt.Errorf("field_sep and kv_sep cannot be empty")
+	}
+	ret := object.NewMap()
+	rest := args.String("value")
+	for rest != "" {
+		field, next, _ := strings.Cut(rest, fieldSep)
+		key, val, found := strings.Cut(field, kvSep)
+		if !found {
+			rest = next
+			continue
+		}
+		// a quoted value can run past the first field_sep
+		if quoted, after, ok := cutQuoted(rest[len(key)+len(kvSep):], fieldSep); ok {
+			val, next = quoted, after
+		} else {
+			val = strings.TrimSpace(val)
+		}
+		rest = next
+		if key = strings.TrimSpace(key); key != "" {
+			ret.Pairs[key] = newString(val)
+		}
+	}
+	return ret, nil
+}
+
+// returns the value inside the quotes s starts with, and what follows the field_sep after them.
+// the closing quote has to end the field, so a quote inside an unquoted value isn't mistaken for one.
+func cutQuoted(s string, fieldSep string) (string, string, bool) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return "", "", false
+	}
+	for idx := 1; idx < len(s); idx++ {
+		if s[idx] != s[0] {
+			continue
+		}
+		tail := s[idx+1:]
+		if strings.TrimSpace(tail) == "" {
+			return s[1:idx], "", true
+		}
+		if after, found := strings.CutPrefix(tail, fieldSep); found {
+			return s[1:idx], after, true
+		}
+		if after, found := strings.CutPrefix(strings.TrimLeft(tail, " \t"), fieldSep); found {
+			return s[1:idx], after, true
+		}
+	}
+	return "", "", false
+}
+
+func parseCSVLine(args *Args) (object.Object, error) {
+	delimiter := args.String("delimiter")
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return nil, fmt.Errorf("delimiter must be a single character. got=%q", delimiter)
+	}
+	reader := csv.NewReader(strings.NewReader(args.String("value")))
+	reader.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	reader.FieldsPerRecord = -1
+	fields, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return newStringArray([]string{}), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if _, err := reader.Read(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("value contains more than one line")
+	}
+	return newStringArray(fields), nil
+}
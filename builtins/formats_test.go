@@ -0,0 +1,70 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestFormatFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{`parse_json('{"user": {"id": 7, "score": 1.5, "tags": ["a", null], "ok": true}}')`, `{"user": {"id": 7, "ok": true, "score": 1.5, "tags": [a, null]}}`},
+		{`parse_json('[1, 2]')`, `[1, 2]`},
+		{`parse_json('99999999999999999999')`, `99999999999999999999`},
+		{`parse_json('{"id": 1541815603606036480, "max": 18446744073709551615, "n": 0.1, "d": 1.00000000000000000001}') | to_json`, `{"d":1.00000000000000000001,"id":1541815603606036480,"max":18446744073709551615,"n":0.1}`},
+		{`parse_json('[9007199254740993, -9223372036854775808, 1e3, 2.50]') | to_json`, `[9007199254740993,-9223372036854775808,1000,2.5]`},
+		{`parse_json('18446744073709551615') | type_of`, `uinteger`},
+		{`parse_json('1.00000000000000000001') | type_of`, `decimal`},
+		{`parse_json('1.5') | type_of`, `float`},
+		{`parse_json('1e400') | type_of`, `decimal`},
+		{`parse_json('1e-500') | type_of`, `float`},
+		{`parse_json('2.5e-1000000')`, `0`},
+		{`parse_json('{"b": [1, "<x>"], "a": null}') | to_json`, `{"a":null,"b":[1,"<x>"]}`},
+		{`to_json(parse_json('{"a": [1]}'), indent: 2)`, "{\n  \"a\": [\n    1\n  ]\n}"},
+		{`to_json(parse_ip("10.0.0.1"))`, `"10.0.0.1"`},
+		{`parse_logfmt('level=info msg="hello \"world\"" cached path=/x empty=')`, `{"cached": true, "empty": , "level": info, "msg": hello "world", "path": /x}`},
+		{`parse_logfmt('level=info msg="a b" n=3') | to_logfmt`, `level=info msg="a b" n=3`},
+		{`to_logfmt(parse_json('{"a": null, "b": "", "c": "x=y"}'))`, `a= b="" c="x=y"`},
+		{`parse_kv("user=kim action=login  status='ok'")`, `{"action": login, "status": ok, "user": kim}`},
+		{`parse_kv("user:kim; action:login; junk", field_sep: ";", kv_sep: ":")`, `{"action": login, "user": kim}`},
+		{`parse_kv('a=1 msg="hello world" b=2')`, `{"a": 1, "b": 2, "msg": hello world}`},
+		{`parse_kv("a='x; y=z' ; b=2", field_sep: ";")`, `{"a": x; y=z, "b": 2}`},
+		{`parse_kv('a="b"c d=1')`, `{"a": "b"c, "d": 1}`},
+		{`parse_kv('a="" b=1')`, `{"a": , "b": 1}`},
+		{`parse_csv_line('a,"b,c",,"say ""hi"""')`, `[a, b,c, , say "hi"]`},
+		{`parse_csv_line("a|b|c", delimiter: "|")`, `[a, b, c]`},
+		{`parse_csv_line("")`, `[]`},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`parse_json('{"a": ')`, "parse_json: invalid JSON: unexpected EOF"},
+		{`parse_json('{} {}')`, "parse_json: invalid JSON: unexpected data after the top-level value"},
+		{`parse_json('1e1000000')`, "parse_json: invalid JSON: number 1e1000000 is out of range"},
+		{`parse_json('1e9999999')`, "parse_json: invalid JSON: number 1e9999999 is out of range"},
+		{`parse_json('-1E+1000000000000000000000')`, "parse_json: invalid JSON: number -1E+1000000000000000000000 is out of range"},
+		{`to_json(x ~> x)`, "to_json: FUNCTION values can't be written as JSON"},
+		{`to_json(1, indent: -1)`, "to_json: indent must be between 0 and 16. got=-1"},
+		{`to_json(1, indent: 9223372036854775807)`, "to_json: indent must be between 0 and 16. got=9223372036854775807"},
+		{`parse_logfmt('msg="unterminated')`, "parse_logfmt: unterminated quoted value starting at offset 4"},
+		{`parse_kv("a=b", kv_sep: "")`, "parse_kv: field_sep and kv_sep cannot be empty"},
+		{`parse_csv_line("a,b", delimiter: "||")`, `parse_csv_line: delimiter must be a single character. got="||"`},
+		{`parse_csv_line('a,"b')`, `parse_csv_line: invalid CSV: parse error on line 1, column 5: extraneous or missing " in quoted-field`},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
@@ -0,0 +1,317 @@
+package builtins
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// matches a pattern reference like %{NAME}, %{NAME:field} or %{NAME:field:int}
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([\w.@\[\]-]+))?(?::(int|float|string))?\}`)
+
+var grokPatternName = regexp.MustCompile(`^\w+$`)
+
+// guards against patterns that reference each other in a loop
+const maxGrokDepth = 32
+
+type grokField struct {
+	name      string
+	groupName string
+	valueType string // "int", "float", or "" for strings
+}
+
+type grokExpression struct {
+	re     *regexp.Regexp
+	fields []grokField
+}
+
+type grokLibrary struct {
+	mu          sync.RWMutex
+	patterns    map[string]string
+	expressions map[string]*grokExpression
+}
+
+func newGrokLibrary() *grokLibrary {
+	g := &grokLibrary{
+		patterns:    make(map[string]string, len(defaultGrokPatterns)),
+		expressions: make(map[string]*grokExpression),
+	}
+	for name, pattern := range defaultGrokPatterns {
+		g.patterns[name] = pattern
+	}
+	return g
+}
+
+func (g *grokLibrary) addPattern(name string, pattern string) error {
+	if !grokPatternName.MatchString(name) {
+		return fmt.Errorf("invalid grok pattern name %q", name)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.patterns[name] = pattern
+	// a new definition can change the meaning of anything already compiled
+	g.expressions = make(map[string]*grokExpression)
+	return nil
+}
+
+func (g *grokLibrary) get(expr string) (*grokExpression, error) {
+	g.mu.RLock()
+	compiled, ok := g.expressions[expr]
+	g.mu.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fields := []grokField{}
+	expanded, err := g.expand(expr, 0, &fields)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, err
+	}
+	compiled = &grokExpression{re: re, fields: fields}
+	if len(g.expressions) < maxCachedPatterns {
+		g.expressions[expr] = compiled
+	}
+	return compiled, nil
+}
+
+func (g *grokLibrary) precompile(expr string) error {
+	_, err := g.get(expr)
+	return err
+}
+
+// replaces every %{...} reference with the regex it stands for. named references become capture groups.
+// callers must hold the lock.
+func (g *grokLibrary) expand(expr string, depth int, fields *[]grokField) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns are nested too deeply (possible reference cycle)")
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, idxs := range grokReference.FindAllStringSubmatchIndex(expr, -1) {
+		sb.WriteString(expr[last:idxs[0]])
+		last = idxs[1]
+
+		name := expr[idxs[2]:idxs[3]]
+		pattern, ok := g.patterns[name]
+		if !ok {
+			return "", fmt.Errorf("unknown grok pattern %q", name)
+		}
+		sub, err := g.expand(pattern, depth+1, fields)
+		if err != nil {
+			return "", err
+		}
+
+		if idxs[4] < 0 { // unnamed, so just match it
+			sb.WriteString("(?:" + sub + ")")
+			continue
+		}
+		field := grokField{
+			name:      expr[idxs[4]:idxs[5]],
+			groupName: fmt.Sprintf("grok%d", len(*fields)),
+		}
+		if idxs[6] >= 0 && expr[idxs[6]:idxs[7]] != "string" {
+			field.valueType = expr[idxs[6]:idxs[7]]
+		}
+		*fields = append(*fields, field)
+		sb.WriteString(fmt.Sprintf("(?P<%s>%s)", field.groupName, sub))
+	}
+	sb.WriteString(expr[last:])
+	return sb.String(), nil
+}
+
+// returns a map of field name to value, or null when the expression doesn't match
+func (ge *grokExpression) parse(value string) (object.Object, error) {
+	idxs := ge.re.FindStringSubmatchIndex(value)
+	if idxs == nil {
+		return &object.Null{}, nil
+	}
+
+	groupIdx := map[string]int{}
+	for idx, name := range ge.re.SubexpNames() {
+		groupIdx[name] = idx
+	}
+
+	ret := object.NewMap()
+	for _, field := range ge.fields {
+		idx := groupIdx[field.groupName]
+		start, end := idxs[idx*2], idxs[idx*2+1]
+		if start < 0 {
+			// the same field can show up in several alternations, so don't overwrite a value that did match
+			if _, found := ret.Pairs[field.name]; !found {
+				ret.Pairs[field.name] = &object.Null{}
+			}
+			continue
+		}
+		val, err := convertGrokValue(value[start:end], field)
+		if err != nil {
+			return nil, err
+		}
+		ret.Pairs[field.name] = val
+	}
+	return ret, nil
+}
+
+func convertGrokValue(raw string, field grokField) (object.Object, error) {
+	switch field.valueType {
+	case "int":
+		val, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %q is not an integer", field.name, raw)
+		}
+		return newInteger(val), nil
+	case "float":
+		val, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %q is not a float", field.name, raw)
+		}
+		return &object.Float{Value: val}, nil
+	}
+	return newString(raw), nil
+}
+
+func registerGrok(r *Registry) {
+	r.grok = newGrokLibrary()
+	lib := r.grok
+
+	r.mustRegister(
+		&Function{
+			Name: "grok",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "pattern", Types: String, Precompile: lib.precompile},
+			},
+			Returns: []object.ObjectType{object.MAP_OBJ, object.NULL_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				expr, err := lib.get(args.String("pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return expr.parse(args.String("value"))
+			},
+		},
+	)
+}
+
+//
+// host api
+//
+
+// AddGrokPattern defines or overrides a named pattern that grok expressions can reference with %{NAME}.
+// patterns should be added before the program is prepared, since adding one clears already compiled expressions.
+func (r *Registry) AddGrokPattern(name string, pattern string) error {
+	if r.grok == nil {
+		return fmt.Errorf("add grok pattern: grok is not registered")
+	}
+	return r.grok.addPattern(name, pattern)
+}
+
+// LoadGrokPatterns reads pattern definitions in the logstash file format, where each line is a name followed by whitespace and the pattern.
+// empty lines and lines starting with # are skipped.
+func (r *Registry) LoadGrokPatterns(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, found := strings.Cut(line, " ")
+		if !found {
+			name, pattern, found = strings.Cut(line, "\t")
+		}
+		if !found {
+			return fmt.Errorf("load grok patterns: line %d: expected a name followed by a pattern", lineNum)
+		}
+		if err := r.AddGrokPattern(name, strings.TrimSpace(pattern)); err != nil {
+			return fmt.Errorf("load grok patterns: line %d: %w", lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// a subset of the logstash core patterns, adjusted for go's regex syntax (no lookarounds).
+var defaultGrokPatterns = map[string]string{
+	"USERNAME":       `[a-zA-Z0-9._-]+`,
+	"USER":           `%{USERNAME}`,
+	"EMAILLOCALPART": `[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+(?:\.[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+)*`,
+	"EMAILADDRESS":   `%{EMAILLOCALPART}@%{HOSTNAME}`,
+	"INT":            `[+-]?(?:[0-9]+)`,
+	"BASE10NUM":      `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"NUMBER":         `%{BASE10NUM}`,
+	"BASE16NUM":      `[+-]?(?:0x)?(?:[0-9A-Fa-f]+)`,
+	"POSINT":         `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":      `\b(?:[0-9]+)\b`,
+	"WORD":           `\b\w+\b`,
+	"NOTSPACE":       `\S+`,
+	"SPACE":          `\s*`,
+	"DATA":           `.*?`,
+	"GREEDYDATA":     `.*`,
+	"QUOTEDSTRING":   `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":             `%{QUOTEDSTRING}`,
+	"UUID":           `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"MAC":            `(?:[A-Fa-f0-9]{2}[:-]){5}[A-Fa-f0-9]{2}|(?:[A-Fa-f0-9]{4}\.){2}[A-Fa-f0-9]{4}`,
+
+	"IPV4": `(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`,
+	// alternatives are ordered so that the longest form wins, since go picks the first alternative that matches
+	"IPV6": `(?:[0-9A-Fa-f]{1,4}:){1,4}:%{IPV4}|::(?:[Ff]{4}(?::0{1,4})?:)?%{IPV4}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}` +
+		`|[0-9A-Fa-f]{1,4}:(?::[0-9A-Fa-f]{1,4}){1,6}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){1,2}(?::[0-9A-Fa-f]{1,4}){1,5}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){1,3}(?::[0-9A-Fa-f]{1,4}){1,4}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){1,4}(?::[0-9A-Fa-f]{1,4}){1,3}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){1,5}(?::[0-9A-Fa-f]{1,4}){1,2}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){1,6}:[0-9A-Fa-f]{1,4}` +
+		`|(?:[0-9A-Fa-f]{1,4}:){1,7}:` +
+		`|:(?:(?::[0-9A-Fa-f]{1,4}){1,7}|:)`,
+	"IP":       `%{IPV6}|%{IPV4}`,
+	"HOSTNAME": `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST": `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	"PATH":         `%{UNIXPATH}|%{WINPATH}`,
+	"UNIXPATH":     `(?:/[\w_%!$@:.,+~-]*)+`,
+	"WINPATH":      `(?:[A-Za-z]+:|\\)(?:\\[^\\?*]*)+`,
+	"URIPROTO":     `[A-Za-z][A-Za-z0-9+\-.]+`,
+	"URIHOST":      `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{URIHOST})?(?:%{URIPATHPARAM})?`,
+
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:1[0-2]|0?[1-9])`,
+	"MONTHDAY":          `(?:3[01]|[12][0-9]|0[1-9]|[1-9])`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"DATE_US":           `%{MONTHNUM}[/-]%{MONTHDAY}[/-]%{YEAR}`,
+	"DATE_EU":           `%{MONTHDAY}[./-]%{MONTHNUM}[./-]%{YEAR}`,
+	"DATE":              `%{DATE_US}|%{DATE_EU}`,
+	"DATESTAMP":         `%{DATE}[- ]%{TIME}`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"LOGLEVEL":          `[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo(?:rmation)?|INFO(?:RMATION)?|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?`,
+
+	"HTTPDUSER":         `%{EMAILADDRESS}|%{USER}`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{HTTPDUSER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
@@ -0,0 +1,134 @@
+package builtins_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestGrok(t *testing.T) {
+	tests := []struct {
+		message string
+		pattern string
+		want    map[string]string
+	}{
+		{
+			"55.3.244.1 GET /index.html?a=b",
+			"%{IP:client} %{WORD:method} %{URIPATHPARAM:path}",
+			map[string]string{"client": "55.3.244.1", "method": "GET", "path": "/index.html?a=b"},
+		},
+		{
+			"2001:db8::1:2 connected",
+			"%{IP:client} %{WORD:action}",
+			map[string]string{"client": "2001:db8::1:2", "action": "connected"},
+		},
+		{
+			"2024-03-05T10:22:33.123Z ERROR disk full",
+			"%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{GREEDYDATA:msg}",
+			map[string]string{"ts": "2024-03-05T10:22:33.123Z", "level": "ERROR", "msg": "disk full"},
+		},
+		{
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			"%{COMMONAPACHELOG}",
+			map[string]string{"clientip": "127.0.0.1", "auth": "frank", "timestamp": "10/Oct/2000:13:55:36 -0700", "verb": "GET", "request": "/apache_pb.gif", "response": "200", "bytes": "2326"},
+		},
+	}
+	for _, tt := range tests {
+		src := object.NewMap()
+		src.Pairs["message"] = &object.String{Value: tt.message}
+		input := `grok($src.message, "` + strings.ReplaceAll(tt.pattern, `"`, `\"`) + `")`
+		got := evalWithSource(t, input, src, builtins.Default())
+		m, ok := got.(*object.Map)
+		if !ok {
+			t.Fatalf("%s: result is not *object.Map. got=%T (%s)", tt.pattern, got, got.Inspect())
+		}
+		for k, want := range tt.want {
+			testStringResult(t, tt.pattern+" "+k, m.Pairs[k], want)
+		}
+	}
+}
+
+func TestGrokTypedFields(t *testing.T) {
+	input := `m = grok("took 250ms, load 0.75", "took %{INT:took:int}ms, load %{NUMBER:load:float}")
+	m.took`
+	testIntegerResult(t, input, evalInput(t, input), 250)
+
+	input = `grok("no digits here", "%{INT:num}") == null`
+	got, ok := evalInput(t, input).(*object.Boolean)
+	if !ok || !got.Value {
+		t.Errorf("expected a non-matching grok to return null")
+	}
+}
+
+func TestGrokErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`p = "%{NOPE:x}"
+		grok("a", p)`, `grok: unknown grok pattern "NOPE"`},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+
+	l := lexer.New([]rune(`grok("a", "%{MISSING}")`))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = evaluator.New(l.InputRunes()).Prepare(program)
+	if err == nil || !strings.Contains(err.Error(), `grok: invalid pattern: unknown grok pattern "MISSING"`) {
+		t.Errorf("expected Prepare to report the unknown grok pattern. got=%v", err)
+	}
+}
+
+func TestGrokCustomPatterns(t *testing.T) {
+	r := builtins.Default()
+	if err := r.AddGrokPattern("TICKET", `[A-Z]+-\d+`); err != nil {
+		t.Fatal(err)
+	}
+	patternFile := `
+# custom patterns
+ENVIRONMENT (?:prod|staging|dev)
+DEPLOY %{ENVIRONMENT:env} %{TICKET:ticket}
+`
+	if err := r.LoadGrokPatterns(strings.NewReader(patternFile)); err != nil {
+		t.Fatal(err)
+	}
+	src := object.NewMap()
+	src.Pairs["message"] = &object.String{Value: "deploying staging OPS-42"}
+	got := evalWithSource(t, `grok($src.message, "deploying %{DEPLOY}")`, src, r)
+	m, ok := got.(*object.Map)
+	if !ok {
+		t.Fatalf("result is not *object.Map. got=%T (%s)", got, got.Inspect())
+	}
+	testStringResult(t, "env", m.Pairs["env"], "staging")
+	testStringResult(t, "ticket", m.Pairs["ticket"], "OPS-42")
+
+	err := r.LoadGrokPatterns(strings.NewReader("BROKEN"))
+	if err == nil {
+		t.Fatal("expected an error for a pattern line without a pattern. got no error")
+	}
+	if isEq, failMsg := testutils.Equal("load grok patterns: line 1: expected a name followed by a pattern", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+}
+
+func evalWithSource(t *testing.T, input string, src object.Object, r *builtins.Registry) object.Object {
+	t.Helper()
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatalf("evalWithSource: %s", err.Error())
+	}
+	env := object.NewEnvironment()
+	env.Set("$src", src)
+	return evaluator.New(l.InputRunes(), evaluator.WithRegistry(r)).Eval(program, env)
+}
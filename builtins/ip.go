@@ -0,0 +1,209 @@
+package builtins
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// subnets and hosts return arrays, so cap them to keep a typo like a /8 from allocating millions of values
+const maxIPResults = 65536
+
+func registerIP(r *Registry) {
+	// functions taking an address accept either an IP or a string to parse
+	ip := Param{Name: "ip", Types: []object.ObjectType{object.IP_OBJ, object.STRING_OBJ}}
+	cidr := Param{Name: "cidr", Types: String, Precompile: func(literal string) error {
+		_, err := parseCIDR(literal)
+		return err
+	}}
+
+	r.mustRegister(
+		&Function{
+			Name:    "parse_ip",
+			Params:  []Param{{Name: "value", Types: String}},
+			Returns: []object.ObjectType{object.IP_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				addr, err := parseIP(args.String("value"))
+				if err != nil {
+					return nil, err
+				}
+				return &object.IP{Value: addr}, nil
+			},
+		},
+		// the is_ipv* checks return false for values that aren't addresses at all, so they can be used to classify any field
+		&Function{
+			Name:    "is_ipv4",
+			Params:  []Param{ip},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				addr, err := toAddr(args.Get("ip"))
+				return newBoolean(err == nil && addr.Is4()), nil
+			},
+		},
+		&Function{
+			Name:    "is_ipv6",
+			Params:  []Param{ip},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				addr, err := toAddr(args.Get("ip"))
+				return newBoolean(err == nil && addr.Is6()), nil
+			},
+		},
+		&Function{
+			Name:    "is_private",
+			Params:  []Param{ip}, // RFC 1918 and RFC 4193 ranges
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				addr, err := toAddr(args.Get("ip"))
+				if err != nil {
+					return nil, err
+				}
+				return newBoolean(addr.IsPrivate()), nil
+			},
+		},
+		&Function{
+			Name:    "cidr_contains",
+			Params:  []Param{cidr, ip},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				prefix, err := parseCIDR(args.String("cidr"))
+				if err != nil {
+					return nil, err
+				}
+				addr, err := toAddr(args.Get("ip"))
+				if err != nil {
+					return nil, err
+				}
+				return newBoolean(prefix.Contains(addr)), nil
+			},
+		},
+		&Function{
+			Name:    "ip_to_int",
+			Params:  []Param{ip}, // IPv4 only, since IPv6 addresses don't fit in an integer
+			Returns: Integer,
+			Fn: func(args *Args) (object.Object, error) {
+				addr, err := toAddr(args.Get("ip"))
+				if err != nil {
+					return nil, err
+				}
+				if !addr.Is4() {
+					return nil, fmt.Errorf("only IPv4 addresses can be converted to an integer. got=%s", addr)
+				}
+				b := addr.As4()
+				return newInteger(int64(b[0])<<24 | int64(b[1])<<16 | int64(b[2])<<8 | int64(b[3])), nil
+			},
+		},
+		&Function{
+			Name:    "mask",
+			Params:  []Param{ip, {Name: "bits", Types: Integer}}, // keeps the first bits of the address and zeroes the rest
+			Returns: []object.ObjectType{object.IP_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				addr, err := toAddr(args.Get("ip"))
+				if err != nil {
+					return nil, err
+				}
+				prefix, err := addr.Prefix(int(args.Int("bits")))
+				if err != nil {
+					return nil, fmt.Errorf("bits must be between 0 and %d. got=%d", addr.BitLen(), args.Int("bits"))
+				}
+				return &object.IP{Value: prefix.Addr()}, nil
+			},
+		},
+		&Function{
+			Name:    "subnets",
+			Params:  []Param{cidr, {Name: "bits", Types: Integer}}, // splits the range into subnets with this prefix length, like subnets("10.0.0.0/16", 24)
+			Returns: Array,
+			Fn:      subnets,
+		},
+		&Function{
+			Name:    "hosts",
+			Params:  []Param{cidr}, // every address in the range, in order
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				prefix, err := parseCIDR(args.String("cidr"))
+				if err != nil {
+					return nil, err
+				}
+				if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits > 16 {
+					return nil, fmt.Errorf("%s has more than %d addresses", prefix, maxIPResults)
+				}
+				ret := []object.Object{}
+				for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+					ret = append(ret, &object.IP{Value: addr})
+				}
+				return &object.Array{Elements: ret}, nil
+			},
+		},
+	)
+}
+
+// IPv4-mapped IPv6 addresses like ::ffff:10.0.0.1 are treated as the IPv4 address they contain
+func parseIP(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%q is not a valid IP address", value)
+	}
+	return addr.Unmap(), nil
+}
+
+func toAddr(obj object.Object) (netip.Addr, error) {
+	switch obj := obj.(type) {
+	case *object.IP:
+		return obj.Value, nil
+	case *object.String:
+		return parseIP(obj.Value)
+	}
+	return netip.Addr{}, fmt.Errorf("expected an IP address. got=%s", obj.Type())
+}
+
+// the range is normalized, so 10.1.2.3/8 is the same as 10.0.0.0/8
+func parseCIDR(value string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%q is not a valid CIDR range", value)
+	}
+	return prefix.Masked(), nil
+}
+
+func subnets(args *Args) (object.Object, error) {
+	prefix, err := parseCIDR(args.String("cidr"))
+	if err != nil {
+		return nil, err
+	}
+	bits := int(args.Int("bits"))
+	if bits < prefix.Bits() || bits > prefix.Addr().BitLen() {
+		return nil, fmt.Errorf("bits must be between %d and %d. got=%d", prefix.Bits(), prefix.Addr().BitLen(), bits)
+	}
+	if bits-prefix.Bits() > 16 {
+		return nil, fmt.Errorf("%s has more than %d subnets of /%d", prefix, maxIPResults, bits)
+	}
+
+	ret := []object.Object{}
+	count := 1 << (bits - prefix.Bits())
+	addr := prefix.Addr()
+	for range count {
+		subnet := netip.PrefixFrom(addr, bits)
+		ret = append(ret, newString(subnet.String()))
+		addr = nextSubnetAddr(subnet)
+	}
+	return &object.Array{Elements: ret}, nil
+}
+
+// the first address after the end of the subnet
+func nextSubnetAddr(subnet netip.Prefix) netip.Addr {
+	b := subnet.Addr().AsSlice()
+	// add one at the last bit of the prefix, carrying into the bytes before it
+	bitIdx := subnet.Bits() - 1
+	if bitIdx < 0 {
+		return netip.Addr{}
+	}
+	carry := byte(1) << (7 - bitIdx%8)
+	for idx := bitIdx / 8; idx >= 0 && carry != 0; idx-- {
+		sum := uint16(b[idx]) + uint16(carry)
+		b[idx] = byte(sum)
+		carry = byte(sum >> 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
@@ -0,0 +1,94 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestIPFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{`parse_ip("10.1.2.3")`, "10.1.2.3"},
+		{`parse_ip("::ffff:10.1.2.3")`, "10.1.2.3"},
+		{`parse_ip("2001:DB8::1")`, "2001:db8::1"},
+		{`is_ipv4("10.1.2.3")`, "true"},
+		{`is_ipv4("2001:db8::1")`, "false"},
+		{`is_ipv4("not an ip")`, "false"},
+		{`is_ipv6(parse_ip("2001:db8::1"))`, "true"},
+		{`is_private("192.168.0.10")`, "true"},
+		{`is_private("fd00::1")`, "true"},
+		{`is_private("8.8.8.8")`, "false"},
+		{`cidr_contains("10.0.0.0/8", "10.200.1.1")`, "true"},
+		{`cidr_contains("10.0.0.0/8", parse_ip("11.0.0.1"))`, "false"},
+		{`cidr_contains("2001:db8::/32", "2001:db8:ffff::1")`, "true"},
+		{`ip_to_int("192.168.1.1")`, "3232235777"},
+		{`mask("192.168.1.77", 24)`, "192.168.1.0"},
+		{`mask("2001:db8:aaaa:bbbb::1", 48)`, "2001:db8:aaaa::"},
+		{`subnets("10.0.0.0/22", 24)`, "[10.0.0.0/24, 10.0.1.0/24, 10.0.2.0/24, 10.0.3.0/24]"},
+		{`subnets("10.0.0.7/23", 23)`, "[10.0.0.0/23]"},
+		{`subnets("255.255.255.0/24", 25)`, "[255.255.255.0/25, 255.255.255.128/25]"},
+		{`subnets("2001:db8::/47", 48)`, "[2001:db8::/48, 2001:db8:1::/48]"},
+		{`hosts("192.168.1.0/30")`, "[192.168.1.0, 192.168.1.1, 192.168.1.2, 192.168.1.3]"},
+		{`hosts("255.255.255.254/31")`, "[255.255.255.254, 255.255.255.255]"},
+		{`parse_ip("10.0.0.1") == parse_ip("::ffff:10.0.0.1")`, "true"},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestIPInCondition(t *testing.T) {
+	src := object.NewMap()
+	src.Pairs["ip"] = &object.String{Value: "172.16.4.20"}
+	input := `if is_ipv4($src.ip) && cidr_contains("172.16.0.0/12", $src.ip) { "internal" } else { "external" }`
+	testStringResult(t, input, evalWithSource(t, input, src, builtins.Default()), "internal")
+}
+
+func TestIPErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`parse_ip("10.0.0.300")`, `parse_ip: "10.0.0.300" is not a valid IP address`},
+		{`is_private("nope")`, `is_private: "nope" is not a valid IP address`},
+		{`ip_to_int("::1")`, "ip_to_int: only IPv4 addresses can be converted to an integer. got=::1"},
+		{`mask("10.0.0.1", 33)`, "mask: bits must be between 0 and 32. got=33"},
+		{`subnets("10.0.0.0/16", 8)`, "subnets: bits must be between 16 and 32. got=8"},
+		{`subnets("10.0.0.0/8", 32)`, "subnets: 10.0.0.0/8 has more than 65536 subnets of /32"},
+		{`hosts("10.0.0.0/8")`, "hosts: 10.0.0.0/8 has more than 65536 addresses"},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestPrepareReportsInvalidCIDR(t *testing.T) {
+	input := "x = \"10.0.0.1\"\nif cidr_contains(\"10.0.0.0/33\", x) { 1 }"
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = evaluator.New(l.InputRunes()).Prepare(program)
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("err is not *object.Error. got=%T", err)
+	}
+	if isEq, failMsg := testutils.Equal(`cidr_contains: invalid cidr: "10.0.0.0/33" is not a valid CIDR range`, errObj.Message); !isEq {
+		t.Errorf("wrong error message: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal(2, errObj.Line); !isEq {
+		t.Errorf("wrong error line: %s", failMsg)
+	}
+}
@@ -0,0 +1,359 @@
+package builtins
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// Match is how a table matches a key against the key column of its rows.
+type Match int
+
+const (
+	Exact Match = iota // the key equals the row's key, compared as strings so 5 matches "5"
+	CIDR               // the key is an IP inside the row's CIDR range. the most specific range wins.
+	Range              // the key is a number or IP between the row's key and end columns, inclusive
+)
+
+// TableSpec describes how a table is indexed.
+type TableSpec struct {
+	Key   string // the column rows are matched on
+	Match Match
+	End   string // the column holding the end of each range, for Range tables
+}
+
+// Table is a set of rows, each a map of column names to values, indexed by one column for lookup.
+type Table struct {
+	spec     TableSpec
+	exact    map[string]*object.Map
+	prefixes map[int]map[netip.Prefix]*object.Map // by prefix length, so the longest can be tried first
+	lengths  []int                                // prefix lengths in descending order
+	ranges   []rangeRow                           // sorted by start. NewTable rejects ranges that overlap.
+}
+
+type rangeRow struct {
+	start, end bound
+	row        *object.Map
+}
+
+// a range bound is an IP or a number. a table's bounds are all the same kind.
+type bound struct {
+	addr netip.Addr
+	num  *big.Rat
+}
+
+func (b bound) isAddr() bool {
+	return b.num == nil
+}
+
+func (b bound) String() string {
+	if b.isAddr() {
+		return b.addr.String()
+	}
+	return b.num.RatString()
+}
+
+func (b bound) compare(other bound) int {
+	if b.isAddr() {
+		return b.addr.Compare(other.addr)
+	}
+	return b.num.Cmp(other.num)
+}
+
+// NewTable indexes rows held in memory. a row missing its key is an error, and for exact tables the last row with a key wins.
+// the ranges of a range table can't overlap, so each key matches at most one row.
+func NewTable(spec TableSpec, rows []*object.Map) (*Table, error) {
+	if spec.Key == "" {
+		return nil, fmt.Errorf("new table: key column cannot be empty")
+	}
+	if spec.Match == Range && spec.End == "" {
+		return nil, fmt.Errorf("new table: range tables need an end column")
+	}
+	t := &Table{spec: spec, exact: map[string]*object.Map{}, prefixes: map[int]map[netip.Prefix]*object.Map{}}
+	for idx, row := range rows {
+		if err := t.add(row); err != nil {
+			return nil, fmt.Errorf("new table: row %d: %w", idx+1, err)
+		}
+	}
+	for bits := range t.prefixes {
+		t.lengths = append(t.lengths, bits)
+	}
+	slices.Sort(t.lengths)
+	slices.Reverse(t.lengths)
+	sort.SliceStable(t.ranges, func(i, j int) bool {
+		return t.ranges[i].start.compare(t.ranges[j].start) < 0
+	})
+	// Get only checks the last range starting at or before a key, which misses keys in an earlier range that overlaps it
+	for idx := 1; idx < len(t.ranges); idx++ {
+		prev, r := t.ranges[idx-1], t.ranges[idx]
+		if r.start.compare(prev.end) <= 0 {
+			return nil, fmt.Errorf("new table: range %s-%s overlaps range %s-%s", r.start, r.end, prev.start, prev.end)
+		}
+	}
+	return t, nil
+}
+
+func (t *Table) add(row *object.Map) error {
+	key, ok := row.Pairs[t.spec.Key]
+	if !ok {
+		return fmt.Errorf("missing key column %q", t.spec.Key)
+	}
+	switch t.spec.Match {
+	case CIDR:
+		s, ok := key.(*object.String)
+		if !ok {
+			return fmt.Errorf("%s must be a CIDR range. got=%s", t.spec.Key, key.Type())
+		}
+		prefix, err := parseCIDR(s.Value)
+		if err != nil {
+			return err
+		}
+		if t.prefixes[prefix.Bits()] == nil {
+			t.prefixes[prefix.Bits()] = map[netip.Prefix]*object.Map{}
+		}
+		t.prefixes[prefix.Bits()][prefix] = row
+	case Range:
+		end, ok := row.Pairs[t.spec.End]
+		if !ok {
+			return fmt.Errorf("missing end column %q", t.spec.End)
+		}
+		r := rangeRow{row: row}
+		var err error
+		if r.start, err = toBound(key); err != nil {
+			return err
+		}
+		if r.end, err = toBound(end); err != nil {
+			return err
+		}
+		if r.start.isAddr() != r.end.isAddr() || (len(t.ranges) > 0 && t.ranges[0].start.isAddr() != r.start.isAddr()) {
+			return fmt.Errorf("range bounds must all be IPs or all be numbers")
+		}
+		if r.start.compare(r.end) > 0 {
+			return fmt.Errorf("range starts after it ends")
+		}
+		t.ranges = append(t.ranges, r)
+	default:
+		t.exact[exactKey(key)] = row
+	}
+	return nil
+}
+
+// Get returns the row matching key, or false if none do.
+func (t *Table) Get(key object.Object) (*object.Map, bool) {
+	switch t.spec.Match {
+	case CIDR:
+		addr, err := toAddr(key)
+		if err != nil {
+			return nil, false
+		}
+		for _, bits := range t.lengths {
+			prefix, err := addr.Prefix(bits)
+			if err != nil {
+				continue // an IPv4 address can't have an IPv6 length prefix
+			}
+			if row, ok := t.prefixes[bits][prefix]; ok {
+				return row, true
+			}
+		}
+		return nil, false
+	case Range:
+		b, err := toBound(key)
+		if err != nil || len(t.ranges) == 0 || b.isAddr() != t.ranges[0].start.isAddr() {
+			return nil, false
+		}
+		// the last range starting at or before the key is the only one that can hold it
+		idx := sort.Search(len(t.ranges), func(i int) bool {
+			return t.ranges[i].start.compare(b) > 0
+		}) - 1
+		if idx < 0 || t.ranges[idx].end.compare(b) < 0 {
+			return nil, false
+		}
+		return t.ranges[idx].row, true
+	}
+	row, ok := t.exact[exactKey(key)]
+	return row, ok
+}
+
+func exactKey(key object.Object) string {
+	if s, ok := key.(*object.String); ok {
+		return s.Value
+	}
+	return key.Inspect()
+}
+
+// accepts IPs and numbers, and strings holding either, since values read from CSV are always strings
+func toBound(obj object.Object) (bound, error) {
+	if r, ok := object.ToRat(obj); ok {
+		return bound{num: r}, nil
+	}
+	switch obj := obj.(type) {
+	case *object.IP:
+		return bound{addr: obj.Value}, nil
+	case *object.String:
+		if addr, err := parseIP(obj.Value); err == nil {
+			return bound{addr: addr}, nil
+		}
+		if r, ok := new(big.Rat).SetString(strings.TrimSpace(obj.Value)); ok {
+			return bound{num: r}, nil
+		}
+		return bound{}, fmt.Errorf("%q is not an IP address or a number", obj.Value)
+	}
+	return bound{}, fmt.Errorf("range bounds must be IPs or numbers. got=%s", obj.Type())
+}
+
+//
+// loaders
+//
+
+// LoadCSV reads a table from CSV with a header row naming the columns. every value is read as a string.
+func LoadCSV(r io.Reader, spec TableSpec) (*Table, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("load csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("load csv: missing header row")
+	}
+	header := records[0]
+	rows := make([]*object.Map, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := object.NewMap()
+		for idx, col := range header {
+			row.Pairs[col] = newString(record[idx])
+		}
+		rows = append(rows, row)
+	}
+	return NewTable(spec, rows)
+}
+
+// LoadJSON reads a table from a JSON array of objects.
+func LoadJSON(r io.Reader, spec TableSpec) (*Table, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("load json: %w", err)
+	}
+	decoded, err := object.DecodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("load json: %w", err)
+	}
+	arr, ok := decoded.(*object.Array)
+	if !ok {
+		return nil, fmt.Errorf("load json: expected an array of objects. got=%s", decoded.Type())
+	}
+	rows := make([]*object.Map, 0, len(arr.Elements))
+	for idx, el := range arr.Elements {
+		row, ok := el.(*object.Map)
+		if !ok {
+			return nil, fmt.Errorf("load json: row %d is not an object. got=%s", idx+1, el.Type())
+		}
+		rows = append(rows, row)
+	}
+	return NewTable(spec, rows)
+}
+
+// LoadJSONL reads a table from JSON lines, one object per line. empty lines are skipped.
+func LoadJSONL(r io.Reader, spec TableSpec) (*Table, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	rows := []*object.Map{}
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		decoded, err := object.DecodeJSON([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("load jsonl: line %d: %w", lineNum, err)
+		}
+		row, ok := decoded.(*object.Map)
+		if !ok {
+			return nil, fmt.Errorf("load jsonl: line %d is not an object. got=%s", lineNum, decoded.Type())
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("load jsonl: %w", err)
+	}
+	return NewTable(spec, rows)
+}
+
+//
+// registration
+//
+
+// tables shared by every lookup call, which hosts can add to while pipelines are running
+type lookupTables struct {
+	mu     sync.RWMutex
+	tables map[string]*Table
+}
+
+func (l *lookupTables) get(name string) (*Table, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	t, ok := l.tables[name]
+	return t, ok
+}
+
+func (l *lookupTables) set(name string, t *Table) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tables[name] = t
+}
+
+func registerLookup(r *Registry) {
+	r.tables = &lookupTables{tables: map[string]*Table{}}
+	tables := r.tables
+	table := Param{Name: "table", Types: String, Precompile: func(literal string) error {
+		if _, ok := tables.get(literal); !ok {
+			return fmt.Errorf("unknown lookup table %q", literal)
+		}
+		return nil
+	}}
+
+	r.mustRegister(
+		// returns the matching row as a map, or default when no row matches
+		&Function{
+			Name:   "lookup",
+			Params: []Param{table, {Name: "key"}, {Name: "default", Default: &object.Null{}}},
+			Fn: func(args *Args) (object.Object, error) {
+				t, ok := tables.get(args.String("table"))
+				if !ok {
+					return nil, fmt.Errorf("unknown lookup table %q", args.String("table"))
+				}
+				if row, ok := t.Get(args.Get("key")); ok {
+					return row, nil
+				}
+				return args.Get("default"), nil
+			},
+		},
+	)
+}
+
+//
+// host api
+//
+
+// AddTable defines or replaces a named table that lookup can query.
+// tables should be added before the program is prepared, since lookups of unknown tables are reported then.
+func (r *Registry) AddTable(name string, t *Table) error {
+	if r.tables == nil {
+		return fmt.Errorf("add table: lookup is not registered")
+	}
+	if name == "" {
+		return errors.New("add table: name cannot be empty")
+	}
+	r.tables.set(name, t)
+	return nil
+}
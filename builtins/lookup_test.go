@@ -0,0 +1,194 @@
+package builtins_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestLookup(t *testing.T) {
+	r := builtins.Default()
+	countries, err := builtins.LoadCSV(strings.NewReader("code,name\nNZ,New Zealand\nDE,Germany\n"), builtins.TableSpec{Key: "code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets, err := builtins.LoadJSON(strings.NewReader(`[{"cidr": "10.0.0.0/8", "zone": "corp"}, {"cidr": "10.1.0.0/16", "zone": "lab"}, {"cidr": "2001:db8::/32", "zone": "v6"}]`),
+		builtins.TableSpec{Key: "cidr", Match: builtins.CIDR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	geo, err := builtins.LoadJSONL(strings.NewReader("{\"start\": \"1.0.0.0\", \"end\": \"1.0.0.255\", \"country\": \"AU\"}\n\n{\"start\": \"1.0.1.0\", \"end\": \"1.0.3.255\", \"country\": \"CN\"}\n"),
+		builtins.TableSpec{Key: "start", End: "end", Match: builtins.Range})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports, err := builtins.NewTable(builtins.TableSpec{Key: "low", End: "high", Match: builtins.Range}, []*object.Map{
+		row(t, `{"low": 0, "high": 1023, "class": "system"}`),
+		row(t, `{"low": 1024, "high": 49151, "class": "registered"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := builtins.NewTable(builtins.TableSpec{Key: "id"}, []*object.Map{row(t, `{"id": 7, "owner": "ops"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, table := range map[string]*builtins.Table{"countries": countries, "assets": assets, "geo": geo, "ports": ports, "ids": ids} {
+		if err := r.AddTable(name, table); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{`lookup("countries", "NZ").name`, "New Zealand"},
+		{`lookup("countries", key: "FR")`, "null"},
+		{`lookup("countries", key: "FR", default: "unknown")`, "unknown"},
+		{`lookup("assets", "10.2.3.4").zone`, "corp"},
+		{`lookup("assets", parse_ip("10.1.3.4")).zone`, "lab"},
+		{`lookup("assets", "2001:db8::1").zone`, "v6"},
+		{`lookup("assets", "192.168.0.1", default: "none")`, "none"},
+		{`lookup("assets", "not an ip")`, "null"},
+		{`lookup("geo", "1.0.2.9").country`, "CN"},
+		{`lookup("geo", "1.0.0.0").country`, "AU"},
+		{`lookup("geo", "1.0.4.0")`, "null"},
+		{`lookup("geo", 5)`, "null"},
+		{`lookup("ports", 443).class`, "system"},
+		{`lookup("ports", "8080").class`, "registered"},
+		{`lookup("ports", 50000)`, "null"},
+		{`lookup("ids", 7).owner`, "ops"},
+		{`lookup("ids", "7").owner`, "ops"},
+	}
+	for _, tt := range tests {
+		got := evalWithSource(t, tt.input, object.NewMap(), r)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+// run with -race to catch unguarded access to the registry's tables
+func TestLookupWhileAddingTables(t *testing.T) {
+	r := builtins.Default()
+	ids, err := builtins.NewTable(builtins.TableSpec{Key: "id"}, []*object.Map{row(t, `{"id": 7, "owner": "ops"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddTable("ids", ids); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			if err := r.AddTable("ids", ids); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for range 100 {
+		got := evalWithSource(t, `lookup("ids", 7).owner`, object.NewMap(), r)
+		if isEq, failMsg := testutils.Equal("ops", got.Inspect()); !isEq {
+			t.Fatalf("wrong result: %s", failMsg)
+		}
+	}
+	wg.Wait()
+}
+
+func TestLookupUnknownTable(t *testing.T) {
+	input := `lookup("missing", "a")`
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = evaluator.New(l.InputRunes()).Prepare(program)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if isEq, failMsg := testutils.Equal("runtime error at 1:8:\n\tlookup: invalid table: unknown lookup table \"missing\"", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+}
+
+func TestTableErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		load func() error
+		want string
+	}{
+		{"no key", func() error {
+			_, err := builtins.NewTable(builtins.TableSpec{}, nil)
+			return err
+		}, "new table: key column cannot be empty"},
+		{"no end", func() error {
+			_, err := builtins.NewTable(builtins.TableSpec{Key: "start", Match: builtins.Range}, nil)
+			return err
+		}, "new table: range tables need an end column"},
+		{"missing key", func() error {
+			_, err := builtins.LoadCSV(strings.NewReader("a,b\n1,2\n"), builtins.TableSpec{Key: "c"})
+			return err
+		}, "new table: row 1: missing key column \"c\""},
+		{"bad cidr", func() error {
+			_, err := builtins.LoadCSV(strings.NewReader("cidr\n10.0.0.0/99\n"), builtins.TableSpec{Key: "cidr", Match: builtins.CIDR})
+			return err
+		}, "new table: row 1: \"10.0.0.0/99\" is not a valid CIDR range"},
+		{"mixed bounds", func() error {
+			_, err := builtins.LoadCSV(strings.NewReader("s,e\n1,2\n10.0.0.1,10.0.0.2\n"), builtins.TableSpec{Key: "s", End: "e", Match: builtins.Range})
+			return err
+		}, "new table: row 2: range bounds must all be IPs or all be numbers"},
+		{"backwards range", func() error {
+			_, err := builtins.LoadCSV(strings.NewReader("s,e\n5,2\n"), builtins.TableSpec{Key: "s", End: "e", Match: builtins.Range})
+			return err
+		}, "new table: row 1: range starts after it ends"},
+		{"overlapping ranges", func() error {
+			_, err := builtins.LoadCSV(strings.NewReader("s,e\n10,20\n1,100\n"), builtins.TableSpec{Key: "s", End: "e", Match: builtins.Range})
+			return err
+		}, "new table: range 10-20 overlaps range 1-100"},
+		{"ranges sharing a bound", func() error {
+			_, err := builtins.LoadCSV(strings.NewReader("s,e\n10.0.0.0,10.0.0.9\n10.0.0.9,10.0.0.20\n"), builtins.TableSpec{Key: "s", End: "e", Match: builtins.Range})
+			return err
+		}, "new table: range 10.0.0.9-10.0.0.20 overlaps range 10.0.0.0-10.0.0.9"},
+		{"json not array", func() error {
+			_, err := builtins.LoadJSON(strings.NewReader(`{"a": 1}`), builtins.TableSpec{Key: "a"})
+			return err
+		}, "load json: expected an array of objects. got=MAP"},
+		{"jsonl not object", func() error {
+			_, err := builtins.LoadJSONL(strings.NewReader("{\"a\": 1}\n[1]\n"), builtins.TableSpec{Key: "a"})
+			return err
+		}, "load jsonl: line 2 is not an object. got=ARRAY"},
+		{"not registered", func() error {
+			return builtins.NewRegistry().AddTable("a", nil)
+		}, "add table: lookup is not registered"},
+	}
+	for _, tt := range tests {
+		err := tt.load()
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.name, failMsg)
+		}
+	}
+}
+
+func row(t *testing.T, doc string) *object.Map {
+	t.Helper()
+	obj, err := object.DecodeJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*object.Map)
+}
@@ -0,0 +1,407 @@
+package builtins
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/utils/mathutils"
+)
+
+// the most decimal places round takes, since decimals are rounded exactly
+const maxRoundDigits = 100
+
+// integers and uintegers are already whole, and decimals stay exact however large they are
+var exactInteger = []object.ObjectType{object.INTEGER_OBJ, object.UINTEGER_OBJ, object.DECIMAL_OBJ}
+
+func registerMath(r *Registry) {
+	number := Param{Name: "value", Types: Number}
+	// aggregate functions accept either a single array or the numbers themselves, like sum([1, 2]) or sum(1, 2)
+	values := Param{Name: "values", Types: append([]object.ObjectType{object.ARRAY_OBJ}, Number...), Variadic: true}
+
+	r.mustRegister(
+		&Function{
+			Name:   "abs",
+			Params: []Param{number},
+			Fn: func(args *Args) (object.Object, error) {
+				switch value := args.Get("value").(type) {
+				case *object.Integer:
+					if value.Value >= 0 {
+						return value, nil
+					}
+					ret, ok := mathutils.NegInt64(value.Value)
+					if !ok {
+						return nil, errIntegerOverflow
+					}
+					return newInteger(ret), nil
+				case *object.UInteger:
+					return value, nil
+				case *object.Decimal:
+					return &object.Decimal{Value: new(big.Rat).Abs(value.Value)}, nil
+				}
+				return newFloat(math.Abs(args.Float("value"))), nil
+			},
+		},
+		&Function{
+			Name:    "floor",
+			Params:  []Param{number},
+			Returns: exactInteger,
+			Fn: func(args *Args) (object.Object, error) {
+				return toWholeNumber(args.Get("value"), math.Floor, floorRat)
+			},
+		},
+		&Function{
+			Name:    "ceil",
+			Params:  []Param{number},
+			Returns: exactInteger,
+			Fn: func(args *Args) (object.Object, error) {
+				return toWholeNumber(args.Get("value"), math.Ceil, ceilRat)
+			},
+		},
+		&Function{
+			Name: "round",
+			Params: []Param{
+				number,
+				// rounds to a whole number when 0, otherwise to this many decimal places
+				{Name: "digits", Types: Integer, Default: newInteger(0)},
+			},
+			Returns: append([]object.ObjectType{object.FLOAT_OBJ}, exactInteger...),
+			Fn: func(args *Args) (object.Object, error) {
+				digits := args.Int("digits")
+				if digits < 0 {
+					return nil, fmt.Errorf("digits cannot be negative. got=%d", digits)
+				}
+				if digits > maxRoundDigits {
+					return nil, fmt.Errorf("digits must be at most %d. got=%d", maxRoundDigits, digits)
+				}
+				switch value := args.Get("value").(type) {
+				case *object.Integer, *object.UInteger:
+					return value, nil
+				case *object.Decimal:
+					ret, _ := object.NumberFromRat(roundRat(value.Value, int(digits)))
+					return ret, nil
+				}
+				if digits == 0 {
+					return floatToInteger(math.Round(args.Float("value")))
+				}
+				value, scale := args.Float("value"), math.Pow10(int(digits))
+				scaled := value * scale
+				if math.IsInf(scaled, 0) || math.Abs(scaled) >= 1<<53 {
+					return newFloat(value), nil // a float this large has no digits that far past the point
+				}
+				return newFloat(math.Round(scaled) / scale), nil
+			},
+		},
+		&Function{
+			Name:   "min",
+			Params: []Param{values},
+			Fn: func(args *Args) (object.Object, error) {
+				return extreme(args, func(order int) bool { return order < 0 })
+			},
+		},
+		&Function{
+			Name:   "max",
+			Params: []Param{values},
+			Fn: func(args *Args) (object.Object, error) {
+				return extreme(args, func(order int) bool { return order > 0 })
+			},
+		},
+		&Function{
+			Name:    "sum",
+			Params:  []Param{values},
+			Returns: Number,
+			Fn:      sum,
+		},
+		&Function{
+			Name:    "avg",
+			Params:  []Param{values},
+			Returns: []object.ObjectType{object.FLOAT_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				nums, err := numericValues(args)
+				if err != nil {
+					return nil, err
+				}
+				if len(nums) == 0 {
+					return nil, errNoValues
+				}
+				total := 0.0
+				for _, n := range nums {
+					total += toFloat(n)
+				}
+				return newFloat(total / float64(len(nums))), nil
+			},
+		},
+		&Function{
+			Name:    "median",
+			Params:  []Param{values},
+			Returns: []object.ObjectType{object.FLOAT_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				return percentile(args, 50)
+			},
+		},
+		&Function{
+			Name: "percentile",
+			Params: []Param{
+				{Name: "values", Types: Array},
+				{Name: "p", Types: Number}, // between 0 and 100
+			},
+			Returns: []object.ObjectType{object.FLOAT_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				p := args.Float("p")
+				if p < 0 || p > 100 {
+					return nil, fmt.Errorf("p must be between 0 and 100. got=%s", args.Get("p").Inspect())
+				}
+				return percentile(args, p)
+			},
+		},
+		&Function{
+			Name:   "clamp",
+			Params: []Param{number, {Name: "min", Types: Number}, {Name: "max", Types: Number}},
+			Fn: func(args *Args) (object.Object, error) {
+				if args.Float("min") > args.Float("max") {
+					return nil, fmt.Errorf("min (%s) is greater than max (%s)", args.Get("min").Inspect(), args.Get("max").Inspect())
+				}
+				switch {
+				case args.Float("value") < args.Float("min"):
+					return args.Get("min"), nil
+				case args.Float("value") > args.Float("max"):
+					return args.Get("max"), nil
+				}
+				return args.Get("value"), nil
+			},
+		},
+		&Function{
+			Name: "log",
+			Params: []Param{
+				number,
+				{Name: "base", Types: Number, Default: newFloat(math.E)},
+			},
+			Returns: []object.ObjectType{object.FLOAT_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				if args.Float("value") <= 0 {
+					return nil, fmt.Errorf("value must be greater than 0. got=%s", args.Get("value").Inspect())
+				}
+				base := args.Float("base")
+				if base <= 0 || base == 1 {
+					return nil, fmt.Errorf("base must be greater than 0 and not 1. got=%s", args.Get("base").Inspect())
+				}
+				return newFloat(math.Log(args.Float("value")) / math.Log(base)), nil
+			},
+		},
+		&Function{
+			Name:    "pow",
+			Params:  []Param{{Name: "base", Types: Number}, {Name: "exponent", Types: Number}},
+			Returns: []object.ObjectType{object.INTEGER_OBJ, object.FLOAT_OBJ},
+			Fn:      pow,
+		},
+		&Function{
+			Name:    "sqrt",
+			Params:  []Param{number},
+			Returns: []object.ObjectType{object.FLOAT_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				if args.Float("value") < 0 {
+					return nil, fmt.Errorf("cannot take the square root of a negative number. got=%s", args.Get("value").Inspect())
+				}
+				return newFloat(math.Sqrt(args.Float("value"))), nil
+			},
+		},
+	)
+}
+
+var (
+	errIntegerOverflow = fmt.Errorf("integer overflow")
+	errNoValues        = fmt.Errorf("no values given")
+)
+
+// flattens the variadic values of an aggregate function, so a single array arg is treated the same as passing its elements
+func numericValues(args *Args) ([]object.Object, error) {
+	values := args.Rest()
+	if arr, ok := args.Get("values").(*object.Array); ok {
+		values = arr.Elements
+	} else if len(values) == 1 {
+		if arr, ok := values[0].(*object.Array); ok {
+			values = arr.Elements
+		}
+	}
+	for idx, v := range values {
+		if !slices.Contains(Number, v.Type()) {
+			return nil, fmt.Errorf("value at index %d must be a number. got=%s", idx, v.Type())
+		}
+	}
+	return values, nil
+}
+
+func extreme(args *Args, isBetter func(order int) bool) (object.Object, error) {
+	nums, err := numericValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return nil, errNoValues
+	}
+	ret := nums[0]
+	for _, n := range nums[1:] {
+		if isBetter(compareNumbers(n, ret)) {
+			ret = n
+		}
+	}
+	return ret, nil
+}
+
+// orders numbers exactly, since every number but NaN and the infinities converts to an exact rational
+func compareNumbers(a, b object.Object) int {
+	if aRat, ok := object.ToRat(a); ok {
+		if bRat, ok := object.ToRat(b); ok {
+			return aRat.Cmp(bRat)
+		}
+	}
+	return cmp.Compare(toFloat(a), toFloat(b))
+}
+
+// adds uintegers and decimals exactly, and stays an integer as long as every value is one, overflowing the same as adding integers does.
+// any float makes the total a float.
+func sum(args *Args) (object.Object, error) {
+	nums, err := numericValues(args)
+	if err != nil {
+		return nil, err
+	}
+	exact := new(big.Rat)
+	floatTotal := 0.0
+	isFloat := false
+	onlyIntegers := true
+	for _, n := range nums {
+		if f, ok := n.(*object.Float); ok {
+			isFloat = true
+			floatTotal += f.Value
+			continue
+		}
+		if n.Type() != object.INTEGER_OBJ {
+			onlyIntegers = false
+		}
+		r, _ := object.ToRat(n)
+		exact.Add(exact, r)
+	}
+	if isFloat {
+		f, _ := exact.Float64()
+		return newFloat(f + floatTotal), nil
+	}
+	if onlyIntegers && !exact.Num().IsInt64() {
+		return nil, errIntegerOverflow
+	}
+	// a sum of integers, uintegers and decimals always has an exact decimal form
+	ret, _ := object.NumberFromRat(exact)
+	return ret, nil
+}
+
+// linearly interpolates between the closest ranks
+func percentile(args *Args, p float64) (object.Object, error) {
+	nums, err := numericValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return nil, errNoValues
+	}
+	sorted := make([]float64, len(nums))
+	for idx, n := range nums {
+		sorted[idx] = toFloat(n)
+	}
+	slices.Sort(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return newFloat(sorted[lower] + (sorted[upper]-sorted[lower])*weight), nil
+}
+
+func pow(args *Args) (object.Object, error) {
+	base, baseIsInt := args.Get("base").(*object.Integer)
+	exp, expIsInt := args.Get("exponent").(*object.Integer)
+	if baseIsInt && expIsInt && exp.Value >= 0 {
+		switch {
+		case exp.Value == 0:
+			return newInteger(1), nil
+		case base.Value == 0 || base.Value == 1:
+			return base, nil
+		case base.Value == -1:
+			return newInteger(1 - 2*(exp.Value%2)), nil
+		}
+		// any other base overflows within 63 multiplications, so this loop stays short
+		ret := int64(1)
+		for range exp.Value {
+			var ok bool
+			ret, ok = mathutils.MulInt64(ret, base.Value)
+			if !ok {
+				return nil, errIntegerOverflow
+			}
+		}
+		return newInteger(ret), nil
+	}
+	ret := math.Pow(args.Float("base"), args.Float("exponent"))
+	if math.IsNaN(ret) || math.IsInf(ret, 0) {
+		return nil, fmt.Errorf("result is not a finite number")
+	}
+	return newFloat(ret), nil
+}
+
+// integers and uintegers are returned as they are, and decimals are rounded exactly to the narrowest type that holds the result
+func toWholeNumber(obj object.Object, float func(float64) float64, exact func(*big.Rat) *big.Rat) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.UInteger:
+		return obj, nil
+	case *object.Decimal:
+		ret, _ := object.NumberFromRat(exact(obj.Value))
+		return ret, nil
+	}
+	return floatToInteger(float(toFloat(obj)))
+}
+
+func floorRat(r *big.Rat) *big.Rat {
+	// the denominator is always positive, so euclidean division rounds down
+	q, _ := new(big.Int).DivMod(r.Num(), r.Denom(), new(big.Int))
+	return new(big.Rat).SetInt(q)
+}
+
+func ceilRat(r *big.Rat) *big.Rat {
+	ret := floorRat(new(big.Rat).Neg(r))
+	return ret.Neg(ret)
+}
+
+// rounds half away from zero like math.Round
+func roundRat(r *big.Rat, digits int) *big.Rat {
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil))
+	ret := new(big.Rat).Mul(new(big.Rat).Abs(r), scale)
+	ret = floorRat(ret.Add(ret, big.NewRat(1, 2)))
+	ret.Quo(ret, scale)
+	if r.Sign() < 0 {
+		ret.Neg(ret)
+	}
+	return ret
+}
+
+func floatToInteger(f float64) (object.Object, error) {
+	if !mathutils.FloatFitsInt64(f) {
+		return nil, errIntegerOverflow
+	}
+	return newInteger(int64(f)), nil
+}
+
+// converts any number type. uintegers and decimals may lose precision, like they do in float arithmetic.
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.UInteger:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	case *object.Decimal:
+		f, _ := obj.Value.Float64()
+		return f
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestMathFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{`abs(-5)`, int64(5)},
+		{`abs(-2.5)`, 2.5},
+		{`floor(2.7)`, int64(2)},
+		{`ceil(2.1)`, int64(3)},
+		{`floor(-2.5)`, int64(-3)},
+		{`round(2.5)`, int64(3)},
+		{`round(3.14159, digits: 2)`, 3.14},
+		{`round(7, digits: 2)`, int64(7)},
+		{`min(3, 1, 2)`, int64(1)},
+		{`max([3, 1.5, 2])`, int64(3)},
+		{`min([2, 0.5])`, 0.5},
+		{`sum(1, 2, 3)`, int64(6)},
+		{`sum([1, 2.5])`, 3.5},
+		{`sum([])`, int64(0)},
+		{`avg([1, 2, 3, 4])`, 2.5},
+		{`median([5, 1, 3])`, 3.0},
+		{`median(4, 1, 3, 2)`, 2.5},
+		{`percentile([1, 2, 3, 4, 5], 90)`, 4.6},
+		{`percentile([10, 20], p: 0)`, 10.0},
+		{`clamp(15, 0, 10)`, int64(10)},
+		{`clamp(-1.5, 0, 10)`, int64(0)},
+		{`clamp(5.5, 0, 10)`, 5.5},
+		{`log(100, base: 10)`, 2.0},
+		{`log(1)`, 0.0},
+		{`pow(2, 10)`, int64(1024)},
+		{`pow(-1, 3)`, int64(-1)},
+		{`pow(2, -1)`, 0.5},
+		{`pow(4, 0.5)`, 2.0},
+		{`sqrt(16)`, 4.0},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		switch want := tt.want.(type) {
+		case int64:
+			testIntegerResult(t, tt.input, got, want)
+		case float64:
+			f, ok := got.(*object.Float)
+			if !ok {
+				t.Errorf("%s: result is not *object.Float. got=%T (%s)", tt.input, got, got.Inspect())
+				continue
+			}
+			if isEq, failMsg := testutils.Equal(want, f.Value); !isEq {
+				t.Errorf("%s: wrong float value: %s", tt.input, failMsg)
+			}
+		}
+	}
+}
+
+// exact numbers keep their type, and aren't rounded through a float
+func TestExactRounding(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`floor(9007199254740993)`, "INTEGER 9007199254740993"},
+		{`ceil(9223372036854775807)`, "INTEGER 9223372036854775807"},
+		{`round(parse_json("18446744073709551615"))`, "UINTEGER 18446744073709551615"},
+		{`abs(parse_json("18446744073709551615"))`, "UINTEGER 18446744073709551615"},
+		{`floor(parse_json("-2.50000000000000000001"))`, "INTEGER -3"},
+		{`ceil(parse_json("2.50000000000000000001"))`, "INTEGER 3"},
+		{`ceil(parse_json("100000000000000000000.5"))`, "DECIMAL 100000000000000000001"},
+		{`round(parse_json("-2.50000000000000000001"))`, "INTEGER -3"},
+		{`round(parse_json("1.23456789012345678901"), digits: 19)`, "DECIMAL 1.234567890123456789"},
+		{`abs(parse_json("-0.10000000000000000001"))`, "DECIMAL 0.10000000000000000001"},
+		{`round(1.5, digits: 100)`, "FLOAT 1.5"},
+		{`round(parse_json("1e300"), digits: 20) == parse_json("1e300")`, "BOOLEAN true"},
+		{`sum(parse_json("18446744073709551615"), -1)`, "UINTEGER 18446744073709551614"},
+		{`sum(parse_json("[0.10000000000000000001, 0.20000000000000000001]"))`, "DECIMAL 0.30000000000000000002"},
+		{`sum(parse_json("18446744073709551615"), 1)`, "DECIMAL 18446744073709551616"},
+		{`sum(parse_json("18446744073709551615"), -9223372036854775807, -9223372036854775807)`, "INTEGER 1"},
+		{`max(parse_json("18446744073709551615"), parse_json("18446744073709551614"))`, "UINTEGER 18446744073709551615"},
+		{`min(parse_json("[0.10000000000000000002, 0.10000000000000000001]"))`, "DECIMAL 0.10000000000000000001"},
+		{`max(9007199254740993, 9007199254740992.0)`, "INTEGER 9007199254740993"},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, string(got.Type())+" "+got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestMathErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`9223372036854775807 + 1`, "integer overflow: 9223372036854775807 + 1"},
+		{`3037000500 * 3037000500`, "integer overflow: 3037000500 * 3037000500"},
+		{`5 / 0`, "division by zero"},
+		{`5.0 / 0`, "division by zero"},
+		{`sum(9223372036854775807, 1)`, "sum: integer overflow"},
+		{`pow(10, 19)`, "pow: integer overflow"},
+		{`avg([])`, "avg: no values given"},
+		{`max([1, "a"])`, "max: value at index 1 must be a number. got=STRING"},
+		{`sqrt(-4)`, "sqrt: cannot take the square root of a negative number. got=-4"},
+		{`log(0)`, "log: value must be greater than 0. got=0"},
+		{`percentile([1], 101)`, "percentile: p must be between 0 and 100. got=101"},
+		{`clamp(1, 10, 0)`, "clamp: min (10) is greater than max (0)"},
+		{`round(1.5, digits: -1)`, "round: digits cannot be negative. got=-1"},
+		{`round(1.5, digits: 400)`, "round: digits must be at most 100. got=400"},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
@@ -0,0 +1,150 @@
+package builtins
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// patterns coming from data instead of literals could grow the cache forever, so stop adding entries past this size.
+const maxCachedPatterns = 1024
+
+// compiled patterns shared by every call in a registry. patterns from string literals are
+// compiled once by the Precompile hook, so evaluating a record only has to do a map lookup.
+type regexCache struct {
+	mu       sync.RWMutex
+	patterns map[string]*regexp.Regexp
+}
+
+func newRegexCache() *regexCache {
+	return &regexCache{patterns: make(map[string]*regexp.Regexp)}
+}
+
+func (c *regexCache) get(pattern string) (*regexp.Regexp, error) {
+	c.mu.RLock()
+	re, ok := c.patterns[pattern]
+	c.mu.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if len(c.patterns) < maxCachedPatterns {
+		c.patterns[pattern] = re
+	}
+	c.mu.Unlock()
+	return re, nil
+}
+
+func (c *regexCache) precompile(pattern string) error {
+	_, err := c.get(pattern)
+	return err
+}
+
+func registerRegex(r *Registry) {
+	cache := newRegexCache()
+	pattern := Param{Name: "pattern", Types: String, Precompile: cache.precompile}
+
+	r.mustRegister(
+		&Function{
+			Name:    "match",
+			Params:  []Param{{Name: "value", Types: String}, pattern},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				re, err := cache.get(args.String("pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return newBoolean(re.MatchString(args.String("value"))), nil
+			},
+		},
+		&Function{
+			Name: "find_all",
+			Params: []Param{
+				{Name: "value", Types: String},
+				pattern,
+				{Name: "limit", Types: Integer, Default: newInteger(-1)},
+			},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				re, err := cache.get(args.String("pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return newStringArray(re.FindAllString(args.String("value"), int(args.Int("limit")))), nil
+			},
+		},
+		&Function{
+			Name:    "capture",
+			Params:  []Param{{Name: "value", Types: String}, pattern},
+			Returns: []object.ObjectType{object.MAP_OBJ, object.NULL_OBJ},
+			Fn: func(args *Args) (object.Object, error) {
+				re, err := cache.get(args.String("pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return captureGroups(re, args.String("value")), nil
+			},
+		},
+		&Function{
+			Name: "replace_regex",
+			Params: []Param{
+				{Name: "value", Types: String},
+				pattern,
+				{Name: "replacement", Types: String}, // supports $1 and ${name} group references
+			},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				re, err := cache.get(args.String("pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return newString(re.ReplaceAllString(args.String("value"), args.String("replacement"))), nil
+			},
+		},
+		&Function{
+			Name: "split_regex",
+			Params: []Param{
+				{Name: "value", Types: String},
+				pattern,
+				{Name: "limit", Types: Integer, Default: newInteger(-1)},
+			},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				re, err := cache.get(args.String("pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return newStringArray(re.Split(args.String("value"), int(args.Int("limit")))), nil
+			},
+		},
+	)
+}
+
+// returns a map of named group to matched text, or null when the pattern doesn't match.
+// named groups that didn't participate in the match are set to null.
+func captureGroups(re *regexp.Regexp, value string) object.Object {
+	idxs := re.FindStringSubmatchIndex(value)
+	if idxs == nil {
+		return &object.Null{}
+	}
+	ret := object.NewMap()
+	for groupIdx, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		start, end := idxs[groupIdx*2], idxs[groupIdx*2+1]
+		if start < 0 {
+			ret.Pairs[name] = &object.Null{}
+			continue
+		}
+		ret.Pairs[name] = newString(value[start:end])
+	}
+	return ret
+}
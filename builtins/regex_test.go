@@ -0,0 +1,89 @@
+package builtins_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestRegexFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`join(find_all("a1b22c333", "[0-9]+"), ",")`, "1,22,333"},
+		{`join(find_all("a1b22c333", "[0-9]+", limit: 2), ",")`, "1,22"},
+		{`replace_regex("2024-01-02", "(\d+)-(\d+)-(\d+)", "$3/$2/$1")`, "02/01/2024"},
+		{`replace_regex("user=bob", "user=(?P<name>\w+)", "${name}!")`, "bob!"},
+		{`join(split_regex("a, b;c", "[,;]\s*"), "|")`, "a|b|c"},
+		{"m = capture(\"GET /index.html 200\", \"(?P<method>\\w+) (?P<path>\\S+) (?P<status>\\d+)\")\n m.method + \" \" + m.path", "GET /index.html"},
+	}
+	for _, tt := range tests {
+		testStringResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestRegexMatch(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`match("error: disk full", "^error")`, true},
+		{`match("warn: disk full", "^error")`, false},
+		{`capture("abc", "(?P<num>\d+)") == null`, true},
+		{`"GET /" | capture("(?P<method>[A-Z]+)") | len == 1`, true},
+		{`capture("abc", "(?P<num>\d+)?(?P<word>\w+)").num == null`, true},
+	}
+	for _, tt := range tests {
+		got, ok := evalInput(t, tt.input).(*object.Boolean)
+		if !ok {
+			t.Fatalf("%s: result is not *object.Boolean. got=%T", tt.input, got)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, got.Value); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestRegexInvalidPattern(t *testing.T) {
+	input := "p = \"(unclosed\"\nmatch(\"abc\", p)"
+	got := evalInput(t, input)
+	errObj, ok := got.(*object.Error)
+	if !ok {
+		t.Fatalf("result is not *object.Error. got=%T", got)
+	}
+	if !strings.HasPrefix(errObj.Message, "match: error parsing regexp") {
+		t.Errorf("expected a regex parsing error. got=%s", errObj.Message)
+	}
+}
+
+func TestPrepareReportsInvalidLiteralPattern(t *testing.T) {
+	input := "a = 1\nb = \"x\" | match(\"[a-\")"
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = evaluator.New(l.InputRunes()).Prepare(program)
+	if err == nil {
+		t.Fatal("expected Prepare to report the invalid pattern. got no error")
+	}
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("err is not *object.Error. got=%T", err)
+	}
+	if !strings.HasPrefix(errObj.Message, "match: invalid pattern") {
+		t.Errorf("expected an invalid pattern error. got=%s", errObj.Message)
+	}
+	if isEq, failMsg := testutils.Equal(2, errObj.Line); !isEq {
+		t.Errorf("wrong error line: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal(17, errObj.Column); !isEq {
+		t.Errorf("wrong error column: %s", failMsg)
+	}
+}
@@ -0,0 +1,244 @@
+package builtins
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+func registerStrings(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name:    "upper",
+			Params:  []Param{{Name: "value", Types: String}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(strings.ToUpper(args.String("value"))), nil
+			},
+		},
+		&Function{
+			Name:    "lower",
+			Params:  []Param{{Name: "value", Types: String}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(strings.ToLower(args.String("value"))), nil
+			},
+		},
+		&Function{
+			Name: "trim",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "chars", Types: String, Default: newString("")}, // empty trims whitespace
+			},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				if args.String("chars") == "" {
+					return newString(strings.TrimSpace(args.String("value"))), nil
+				}
+				return newString(strings.Trim(args.String("value"), args.String("chars"))), nil
+			},
+		},
+		&Function{
+			Name:    "trim_prefix",
+			Params:  []Param{{Name: "value", Types: String}, {Name: "prefix", Types: String}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(strings.TrimPrefix(args.String("value"), args.String("prefix"))), nil
+			},
+		},
+		&Function{
+			Name:    "trim_suffix",
+			Params:  []Param{{Name: "value", Types: String}, {Name: "suffix", Types: String}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(strings.TrimSuffix(args.String("value"), args.String("suffix"))), nil
+			},
+		},
+		&Function{
+			Name: "split",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "sep", Types: String},
+				{Name: "limit", Types: Integer, Default: newInteger(-1)},
+			},
+			Returns: Array,
+			Fn: func(args *Args) (object.Object, error) {
+				parts := strings.SplitN(args.String("value"), args.String("sep"), int(args.Int("limit")))
+				return newStringArray(parts), nil
+			},
+		},
+		&Function{
+			Name:    "join",
+			Params:  []Param{{Name: "values", Types: Array}, {Name: "sep", Types: String, Default: newString("")}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				parts := []string{}
+				for _, el := range args.Array("values") {
+					parts = append(parts, el.Inspect())
+				}
+				return newString(strings.Join(parts, args.String("sep"))), nil
+			},
+		},
+		&Function{
+			Name: "replace",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "old", Types: String},
+				{Name: "new", Types: String},
+				{Name: "count", Types: Integer, Default: newInteger(-1)}, // negative replaces every match
+			},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(strings.Replace(args.String("value"), args.String("old"), args.String("new"), int(args.Int("count")))), nil
+			},
+		},
+		&Function{
+			Name:    "contains",
+			Params:  []Param{{Name: "value", Types: String}, {Name: "substr", Types: String}},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				return newBoolean(strings.Contains(args.String("value"), args.String("substr"))), nil
+			},
+		},
+		&Function{
+			Name:    "starts_with",
+			Params:  []Param{{Name: "value", Types: String}, {Name: "prefix", Types: String}},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				return newBoolean(strings.HasPrefix(args.String("value"), args.String("prefix"))), nil
+			},
+		},
+		&Function{
+			Name:    "ends_with",
+			Params:  []Param{{Name: "value", Types: String}, {Name: "suffix", Types: String}},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				return newBoolean(strings.HasSuffix(args.String("value"), args.String("suffix"))), nil
+			},
+		},
+		&Function{
+			Name: "substr",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "start", Types: Integer},                           // negative counts back from the end
+				{Name: "length", Types: Integer, Default: newInteger(-1)}, // negative takes the rest of the string
+			},
+			Returns: String,
+			Fn:      substr,
+		},
+		&Function{
+			Name: "pad_left",
+			Params: []Param{
+				{Name: "value", Types: String},
+				{Name: "width", Types: Integer},
+				{Name: "pad", Types: String, Default: newString(" ")},
+			},
+			Returns: String,
+			Fn:      padLeft,
+		},
+		&Function{
+			Name:    "repeat",
+			Params:  []Param{{Name: "value", Types: String}, {Name: "count", Types: Integer}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				value, count := args.String("value"), args.Int("count")
+				if count < 0 {
+					return nil, fmt.Errorf("count cannot be negative. got=%d", count)
+				}
+				if count > 0 && int64(len(value)) > maxStringLength/count {
+					return nil, fmt.Errorf("result would be longer than %d bytes", maxStringLength)
+				}
+				return newString(strings.Repeat(value, int(count))), nil
+			},
+		},
+		&Function{
+			Name:    "len",
+			Params:  []Param{{Name: "value", Types: []object.ObjectType{object.STRING_OBJ, object.ARRAY_OBJ, object.MAP_OBJ}}},
+			Returns: Integer,
+			Fn: func(args *Args) (object.Object, error) {
+				switch v := args.Get("value").(type) {
+				case *object.String:
+					return newInteger(int64(utf8.RuneCountInString(v.Value))), nil
+				case *object.Array:
+					return newInteger(int64(len(v.Elements))), nil
+				case *object.Map:
+					return newInteger(int64(len(v.Pairs))), nil
+				}
+				return nil, fmt.Errorf("unsupported type %s", args.Get("value").Type())
+			},
+		},
+		&Function{
+			Name:    "format",
+			Params:  []Param{{Name: "template", Types: String}, {Name: "values", Variadic: true}},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				values := []any{}
+				for _, obj := range args.Rest() {
+					values = append(values, toFormatValue(obj))
+				}
+				return newString(fmt.Sprintf(args.String("template"), values...)), nil
+			},
+		},
+	)
+}
+
+// results like repeat and pad_left build are capped, so a typo like repeat(x, 10000000000) fails instead of exhausting memory
+const maxStringLength = 1 << 24
+
+func substr(args *Args) (object.Object, error) {
+	runes := []rune(args.String("value"))
+	start := int(args.Int("start"))
+	if start < 0 {
+		start = max(len(runes)+start, 0)
+	}
+	start = min(start, len(runes))
+
+	end := len(runes)
+	if length := args.Int("length"); length >= 0 {
+		// clamped before adding, so a huge length can't overflow
+		end = start + int(min(length, int64(len(runes)-start)))
+	}
+	return newString(string(runes[start:end])), nil
+}
+
+func padLeft(args *Args) (object.Object, error) {
+	value := args.String("value")
+	pad := []rune(args.String("pad"))
+	if len(pad) == 0 {
+		return nil, fmt.Errorf("pad cannot be empty")
+	}
+	width := args.Int("width")
+	if width > maxStringLength {
+		return nil, fmt.Errorf("width must be at most %d. got=%d", maxStringLength, width)
+	}
+	missing := int(width) - utf8.RuneCountInString(value)
+	if missing <= 0 {
+		return newString(value), nil
+	}
+	padding := make([]rune, missing)
+	for idx := range padding {
+		padding[idx] = pad[idx%len(pad)]
+	}
+	return newString(string(padding) + value), nil
+}
+
+// unwraps objects into go values so verbs like %d and %.2f work as expected.
+func toFormatValue(obj object.Object) any {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value
+	case *object.Integer:
+		return obj.Value
+	case *object.UInteger:
+		return obj.Value
+	case *object.Float:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return nil
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,156 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestStringFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`upper("abc")`, "ABC"},
+		{`lower("ABC")`, "abc"},
+		{`trim("  abc	 ")`, "abc"},
+		{`trim("--abc-", chars: "-")`, "abc"},
+		{`trim_prefix("prefix_abc", "prefix_")`, "abc"},
+		{`trim_suffix("abc.log", ".log")`, "abc"},
+		{`join(split("a,b,c", ","), "|")`, "a|b|c"},
+		{`join(split("a,b,c", ",", limit: 2), "|")`, "a|b,c"},
+		{`join(["a", 1, true], "-")`, "a-1-true"},
+		{`replace("a.b.c", ".", "_")`, "a_b_c"},
+		{`replace("a.b.c", ".", "_", count: 1)`, "a_b.c"},
+		{`substr("héllo wörld", 6)`, "wörld"},
+		{`substr("héllo wörld", 1, 4)`, "éllo"},
+		{`substr("héllo", -3)`, "llo"},
+		{`substr("héllo", 10)`, ""},
+		{`substr("abc", 1, 9223372036854775807)`, "bc"},
+		{`substr("abc", 1, 0)`, ""},
+		{`pad_left("7", 3, pad: "0")`, "007"},
+		{`pad_left("1234", 3)`, "1234"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`format("%s=%d (%.1f%%)", "hits", 5, 12.34)`, "hits=5 (12.3%)"},
+		{`"  MiXeD " | trim() | lower`, "mixed"},
+		{`"a-b" | replace("-", "+")`, "a+b"},
+	}
+	for _, tt := range tests {
+		testStringResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestStringPredicates(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`contains("hello", "ell")`, true},
+		{`contains("hello", "xyz")`, false},
+		{`starts_with("hello", "he")`, true},
+		{`ends_with("hello", "he")`, false},
+		{`"hello" | ends_with("lo")`, true},
+	}
+	for _, tt := range tests {
+		got, ok := evalInput(t, tt.input).(*object.Boolean)
+		if !ok {
+			t.Fatalf("%s: result is not *object.Boolean. got=%T", tt.input, got)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, got.Value); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{`len("héllo")`, 5},
+		{`len(["a", "b"])`, 2},
+		{`len(split("a b c", " "))`, 3},
+	}
+	for _, tt := range tests {
+		testIntegerResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestStringFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`repeat("a", -1)`, "repeat: count cannot be negative. got=-1"},
+		{`pad_left("a", 3, pad: "")`, "pad_left: pad cannot be empty"},
+		{`pad_left("a", 9223372036854775807)`, "pad_left: width must be at most 16777216. got=9223372036854775807"},
+		{`repeat("ab", 10000000000)`, "repeat: result would be longer than 16777216 bytes"},
+		{`repeat("ab", 9223372036854775807)`, "repeat: result would be longer than 16777216 bytes"},
+		{`upper(1)`, `argument "value" for upper must be STRING. got=INTEGER`},
+		{`1 | upper`, `argument "value" for upper must be STRING. got=INTEGER`},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+//
+// shared helpers for the library tests
+//
+
+func evalInput(t *testing.T, input string) object.Object {
+	t.Helper()
+	l := lexer.New([]rune(input))
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("evalInput: %s", err.Error())
+	}
+	return evaluator.New(l.InputRunes()).Eval(program, object.NewEnvironment())
+}
+
+func testStringResult(t *testing.T, input string, obj object.Object, want string) bool {
+	t.Helper()
+	got, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("%s: result is not *object.String. got=%T (%s)", input, obj, obj.Inspect())
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, got.Value); !isEq {
+		t.Errorf("%s: wrong string value: %s", input, failMsg)
+		return false
+	}
+	return true
+}
+
+func testIntegerResult(t *testing.T, input string, obj object.Object, want int64) bool {
+	t.Helper()
+	got, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("%s: result is not *object.Integer. got=%T (%s)", input, obj, obj.Inspect())
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, got.Value); !isEq {
+		t.Errorf("%s: wrong integer value: %s", input, failMsg)
+		return false
+	}
+	return true
+}
+
+func testErrorResult(t *testing.T, input string, obj object.Object, want string) bool {
+	t.Helper()
+	got, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("%s: result is not *object.Error. got=%T (%s)", input, obj, obj.Inspect())
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, got.Message); !isEq {
+		t.Errorf("%s: wrong error message: %s", input, failMsg)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package builtins
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+func registerSyslog(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name: "parse_syslog",
+			Params: []Param{
+				{Name: "value", Types: String},
+				// RFC 3164 timestamps have no year or offset, so they're read as the current year in this timezone
+				{Name: "timezone", Types: String, Default: newString("UTC"), Precompile: timezones.precompile},
+			},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				loc, err := timezones.get(args.String("timezone"))
+				if err != nil {
+					return nil, err
+				}
+				return parseSyslog(args.String("value"), loc)
+			},
+		},
+	)
+}
+
+// returns a map with the priority parts, timestamp, hostname, app_name, proc_id, msg_id, structured_data and message.
+// fields a format doesn't have, or that are sent as the - nil value, are null.
+func parseSyslog(value string, loc *time.Location) (object.Object, error) {
+	if !strings.HasPrefix(value, "<") {
+		return nil, fmt.Errorf("message must start with a <priority>")
+	}
+	end := strings.IndexByte(value, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("invalid priority")
+	}
+	// only 1 to 3 ascii digits, so signs like <-1> or <+1> are rejected before they can index the name tables
+	pri, err := strconv.Atoi(value[1:end])
+	if err != nil || !isDigits(value[1:end]) || pri < 0 || pri > 191 {
+		return nil, fmt.Errorf("invalid priority %q", value[1:end])
+	}
+
+	ret := object.NewMap()
+	for _, field := range []string{"version", "timestamp", "hostname", "app_name", "proc_id", "msg_id", "structured_data", "message"} {
+		ret.Pairs[field] = &object.Null{}
+	}
+	ret.Pairs["priority"] = newInteger(int64(pri))
+	ret.Pairs["facility"] = newInteger(int64(pri / 8))
+	ret.Pairs["facility_name"] = newString(syslogFacilities[pri/8])
+	ret.Pairs["severity"] = newInteger(int64(pri % 8))
+	ret.Pairs["severity_name"] = newString(syslogSeverities[pri%8])
+
+	rest := value[end+1:]
+	if version, after, found := strings.Cut(rest, " "); found && version != "" && isDigits(version) {
+		ret.Pairs["format"] = newString("rfc5424")
+		v, _ := strconv.Atoi(version)
+		ret.Pairs["version"] = newInteger(int64(v))
+		return ret, parseRFC5424(after, ret)
+	}
+	ret.Pairs["format"] = newString("rfc3164")
+	return ret, parseRFC3164(rest, loc, ret)
+}
+
+func parseRFC5424(value string, ret *object.Map) error {
+	header := []string{"timestamp", "hostname", "app_name", "proc_id", "msg_id"}
+	for _, field := range header {
+		part, rest, found := strings.Cut(value, " ")
+		if !found && field != header[len(header)-1] {
+			return fmt.Errorf("message is missing the %s field", field)
+		}
+		value = rest
+		if part == "-" {
+			continue
+		}
+		if field == "timestamp" {
+			t, err := time.Parse(time.RFC3339Nano, part)
+			if err != nil {
+				return fmt.Errorf("invalid timestamp %q", part)
+			}
+			ret.Pairs[field] = &object.Time{Value: t}
+			continue
+		}
+		ret.Pairs[field] = newString(part)
+	}
+
+	switch {
+	case strings.HasPrefix(value, "-"):
+		value = value[1:]
+	case strings.HasPrefix(value, "["):
+		sd, rest, err := parseStructuredData(value)
+		if err != nil {
+			return err
+		}
+		ret.Pairs["structured_data"] = sd
+		value = rest
+	case value != "":
+		return fmt.Errorf("invalid structured data")
+	}
+	if msg := strings.TrimPrefix(value, " "); msg != "" {
+		// a leading byte order mark marks the message as utf-8, and isn't part of it
+		ret.Pairs["message"] = newString(strings.TrimPrefix(msg, "\ufeff"))
+	}
+	return nil
+}
+
+// parses elements like [id key="value" key2="value"][id2] into a map of element id to a map of its params,
+// and returns whatever follows them.
+func parseStructuredData(value string) (*object.Map, string, error) {
+	ret := object.NewMap()
+	for strings.HasPrefix(value, "[") {
+		idEnd := strings.IndexAny(value, " ]")
+		if idEnd < 0 {
+			return nil, "", fmt.Errorf("unterminated structured data element")
+		}
+		params := object.NewMap()
+		ret.Pairs[value[1:idEnd]] = params
+		value = value[idEnd:]
+
+		for strings.HasPrefix(value, " ") {
+			value = value[1:]
+			name, rest, found := strings.Cut(value, `="`)
+			if !found {
+				return nil, "", fmt.Errorf("invalid structured data param %q", value)
+			}
+			var sb strings.Builder
+			idx := 0
+			for ; idx < len(rest) && rest[idx] != '"'; idx++ {
+				// only ", \ and ] are escaped
+				if rest[idx] == '\\' && idx+1 < len(rest) && strings.ContainsRune(`"\]`, rune(rest[idx+1])) {
+					idx++
+				}
+				sb.WriteByte(rest[idx])
+			}
+			if idx >= len(rest) {
+				return nil, "", fmt.Errorf("unterminated value for structured data param %q", name)
+			}
+			params.Pairs[name] = newString(sb.String())
+			value = rest[idx+1:]
+		}
+		if !strings.HasPrefix(value, "]") {
+			return nil, "", fmt.Errorf("unterminated structured data element")
+		}
+		value = value[1:]
+	}
+	return ret, value, nil
+}
+
+// like: Oct 11 22:14:15 mymachine su[123]: 'su root' failed
+func parseRFC3164(value string, loc *time.Location, ret *object.Map) error {
+	const layout = "Jan _2 15:04:05"
+	if len(value) < len(layout) {
+		return fmt.Errorf("message is missing the timestamp")
+	}
+	t, err := time.ParseInLocation(layout, value[:len(layout)], loc)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", value[:len(layout)])
+	}
+	t = t.AddDate(time.Now().In(loc).Year(), 0, 0)
+	ret.Pairs["timestamp"] = &object.Time{Value: t}
+	value = strings.TrimPrefix(value[len(layout):], " ")
+
+	hostname, rest, _ := strings.Cut(value, " ")
+	if hostname != "" {
+		ret.Pairs["hostname"] = newString(hostname)
+	}
+
+	// the tag is optional, so only treat the next word as one when it ends with a colon
+	tagEnd := strings.Index(rest, ": ")
+	if word, _, _ := strings.Cut(rest, " "); tagEnd > 0 && tagEnd < len(word) {
+		tag := rest[:tagEnd]
+		if name, pid, found := strings.Cut(tag, "["); found && strings.HasSuffix(pid, "]") {
+			ret.Pairs["proc_id"] = newString(strings.TrimSuffix(pid, "]"))
+			tag = name
+		}
+		ret.Pairs["app_name"] = newString(tag)
+		rest = rest[tagEnd+2:]
+	}
+	if rest != "" {
+		ret.Pairs["message"] = newString(rest)
+	}
+	return nil
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
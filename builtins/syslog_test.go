@@ -0,0 +1,92 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{
+			`parse_syslog('<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Appl\]ication"][examplePriority@32473 class="high"] An application event')`,
+			`{"app_name": evntslog, "facility": 20, "facility_name": local4, "format": rfc5424, "hostname": mymachine.example.com, "message": An application event, "msg_id": ID47, "priority": 165, "proc_id": null, "severity": 5, "severity_name": notice, "structured_data": {"examplePriority@32473": {"class": high}, "exampleSDID@32473": {"eventSource": Appl]ication, "iut": 3}}, "timestamp": 2003-10-11T22:14:15.003Z, "version": 1}`,
+		},
+		{
+			`parse_syslog("<34>1 - - - - - -")`,
+			`{"app_name": null, "facility": 4, "facility_name": auth, "format": rfc5424, "hostname": null, "message": null, "msg_id": null, "priority": 34, "proc_id": null, "severity": 2, "severity_name": crit, "structured_data": null, "timestamp": null, "version": 1}`,
+		},
+		{
+			`s = parse_syslog("<34>Oct  1 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8")
+			[s.hostname, s.app_name, s.proc_id, s.message, s.format, format_time(s.timestamp, format: "%m-%d %H:%M:%S")]`,
+			`[mymachine, su, 230, 'su root' failed for lonvick on /dev/pts/8, rfc3164, 10-01 22:14:15]`,
+		},
+		{
+			`s = parse_syslog("<13>Feb 28 09:00:00 host plain message: with colon")
+			[s.app_name, s.message]`,
+			`[null, plain message: with colon]`,
+		},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestParseCEFAndLEEF(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{
+			`parse_cef("<134>Oct  1 22:14:15 fw CEF:0|Secure\|Corp|Firewall|2.1|100|Blocked connection|7|src=10.0.0.1 dst=10.0.0.2 msg=Denied by rule a\=b spt=443")`,
+			`{"device_product": Firewall, "device_vendor": Secure|Corp, "device_version": 2.1, "extensions": {"dst": 10.0.0.2, "msg": Denied by rule a=b, "spt": 443, "src": 10.0.0.1}, "name": Blocked connection, "severity": 7, "signature_id": 100, "version": 0}`,
+		},
+		{
+			`parse_cef("CEF:1|V|P|1|sig|name|High|")`,
+			`{"device_product": P, "device_vendor": V, "device_version": 1, "extensions": {}, "name": name, "severity": High, "signature_id": sig, "version": 1}`,
+		},
+		{
+			"parse_leef(\"LEEF:1.0|Microsoft|MSExchange|4.0|15345|src=10.50.1.1\tdst=2.10.20.20\tusrName=kim\")",
+			`{"attributes": {"dst": 2.10.20.20, "src": 10.50.1.1, "usrName": kim}, "event_id": 15345, "product": MSExchange, "product_version": 4.0, "vendor": Microsoft, "version": 1.0}`,
+		},
+		{
+			`parse_leef("LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5")`,
+			`{"attributes": {"dst": 10.0.0.5, "sev": 5, "src": 10.0.1.8}, "event_id": 41, "product": StealthWatch, "product_version": 1.0, "vendor": Lancope, "version": 2.0}`,
+		},
+		{
+			`parse_leef("LEEF:2.0|V|P|1.0|41|x7C|a=1|b=2").attributes`,
+			`{"a": 1, "b": 2}`,
+		},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestSecurityFormatErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`parse_syslog("no priority")`, "parse_syslog: message must start with a <priority>"},
+		{`parse_syslog("<999>1 -")`, `parse_syslog: invalid priority "999"`},
+		{`parse_syslog("<-1>Oct 11 22:14:15 host su: hi")`, `parse_syslog: invalid priority "-1"`},
+		{`parse_syslog("<+1>Oct 11 22:14:15 host su: hi")`, `parse_syslog: invalid priority "+1"`},
+		{`parse_syslog("<34>1 yesterday host app - - -")`, `parse_syslog: invalid timestamp "yesterday"`},
+		{`parse_syslog('<34>1 - - - - - [id a="1"')`, "parse_syslog: unterminated structured data element"},
+		{`parse_cef("CEF:0|only|three")`, "parse_cef: header has 2 of the 7 required fields"},
+		{`parse_leef("LEEF:2.0|V|P|1.0|41|xZZ|a=1")`, `parse_leef: invalid attribute delimiter "xZZ"`},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
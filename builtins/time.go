@@ -0,0 +1,322 @@
+package builtins
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/utils/mathutils"
+)
+
+// tried in order when parse_time isn't given a format
+var autoTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.UnixDate,
+	time.ANSIC,
+}
+
+var epochFormats = map[string]time.Duration{
+	"epoch_s":  time.Second,
+	"epoch_ms": time.Millisecond,
+	"epoch_us": time.Microsecond,
+	"epoch_ns": time.Nanosecond,
+}
+
+// the range of times RFC 3339 can format, so any time parsed from an epoch can be printed again
+const (
+	minEpochSeconds = -62135596800 // 0001-01-01T00:00:00Z
+	maxEpochSeconds = 253402300799 // 9999-12-31T23:59:59Z
+)
+
+var errEpochRange = fmt.Errorf("epoch timestamp is out of range: it must be between the years 1 and 9999")
+
+// loaded timezones shared by every call, since time.LoadLocation reads the timezone database each time.
+// only timezones that exist are cached, so names coming from data can't grow it past the size of the database.
+type locationCache struct {
+	mu        sync.RWMutex
+	locations map[string]*time.Location
+}
+
+var timezones = &locationCache{locations: make(map[string]*time.Location)}
+
+func (c *locationCache) get(name string) (*time.Location, error) {
+	c.mu.RLock()
+	loc, ok := c.locations[name]
+	c.mu.RUnlock()
+	if ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+
+	c.mu.Lock()
+	c.locations[name] = loc
+	c.mu.Unlock()
+	return loc, nil
+}
+
+func (c *locationCache) precompile(name string) error {
+	_, err := c.get(name)
+	return err
+}
+
+func registerTime(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name:    "now",
+			Returns: Time,
+			Fn: func(args *Args) (object.Object, error) {
+				return &object.Time{Value: time.Now().UTC()}, nil
+			},
+		},
+		&Function{
+			Name: "parse_time",
+			Params: []Param{
+				{Name: "value", Types: []object.ObjectType{object.STRING_OBJ, object.INTEGER_OBJ, object.FLOAT_OBJ}},
+				// strftime (%Y-%m-%d), go layout (2006-01-02), or epoch_s/epoch_ms/epoch_us/epoch_ns. empty tries common formats.
+				{Name: "format", Types: String, Default: newString("")},
+				// used when the value doesn't carry its own offset
+				{Name: "timezone", Types: String, Default: newString("UTC"), Precompile: timezones.precompile},
+			},
+			Returns: Time,
+			Fn:      parseTime,
+		},
+		&Function{
+			Name: "format_time",
+			Params: []Param{
+				{Name: "time", Types: Time},
+				{Name: "format", Types: String, Default: newString(time.RFC3339Nano)},
+			},
+			Returns: String,
+			Fn:      formatTime,
+		},
+		&Function{
+			Name:    "to_timezone",
+			Params:  []Param{{Name: "time", Types: Time}, {Name: "timezone", Types: String, Precompile: timezones.precompile}},
+			Returns: Time,
+			Fn: func(args *Args) (object.Object, error) {
+				loc, err := timezones.get(args.String("timezone"))
+				if err != nil {
+					return nil, err
+				}
+				return &object.Time{Value: args.Time("time").In(loc)}, nil
+			},
+		},
+		&Function{
+			Name: "truncate",
+			Params: []Param{
+				{Name: "time", Types: Time},
+				// a calendar unit (second, minute, hour, day, week, month, year), a duration string like 15m, or a duration
+				{Name: "unit", Types: []object.ObjectType{object.STRING_OBJ, object.DURATION_OBJ}},
+			},
+			Returns: Time,
+			Fn:      truncateTime,
+		},
+		&Function{
+			Name:    "duration",
+			Params:  []Param{{Name: "value", Types: String}}, // like 1h30m or 250ms
+			Returns: Duration,
+			Fn: func(args *Args) (object.Object, error) {
+				d, err := time.ParseDuration(args.String("value"))
+				if err != nil {
+					return nil, fmt.Errorf("%q is not a valid duration", args.String("value"))
+				}
+				return &object.Duration{Value: d}, nil
+			},
+		},
+	)
+}
+
+func parseTime(args *Args) (object.Object, error) {
+	format := args.String("format")
+	loc, err := timezones.get(args.String("timezone"))
+	if err != nil {
+		return nil, err
+	}
+
+	if unit, ok := epochFormats[format]; ok {
+		return parseEpoch(args.Get("value"), unit)
+	}
+
+	value, ok := args.Get("value").(*object.String)
+	if !ok {
+		return nil, fmt.Errorf("numeric values need an epoch format. got format=%q", format)
+	}
+
+	if format == "" {
+		for _, layout := range autoTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value.Value, loc); err == nil {
+				return &object.Time{Value: t}, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not in a recognized time format", value.Value)
+	}
+
+	layout, err := toGoLayout(format)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.ParseInLocation(layout, value.Value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%q does not match format %q", value.Value, format)
+	}
+	return &object.Time{Value: t}, nil
+}
+
+// whole seconds are split off before scaling to nanoseconds, so a timestamp far from 1970 can't overflow a duration
+func parseEpoch(value object.Object, unit time.Duration) (object.Object, error) {
+	perSecond := int64(time.Second / unit)
+	var num float64
+	switch value := value.(type) {
+	case *object.Integer:
+		return epochTime(value.Value/perSecond, value.Value%perSecond*int64(unit))
+	case *object.Float:
+		num = value.Value
+	case *object.String:
+		if i, err := strconv.ParseInt(value.Value, 10, 64); err == nil {
+			return epochTime(i/perSecond, i%perSecond*int64(unit))
+		}
+		f, err := strconv.ParseFloat(value.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an epoch timestamp", value.Value)
+		}
+		num = f
+	}
+	whole, frac := math.Modf(num)
+	if !mathutils.FloatFitsInt64(whole) {
+		return nil, errEpochRange
+	}
+	secs, rem := int64(whole)/perSecond, int64(whole)%perSecond
+	return epochTime(secs, rem*int64(unit)+int64(frac*float64(unit)))
+}
+
+func epochTime(secs int64, nanos int64) (object.Object, error) {
+	if secs < minEpochSeconds || secs > maxEpochSeconds {
+		return nil, errEpochRange
+	}
+	return &object.Time{Value: time.Unix(secs, nanos).UTC()}, nil
+}
+
+func formatTime(args *Args) (object.Object, error) {
+	t := args.Time("time")
+	format := args.String("format")
+	if unit, ok := epochFormats[format]; ok {
+		// UnixNano only covers the years 1678 to 2262, so scale the whole seconds instead
+		ret, ok := mathutils.MulInt64(t.Unix(), int64(time.Second/unit))
+		if ok {
+			ret, ok = mathutils.AddInt64(ret, int64(t.Nanosecond())/int64(unit))
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s is out of range for %s", t.Format(time.RFC3339Nano), format)
+		}
+		return newInteger(ret), nil
+	}
+	layout, err := toGoLayout(format)
+	if err != nil {
+		return nil, err
+	}
+	return newString(t.Format(layout)), nil
+}
+
+func truncateTime(args *Args) (object.Object, error) {
+	t := args.Time("time")
+	if d, ok := args.Get("unit").(*object.Duration); ok {
+		return &object.Time{Value: t.Truncate(d.Value)}, nil
+	}
+
+	unit := args.String("unit")
+	var ret time.Time
+	switch unit {
+	case "second":
+		ret = t.Truncate(time.Second)
+	case "minute":
+		ret = t.Truncate(time.Minute)
+	case "hour":
+		// not t.Truncate(time.Hour), which works from the zero time and breaks for zones with non-hour offsets
+		ret = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "day":
+		ret = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case "week": // weeks start on monday
+		offset := (int(t.Weekday()) + 6) % 7
+		ret = time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	case "month":
+		ret = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case "year":
+		ret = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		d, err := time.ParseDuration(unit)
+		if err != nil {
+			return nil, fmt.Errorf("unknown unit %q", unit)
+		}
+		ret = t.Truncate(d)
+	}
+	return &object.Time{Value: ret}, nil
+}
+
+var strftimeDirectives = map[rune]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'd': "02",
+	'e': "_2",
+	'j': "002",
+	'a': "Mon",
+	'A': "Monday",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'f': "000000", // expects a preceding '.' or ',' like %S.%f
+	'L': "000",
+	'p': "PM",
+	'z': "-0700",
+	'Z': "MST",
+	'F': "2006-01-02",
+	'T': "15:04:05",
+	'D': "01/02/06",
+	'%': "%",
+}
+
+// converts strftime formats to go layouts. anything without a % is assumed to already be a go layout.
+func toGoLayout(format string) (string, error) {
+	if !strings.Contains(format, "%") {
+		return format, nil
+	}
+	var sb strings.Builder
+	runes := []rune(format)
+	for idx := 0; idx < len(runes); idx++ {
+		if runes[idx] != '%' {
+			sb.WriteRune(runes[idx])
+			continue
+		}
+		idx++
+		if idx >= len(runes) {
+			return "", fmt.Errorf("format %q ends with an incomplete directive", format)
+		}
+		layout, ok := strftimeDirectives[runes[idx]]
+		if !ok {
+			return "", fmt.Errorf("unsupported format directive %%%c", runes[idx])
+		}
+		sb.WriteString(layout)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,126 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestTimeFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`parse_time("2024-03-05T10:22:33.5Z") | format_time`, "2024-03-05T10:22:33.5Z"},
+		{`parse_time("2024-03-05 10:22:33") | format_time`, "2024-03-05T10:22:33Z"},
+		{`parse_time("Tue, 05 Mar 2024 10:22:33 +0100") | format_time`, "2024-03-05T10:22:33+01:00"},
+		{`parse_time("05/03/2024 10:22", format: "%d/%m/%Y %H:%M") | format_time`, "2024-03-05T10:22:00Z"},
+		{`parse_time("05/03/2024 10:22", format: "02/01/2006 15:04", timezone: "America/New_York") | format_time`, "2024-03-05T10:22:00-05:00"},
+		{`parse_time("20240305 10:22:33.250", format: "%Y%m%d %H:%M:%S.%L") | format_time`, "2024-03-05T10:22:33.25Z"},
+		{`parse_time(1709634153, format: "epoch_s") | format_time`, "2024-03-05T10:22:33Z"},
+		{`parse_time("1709634153250", format: "epoch_ms") | format_time`, "2024-03-05T10:22:33.25Z"},
+		{`parse_time(1709634153.5, format: "epoch_s") | format_time`, "2024-03-05T10:22:33.5Z"},
+		{`parse_time(-1500, format: "epoch_ms") | format_time`, "1969-12-31T23:59:58.5Z"},
+		{`parse_time(-1.5, format: "epoch_s") | format_time`, "1969-12-31T23:59:58.5Z"},
+		{`parse_time(9223372036854775807, format: "epoch_ns") | format_time`, "2262-04-11T23:47:16.854775807Z"},
+		{`parse_time(253402300799, format: "epoch_s") | format_time`, "9999-12-31T23:59:59Z"},
+		{`format_time(parse_time(32503680000, format: "epoch_s"), format: "epoch_s") | to_string`, "32503680000"},
+		{`format_time(parse_time(-62135596800, format: "epoch_s"), format: "epoch_ms") | to_string`, "-62135596800000"},
+		{`format_time(parse_time(-1500, format: "epoch_ms"), format: "epoch_ms") | to_string`, "-1500"},
+		{`parse_time("2024-03-05T10:22:33Z") | format_time("%Y/%m/%d %I:%M %p")`, "2024/03/05 10:22 AM"},
+		{`parse_time("2024-03-05T10:22:33Z") | to_timezone("Asia/Tokyo") | format_time`, "2024-03-05T19:22:33+09:00"},
+		{`parse_time("2024-03-05T10:22:33Z") | truncate("day") | format_time`, "2024-03-05T00:00:00Z"},
+		{`parse_time("2024-03-07T10:22:33Z") | truncate("week") | format_time`, "2024-03-04T00:00:00Z"},
+		{`parse_time("2024-03-05T10:22:33Z") | truncate("month") | format_time`, "2024-03-01T00:00:00Z"},
+		{`parse_time("2024-03-05T10:22:33Z") | truncate("15m") | format_time`, "2024-03-05T10:15:00Z"},
+		{`parse_time("2024-03-05T10:22:33+05:30") | truncate("hour") | format_time`, "2024-03-05T10:00:00+05:30"},
+		{`t = parse_time("2024-03-05T10:22:33Z")
+		t + duration("1h30m") | format_time`, "2024-03-05T11:52:33Z"},
+		{`t = parse_time("2024-03-05T10:22:33Z")
+		t - duration("24h") | format_time`, "2024-03-04T10:22:33Z"},
+		{`a = parse_time("2024-03-05T10:00:00Z")
+		b = parse_time("2024-03-05T12:30:00Z")
+		format("%s", b - a)`, "2h30m0s"},
+		{`format("%s", duration("1m") * 3 + duration("30s"))`, "3m30s"},
+	}
+	for _, tt := range tests {
+		testStringResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestTimeComparisons(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`parse_time("2024-03-05T10:00:00Z") < parse_time("2024-03-05T11:00:00Z")`, true},
+		{`parse_time("2024-03-05T10:00:00Z") == parse_time("2024-03-05T11:00:00+01:00")`, true},
+		{`now() - parse_time("2024-03-05T10:00:00Z") > duration("1h")`, true},
+		{`duration("90s") == duration("1m30s")`, true},
+	}
+	for _, tt := range tests {
+		got, ok := evalInput(t, tt.input).(*object.Boolean)
+		if !ok {
+			t.Fatalf("%s: result is not *object.Boolean. got=%T", tt.input, got)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, got.Value); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestFormatTimeEpoch(t *testing.T) {
+	input := `parse_time("2024-03-05T10:22:33.25Z") | format_time("epoch_ms")`
+	testIntegerResult(t, input, evalInput(t, input), 1709634153250)
+}
+
+func TestTimeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`parse_time("yesterday")`, `parse_time: "yesterday" is not in a recognized time format`},
+		{`parse_time("2024", format: "%Q")`, "parse_time: unsupported format directive %Q"},
+		{`parse_time("2024-01-01", timezone: "Mars/Olympus")`, `parse_time: unknown timezone "Mars/Olympus"`},
+		{`parse_time(5)`, `parse_time: numeric values need an epoch format. got format=""`},
+		{`parse_time(9223372036854775807, format: "epoch_s")`, "parse_time: epoch timestamp is out of range: it must be between the years 1 and 9999"},
+		{`parse_time(-9223372036854775807, format: "epoch_ms")`, "parse_time: epoch timestamp is out of range: it must be between the years 1 and 9999"},
+		{`parse_time(32503680000, format: "epoch_s") | format_time("epoch_ns")`, "format_time: 3000-01-01T00:00:00Z is out of range for epoch_ns"},
+		{`parse_time("1e300", format: "epoch_us")`, "parse_time: epoch timestamp is out of range: it must be between the years 1 and 9999"},
+		{`now() | truncate("fortnight")`, `truncate: unknown unit "fortnight"`},
+		{`duration("soon")`, `duration: "soon" is not a valid duration`},
+		{`now() + 5`, "type mismatch: TIME + INTEGER"},
+		{`d = duration("2562047h")
+d + d`, "duration overflow: 2562047h0m0s + 2562047h0m0s"},
+		{`duration("-2562047h") - duration("2562047h")`, "duration overflow: -2562047h0m0s - 2562047h0m0s"},
+		{`duration("2562047h") * 2`, "duration overflow: 2562047h0m0s * 2"},
+		{`duration("1h") / 0.0000000000001`, "duration overflow: 1h0m0s / 0.0000000000001"},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestPrepareReportsUnknownTimezone(t *testing.T) {
+	input := "x = parse_time($src.ts)\nx | to_timezone(\"Mars/Olympus\")"
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = evaluator.New(l.InputRunes()).Prepare(program)
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("err is not *object.Error. got=%T", err)
+	}
+	if isEq, failMsg := testutils.Equal(`to_timezone: invalid timezone: unknown timezone "Mars/Olympus"`, errObj.Message); !isEq {
+		t.Errorf("wrong error message: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal(2, errObj.Line); !isEq {
+		t.Errorf("wrong error line: %s", failMsg)
+	}
+}
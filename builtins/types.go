@@ -0,0 +1,233 @@
+package builtins
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// returned by conversions when a value has no form in the target type.
+// lenient conversions return null instead, so a bad value can be dropped or defaulted in the script.
+var errNotConvertible = fmt.Errorf("not convertible")
+
+func registerTypes(r *Registry) {
+	value := Param{Name: "value"}
+	// strict only converts values that represent the target type exactly, and fails on anything else.
+	// lenient also trims strings, truncates floats and reads words like "yes", and returns null when nothing fits.
+	strict := Param{Name: "strict", Types: Boolean, Default: newBoolean(true)}
+
+	isType := func(name string, types ...object.ObjectType) *Function {
+		return &Function{
+			Name:    name,
+			Params:  []Param{value},
+			Returns: Boolean,
+			Fn: func(args *Args) (object.Object, error) {
+				for _, t := range types {
+					if args.Get("value").Type() == t {
+						return newBoolean(true), nil
+					}
+				}
+				return newBoolean(false), nil
+			},
+		}
+	}
+	convert := func(name string, target object.ObjectType, fn func(obj object.Object, strict bool) (object.Object, error)) *Function {
+		return &Function{
+			Name:    name,
+			Params:  []Param{value, strict},
+			Returns: []object.ObjectType{target, object.NULL_OBJ}, // null from lenient conversions
+			Fn: func(args *Args) (object.Object, error) {
+				obj, isStrict := args.Get("value"), args.Bool("strict")
+				if obj.Type() == object.NULL_OBJ && !isStrict {
+					return obj, nil
+				}
+				ret, err := fn(obj, isStrict)
+				switch {
+				case err == nil:
+					return ret, nil
+				case !isStrict:
+					return &object.Null{}, nil
+				case err == errNotConvertible:
+					return nil, fmt.Errorf("cannot convert %s %s to %s", obj.Type(), quoteValue(obj), target)
+				}
+				return nil, fmt.Errorf("cannot convert %s %s to %s: %w", obj.Type(), quoteValue(obj), target, err)
+			},
+		}
+	}
+
+	r.mustRegister(
+		&Function{
+			Name:    "type_of",
+			Params:  []Param{value},
+			Returns: String,
+			Fn: func(args *Args) (object.Object, error) {
+				return newString(strings.ToLower(string(args.Get("value").Type()))), nil
+			},
+		},
+		isType("is_string", object.STRING_OBJ),
+		isType("is_number", Number...),
+		isType("is_bool", object.BOOLEAN_OBJ),
+		isType("is_array", object.ARRAY_OBJ),
+		isType("is_map", object.MAP_OBJ),
+		isType("is_null", object.NULL_OBJ),
+		convert("to_int", object.INTEGER_OBJ, toInt),
+		convert("to_float", object.FLOAT_OBJ, toFloatObject),
+		convert("to_string", object.STRING_OBJ, toStringObject),
+		convert("to_bool", object.BOOLEAN_OBJ, toBool),
+	)
+}
+
+func quoteValue(obj object.Object) string {
+	if obj.Type() == object.STRING_OBJ {
+		return strconv.Quote(obj.Inspect())
+	}
+	return obj.Inspect()
+}
+
+func toInt(obj object.Object, strict bool) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj, nil
+	case *object.UInteger:
+		if obj.Value > math.MaxInt64 {
+			return nil, errIntegerOverflow
+		}
+		return newInteger(int64(obj.Value)), nil
+	case *object.Float:
+		if strict && obj.Value != math.Trunc(obj.Value) {
+			return nil, fmt.Errorf("value has a fractional part")
+		}
+		return floatToInteger(math.Trunc(obj.Value))
+	case *object.Decimal:
+		if strict && !obj.Value.IsInt() {
+			return nil, fmt.Errorf("value has a fractional part")
+		}
+		whole := new(big.Int).Quo(obj.Value.Num(), obj.Value.Denom())
+		if !whole.IsInt64() {
+			return nil, errIntegerOverflow
+		}
+		return newInteger(whole.Int64()), nil
+	case *object.String:
+		s := obj.Value
+		if !strict {
+			s = strings.TrimSpace(s)
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			return newInteger(i), nil
+		}
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return nil, errIntegerOverflow
+		}
+		if !strict {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return toInt(newFloat(f), false)
+			}
+		}
+	case *object.Boolean:
+		if !strict {
+			if obj.Value {
+				return newInteger(1), nil
+			}
+			return newInteger(0), nil
+		}
+	}
+	return nil, errNotConvertible
+}
+
+func toFloatObject(obj object.Object, strict bool) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Float:
+		return obj, nil
+	case *object.Integer, *object.UInteger, *object.Decimal:
+		return newFloat(toFloat(obj)), nil
+	case *object.String:
+		s := obj.Value
+		if !strict {
+			s = strings.TrimSpace(s)
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return newFloat(f), nil
+		}
+	case *object.Boolean:
+		if !strict {
+			if obj.Value {
+				return newFloat(1), nil
+			}
+			return newFloat(0), nil
+		}
+	}
+	return nil, errNotConvertible
+}
+
+// arrays and maps have no string form in strict mode, and are written as JSON in lenient mode
+func toStringObject(obj object.Object, strict bool) (object.Object, error) {
+	switch obj.Type() {
+	case object.STRING_OBJ:
+		return obj, nil
+	case object.INTEGER_OBJ, object.UINTEGER_OBJ, object.FLOAT_OBJ, object.DECIMAL_OBJ, object.BOOLEAN_OBJ, object.TIME_OBJ, object.DURATION_OBJ, object.IP_OBJ:
+		return newString(obj.Inspect()), nil
+	case object.ARRAY_OBJ, object.MAP_OBJ:
+		if strict {
+			break
+		}
+		encoded, err := object.EncodeJSON(obj, "")
+		if err != nil {
+			return nil, err
+		}
+		return newString(string(encoded)), nil
+	}
+	return nil, errNotConvertible
+}
+
+var (
+	lenientTrue  = []string{"true", "t", "yes", "y", "on", "1"}
+	lenientFalse = []string{"false", "f", "no", "n", "off", "0", ""}
+)
+
+func toBool(obj object.Object, strict bool) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj, nil
+	case *object.String:
+		if strict {
+			switch obj.Value {
+			case "true":
+				return newBoolean(true), nil
+			case "false":
+				return newBoolean(false), nil
+			}
+			break
+		}
+		s := strings.ToLower(strings.TrimSpace(obj.Value))
+		switch {
+		case slices.Contains(lenientTrue, s):
+			return newBoolean(true), nil
+		case slices.Contains(lenientFalse, s):
+			return newBoolean(false), nil
+		}
+	case *object.Integer:
+		if !strict || obj.Value == 0 || obj.Value == 1 {
+			return newBoolean(obj.Value != 0), nil
+		}
+	case *object.UInteger:
+		if !strict || obj.Value == 0 || obj.Value == 1 {
+			return newBoolean(obj.Value != 0), nil
+		}
+	case *object.Float:
+		if !strict || obj.Value == 0 || obj.Value == 1 {
+			return newBoolean(obj.Value != 0), nil
+		}
+	case *object.Decimal:
+		if !strict || obj.Value.Sign() == 0 || obj.Value.Cmp(big.NewRat(1, 1)) == 0 {
+			return newBoolean(obj.Value.Sign() != 0), nil
+		}
+	}
+	return nil, errNotConvertible
+}
@@ -0,0 +1,116 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestTypeInspection(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{`type_of("a")`, "string"},
+		{`type_of(1)`, "integer"},
+		{`type_of(1.5)`, "float"},
+		{`type_of(null)`, "null"},
+		{`type_of([1])`, "array"},
+		{`type_of(parse_json("{}"))`, "map"},
+		{`type_of(parse_ip("::1"))`, "ip"},
+		{`type_of(x ~> x)`, "function"},
+		{`is_string("a")`, "true"},
+		{`is_string(1)`, "false"},
+		{`is_number(1)`, "true"},
+		{`is_number(1.5)`, "true"},
+		{`is_number("1")`, "false"},
+		{`is_bool(false)`, "true"},
+		{`is_array([])`, "true"},
+		{`is_map(parse_json("{}"))`, "true"},
+		{`is_null(null)`, "true"},
+		{`is_null(0)`, "false"},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantType object.ObjectType
+		want     string // inspected result
+	}{
+		{`to_int("42")`, object.INTEGER_OBJ, "42"},
+		{`to_int(-7.0)`, object.INTEGER_OBJ, "-7"},
+		{`to_int(" 42 ", strict: false)`, object.INTEGER_OBJ, "42"},
+		{`to_int("4.9", strict: false)`, object.INTEGER_OBJ, "4"},
+		{`to_int(true, strict: false)`, object.INTEGER_OBJ, "1"},
+		{`to_int("abc", strict: false)`, object.NULL_OBJ, "null"},
+		{`to_int(null, strict: false)`, object.NULL_OBJ, "null"},
+		{`to_float("1.5")`, object.FLOAT_OBJ, "1.5"},
+		{`to_float(2)`, object.FLOAT_OBJ, "2"},
+		{`to_float("1e3 ", strict: false)`, object.FLOAT_OBJ, "1000"},
+		{`to_string(12)`, object.STRING_OBJ, "12"},
+		{`to_string(parse_ip("10.0.0.1"))`, object.STRING_OBJ, "10.0.0.1"},
+		{`to_string([1, "a"], strict: false)`, object.STRING_OBJ, `[1,"a"]`},
+		{`to_bool("true")`, object.BOOLEAN_OBJ, "true"},
+		{`to_bool(0)`, object.BOOLEAN_OBJ, "false"},
+		{`to_bool(" Yes ", strict: false)`, object.BOOLEAN_OBJ, "true"},
+		{`to_bool("off", strict: false)`, object.BOOLEAN_OBJ, "false"},
+		{`to_bool(5, strict: false)`, object.BOOLEAN_OBJ, "true"},
+		{`to_bool(parse_json("18446744073709551615"), strict: false)`, object.BOOLEAN_OBJ, "true"},
+		{`to_bool(parse_json("0.10000000000000000001"), strict: false)`, object.BOOLEAN_OBJ, "true"},
+		{`to_bool("maybe", strict: false)`, object.NULL_OBJ, "null"},
+		{`if to_bool("y", strict: false) { "on" } else { "off" }`, object.STRING_OBJ, "on"},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.wantType, got.Type()); !isEq {
+			t.Errorf("%s: wrong result type: %s", tt.input, failMsg)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`to_int("4.5")`, `to_int: cannot convert STRING "4.5" to INTEGER`},
+		{`to_int(4.5)`, "to_int: cannot convert FLOAT 4.5 to INTEGER: value has a fractional part"},
+		{`to_int("99999999999999999999")`, `to_int: cannot convert STRING "99999999999999999999" to INTEGER: integer overflow`},
+		{`to_int(true)`, "to_int: cannot convert BOOLEAN true to INTEGER"},
+		{`to_float("abc")`, `to_float: cannot convert STRING "abc" to FLOAT`},
+		{`to_string(null)`, "to_string: cannot convert NULL null to STRING"},
+		{`to_string([1])`, "to_string: cannot convert ARRAY [1] to STRING"},
+		{`to_bool("yes")`, `to_bool: cannot convert STRING "yes" to BOOLEAN`},
+		{`to_bool(2)`, "to_bool: cannot convert INTEGER 2 to BOOLEAN"},
+		{`to_bool(parse_json("18446744073709551615"))`, "to_bool: cannot convert UINTEGER 18446744073709551615 to BOOLEAN"},
+		{`to_bool(parse_json("0.10000000000000000001"))`, "to_bool: cannot convert DECIMAL 0.10000000000000000001 to BOOLEAN"},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestConversionErrorPosition(t *testing.T) {
+	got := evalInput(t, "a = 1\nb = \"x\" | to_int")
+	errObj, ok := got.(*object.Error)
+	if !ok {
+		t.Fatalf("result is not *object.Error. got=%T (%s)", got, got.Inspect())
+	}
+	if isEq, failMsg := testutils.Equal(2, errObj.Line); !isEq {
+		t.Errorf("wrong error line: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal(11, errObj.Column); !isEq {
+		t.Errorf("wrong error column: %s", failMsg)
+	}
+}
@@ -0,0 +1,171 @@
+package builtins
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// the fields returned by parse_url, which are also what build_url accepts
+var urlParts = []string{"scheme", "user", "host", "port", "path", "query", "fragment"}
+
+func registerURL(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name:    "parse_url",
+			Params:  []Param{{Name: "value", Types: String}},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				u, err := url.Parse(args.String("value"))
+				if err != nil {
+					return nil, fmt.Errorf("%q is not a valid URL", args.String("value"))
+				}
+				return urlToMap(u)
+			},
+		},
+		&Function{
+			Name:    "parse_query",
+			Params:  []Param{{Name: "value", Types: String}}, // a leading ? is ignored
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				values, err := url.ParseQuery(strings.TrimPrefix(args.String("value"), "?"))
+				if err != nil {
+					return nil, fmt.Errorf("%q is not a valid query string", args.String("value"))
+				}
+				return queryToMap(values), nil
+			},
+		},
+		&Function{
+			Name:    "build_url",
+			Params:  []Param{{Name: "parts", Types: Map}}, // takes the same fields parse_url returns
+			Returns: String,
+			Fn:      buildURL,
+		},
+	)
+}
+
+func urlToMap(u *url.URL) (object.Object, error) {
+	ret := object.NewMap()
+	for _, part := range urlParts {
+		ret.Pairs[part] = &object.Null{}
+	}
+	ret.Pairs["scheme"] = newString(u.Scheme)
+	ret.Pairs["host"] = newString(u.Hostname())
+	ret.Pairs["path"] = newString(u.Path)
+	ret.Pairs["fragment"] = newString(u.Fragment)
+	if u.User != nil {
+		ret.Pairs["user"] = newString(u.User.Username())
+	}
+	if u.Port() != "" {
+		port, err := strconv.ParseInt(u.Port(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", u.Port())
+		}
+		ret.Pairs["port"] = newInteger(port)
+	}
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid query string", u.RawQuery)
+	}
+	ret.Pairs["query"] = queryToMap(values)
+	return ret, nil
+}
+
+// keys that appear once map to a string, and repeated keys map to an array of every value in order
+func queryToMap(values url.Values) *object.Map {
+	ret := object.NewMap()
+	for k, vals := range values {
+		if len(vals) == 1 {
+			ret.Pairs[k] = newString(vals[0])
+			continue
+		}
+		ret.Pairs[k] = newStringArray(vals)
+	}
+	return ret
+}
+
+func buildURL(args *Args) (object.Object, error) {
+	parts := args.Map("parts")
+	u := &url.URL{}
+	port := ""
+	for _, k := range parts.Keys() {
+		val := parts.Pairs[k]
+		if val.Type() == object.NULL_OBJ {
+			continue
+		}
+		switch k {
+		case "scheme", "user", "host", "path", "fragment":
+			s, ok := val.(*object.String)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a STRING. got=%s", k, val.Type())
+			}
+			switch k {
+			case "scheme":
+				u.Scheme = s.Value
+			case "user":
+				u.User = url.User(s.Value)
+			case "host":
+				u.Host = s.Value
+			case "path":
+				u.Path = s.Value
+			case "fragment":
+				u.Fragment = s.Value
+			}
+		case "port":
+			if val.Type() != object.INTEGER_OBJ && val.Type() != object.STRING_OBJ {
+				return nil, fmt.Errorf("port must be an INTEGER or STRING. got=%s", val.Type())
+			}
+			port = val.Inspect()
+		case "query":
+			query, ok := val.(*object.Map)
+			if !ok {
+				return nil, fmt.Errorf("query must be a MAP. got=%s", val.Type())
+			}
+			rawQuery, err := encodeQuery(query)
+			if err != nil {
+				return nil, err
+			}
+			u.RawQuery = rawQuery
+		default:
+			return nil, fmt.Errorf("unknown url part %q. expected one of %s", k, strings.Join(urlParts, ", "))
+		}
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(u.Host, port)
+	}
+	return newString(u.String()), nil
+}
+
+// the inverse of queryToMap. keys are encoded in sorted order.
+func encodeQuery(query *object.Map) (string, error) {
+	values := url.Values{}
+	for k, val := range query.Pairs {
+		switch val := val.(type) {
+		case *object.Array:
+			for _, el := range val.Elements {
+				if err := checkQueryValue(k, el); err != nil {
+					return "", err
+				}
+				values.Add(k, el.Inspect())
+			}
+		default:
+			if err := checkQueryValue(k, val); err != nil {
+				return "", err
+			}
+			values.Add(k, val.Inspect())
+		}
+	}
+	return values.Encode(), nil
+}
+
+func checkQueryValue(key string, val object.Object) error {
+	switch val.Type() {
+	case object.STRING_OBJ, object.INTEGER_OBJ, object.UINTEGER_OBJ, object.FLOAT_OBJ, object.DECIMAL_OBJ, object.BOOLEAN_OBJ:
+		return nil
+	}
+	return fmt.Errorf("query value for %q must be a STRING, number or BOOLEAN. got=%s", key, val.Type())
+}
@@ -0,0 +1,99 @@
+package builtins_test
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestURLFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{
+			`parse_url("https://kim@example.com:8443/a/b%20c?q=1&tag=x&tag=y#top")`,
+			`{"fragment": top, "host": example.com, "path": /a/b c, "port": 8443, "query": {"q": 1, "tag": [x, y]}, "scheme": https, "user": kim}`,
+		},
+		{
+			`parse_url("/search?q=go")`,
+			`{"fragment": , "host": , "path": /search, "port": null, "query": {"q": go}, "scheme": , "user": null}`,
+		},
+		{`parse_url("http://[2001:db8::1]:80/").host`, `2001:db8::1`},
+		{`parse_query("?a=1&b=two+words&a=3")`, `{"a": [1, 3], "b": two words}`},
+		{`parse_query("")`, `{}`},
+		{
+			`build_url(parse_url("https://kim@example.com:8443/a/b%20c?q=1&tag=x&tag=y#top"))`,
+			`https://kim@example.com:8443/a/b%20c?q=1&tag=x&tag=y#top`,
+		},
+		{`parse_url("https://example.com/x?id=7") | build_url`, `https://example.com/x?id=7`},
+	}
+	for _, tt := range tests {
+		got := evalInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestURLErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`parse_url("http://a b.com/%zz")`, `parse_url: "http://a b.com/%zz" is not a valid URL`},
+		{`parse_query("a=%zz")`, `parse_query: "a=%zz" is not a valid query string`},
+		{`build_url(parse_query("hostname=x"))`, `build_url: unknown url part "hostname". expected one of scheme, user, host, port, path, query, fragment`},
+		{`build_url(parse_query("host=x&query=y"))`, `build_url: query must be a MAP. got=STRING`},
+	}
+	for _, tt := range tests {
+		testErrorResult(t, tt.input, evalInput(t, tt.input), tt.want)
+	}
+}
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			`{"browser": Chrome, "browser_version": 120.0.6099.109, "device": desktop, "os": Windows, "os_version": 10}`,
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			`{"browser": Edge, "browser_version": 120.0.2210.91, "device": desktop, "os": Windows, "os_version": 10}`,
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			`{"browser": Safari, "browser_version": 17.2, "device": mobile, "os": iOS, "os_version": 17.2.1}`,
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0",
+			`{"browser": Firefox, "browser_version": 121.0, "device": desktop, "os": macOS, "os_version": 10.15}`,
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Safari/537.36",
+			`{"browser": Samsung Internet, "browser_version": 23.0, "device": tablet, "os": Android, "os_version": 13}`,
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			`{"browser": Googlebot, "browser_version": 2.1, "device": bot, "os": null, "os_version": null}`,
+		},
+		{
+			"curl/8.4.0",
+			`{"browser": curl, "browser_version": 8.4.0, "device": bot, "os": null, "os_version": null}`,
+		},
+		{
+			"something else entirely",
+			`{"browser": null, "browser_version": null, "device": null, "os": null, "os_version": null}`,
+		},
+	}
+	for _, tt := range tests {
+		input := `parse_user_agent("` + tt.value + `")`
+		got := evalInput(t, input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.value, failMsg)
+		}
+	}
+}
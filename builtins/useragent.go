@@ -0,0 +1,126 @@
+package builtins
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// a rule matches when its pattern does, and reads the name and version from the named groups of the same name.
+// the rule's own name is used when the pattern has no name group.
+type uaRule struct {
+	pattern *regexp.Regexp
+	name    string
+	// maps the raw version to a display version, like windows NT 6.1 to 7
+	versions map[string]string
+}
+
+func (rule uaRule) apply(value string) (name string, version string, ok bool) {
+	match := rule.pattern.FindStringSubmatch(value)
+	if match == nil {
+		return "", "", false
+	}
+	name = rule.name
+	if idx := rule.pattern.SubexpIndex("name"); idx > 0 && match[idx] != "" {
+		name = match[idx]
+	}
+	if idx := rule.pattern.SubexpIndex("version"); idx > 0 {
+		version = strings.ReplaceAll(match[idx], "_", ".")
+	}
+	if mapped, found := rule.versions[version]; found {
+		version = mapped
+	}
+	return name, version, true
+}
+
+// rules are tried in order and the first match wins, so more specific products come before the ones they imitate.
+// ex: edge and opera also claim to be chrome, and chrome also claims to be safari.
+var (
+	uaBotRules = []uaRule{
+		{pattern: regexp.MustCompile(`(?P<name>Googlebot|bingbot|YandexBot|DuckDuckBot|Baiduspider|Applebot|AhrefsBot|SemrushBot|Twitterbot|facebookexternalhit)(?:/(?P<version>\d+(?:\.\d+)*))?`)},
+		{pattern: regexp.MustCompile(`Yahoo! Slurp`), name: "Yahoo! Slurp"},
+		{pattern: regexp.MustCompile(`(?P<name>curl|Wget|python-requests|Go-http-client|okhttp|PostmanRuntime)/(?P<version>\d+(?:\.\d+)*)`)},
+		{pattern: regexp.MustCompile(`(?i)bot\b|crawler|spider`), name: "Other Bot"},
+	}
+
+	uaBrowserRules = []uaRule{
+		{pattern: regexp.MustCompile(`Edg(?:e|A|iOS)?/(?P<version>\d+(?:\.\d+)*)`), name: "Edge"},
+		{pattern: regexp.MustCompile(`OPR/(?P<version>\d+(?:\.\d+)*)`), name: "Opera"},
+		{pattern: regexp.MustCompile(`SamsungBrowser/(?P<version>\d+(?:\.\d+)*)`), name: "Samsung Internet"},
+		{pattern: regexp.MustCompile(`FxiOS/(?P<version>\d+(?:\.\d+)*)`), name: "Firefox"},
+		{pattern: regexp.MustCompile(`CriOS/(?P<version>\d+(?:\.\d+)*)`), name: "Chrome"},
+		{pattern: regexp.MustCompile(`Firefox/(?P<version>\d+(?:\.\d+)*)`), name: "Firefox"},
+		{pattern: regexp.MustCompile(`(?P<name>Chromium|Chrome)/(?P<version>\d+(?:\.\d+)*)`)},
+		{pattern: regexp.MustCompile(`Version/(?P<version>\d+(?:\.\d+)*).*Safari/`), name: "Safari"},
+		{pattern: regexp.MustCompile(`MSIE (?P<version>\d+(?:\.\d+)*)`), name: "Internet Explorer"},
+		{pattern: regexp.MustCompile(`Trident/.*rv:(?P<version>\d+(?:\.\d+)*)`), name: "Internet Explorer"},
+	}
+
+	uaOSRules = []uaRule{
+		{pattern: regexp.MustCompile(`Windows NT (?P<version>\d+\.\d+)`), name: "Windows", versions: map[string]string{
+			"10.0": "10", "6.3": "8.1", "6.2": "8", "6.1": "7", "6.0": "Vista", "5.2": "XP", "5.1": "XP",
+		}},
+		{pattern: regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS (?P<version>\d+(?:_\d+)*)`), name: "iOS"},
+		{pattern: regexp.MustCompile(`Mac OS X (?P<version>\d+(?:[_.]\d+)*)`), name: "macOS"},
+		{pattern: regexp.MustCompile(`Android (?P<version>\d+(?:\.\d+)*)`), name: "Android"},
+		{pattern: regexp.MustCompile(`CrOS \S+ (?P<version>\d+(?:\.\d+)*)`), name: "Chrome OS"},
+		{pattern: regexp.MustCompile(`Linux`), name: "Linux"},
+	}
+)
+
+func registerUserAgent(r *Registry) {
+	r.mustRegister(
+		&Function{
+			Name:    "parse_user_agent",
+			Params:  []Param{{Name: "value", Types: String}},
+			Returns: Map,
+			Fn: func(args *Args) (object.Object, error) {
+				return parseUserAgent(args.String("value")), nil
+			},
+		},
+	)
+}
+
+// returns browser, browser_version, os, os_version and device fields.
+// device is one of bot, mobile, tablet, desktop or null, and any other field that can't be determined is null.
+func parseUserAgent(value string) *object.Map {
+	ret := object.NewMap()
+	for _, field := range []string{"browser", "browser_version", "os", "os_version", "device"} {
+		ret.Pairs[field] = &object.Null{}
+	}
+	setMatch := func(rules []uaRule, nameField string, versionField string) bool {
+		for _, rule := range rules {
+			name, version, ok := rule.apply(value)
+			if !ok {
+				continue
+			}
+			ret.Pairs[nameField] = newString(name)
+			if version != "" {
+				ret.Pairs[versionField] = newString(version)
+			}
+			return true
+		}
+		return false
+	}
+
+	isBot := setMatch(uaBotRules, "browser", "browser_version")
+	if !isBot {
+		setMatch(uaBrowserRules, "browser", "browser_version")
+	}
+	setMatch(uaOSRules, "os", "os_version")
+
+	switch {
+	case isBot:
+		ret.Pairs["device"] = newString("bot")
+	case strings.Contains(value, "iPad") || strings.Contains(value, "Tablet"),
+		// android tablets leave Mobile out of the user agent
+		strings.Contains(value, "Android") && !strings.Contains(value, "Mobile"):
+		ret.Pairs["device"] = newString("tablet")
+	case strings.Contains(value, "Mobile") || strings.Contains(value, "iPhone") || strings.Contains(value, "iPod"):
+		ret.Pairs["device"] = newString("mobile")
+	case ret.Pairs["os"].Type() != object.NULL_OBJ:
+		ret.Pairs["device"] = newString("desktop")
+	}
+	return ret
+}
@@ -0,0 +1,231 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/object"
+)
+
+// follows the evaluator's scoping: a declaration or annotation creates a new variable in the current scope, and a plain assignment updates the closest variable with the name.
+// assigning to a constant is reported by the resolver.
+func (c *Checker) checkAssignStatement(node *ast.AssignStatement, s *scope) Type {
+	t := c.check(node.Value, s)
+	if node.Keyword != "" || node.Type != nil {
+		var spec *object.TypeSpec
+		if node.Type != nil {
+			if spec = c.resolveType(node.Type); spec != nil {
+				t = c.checkDeclaredType(node, spec, t)
+			}
+		}
+		s.declare(node.Name.Value, t, spec)
+		return t
+	}
+	if spec, ok := s.declaredType(node.Name.Value); ok {
+		t = c.checkDeclaredType(node, spec, t)
+	}
+	s.assign(node.Name.Value, t)
+	return t
+}
+
+// an unknown value is assumed to have the declared type, since the evaluator rejects any other
+func (c *Checker) checkDeclaredType(node *ast.AssignStatement, spec *object.TypeSpec, t Type) Type {
+	if !compatible(t, spec.Kinds) {
+		c.newError(node.Value.Position(), "%s is declared as %s. got=%s", node.Name.Value, spec.String(), t)
+	} else if path, elem, ok := c.elementMismatch(node.Value, spec); ok {
+		c.newError(node.Value.Position(), "%s is declared as %s. got=%s at %s", node.Name.Value, spec.String(), elem, path)
+	}
+	if !t.Known() {
+		return Type(spec.Kinds)
+	}
+	return t
+}
+
+// finds the first element of an array literal that can't have the element type of spec, like the 1 in ['a', 1] for array<string>.
+// returns the path to the element, like [1], in the same form the evaluator reports it.
+func (c *Checker) elementMismatch(expr ast.Expression, spec *object.TypeSpec) (string, Type, bool) {
+	arr, ok := expr.(*ast.ArrayLiteral)
+	if !ok || spec.Elem == nil {
+		return "", nil, false
+	}
+	for idx, t := range c.elements[arr] {
+		path := fmt.Sprintf("[%d]", idx)
+		if !compatible(t, spec.Elem.Kinds) {
+			return path, t, true
+		}
+		if inner, t, ok := c.elementMismatch(arr.Elements[idx], spec.Elem); ok {
+			return path + inner, t, true
+		}
+	}
+	return "", nil, false
+}
+
+// fields the schema for $dest doesn't declare can hold anything, the same as when it's validated
+func (c *Checker) checkFieldAssignStatement(node *ast.FieldAssignStatement, s *scope) Type {
+	t := c.check(node.Value, s)
+	if c.dest == nil || node.Object.Value != "$dest" {
+		return t
+	}
+	fields := c.dest.Fields
+	path := node.Object.Value
+	for idx, name := range node.Path {
+		path += "." + name.Value
+		f := fields.Get(name.Value)
+		if f == nil {
+			return t
+		}
+		if idx == len(node.Path)-1 {
+			accepted := f.Type.Kinds
+			if f.Optional && len(accepted) > 0 {
+				accepted = append(slices.Clone(accepted), object.NULL_OBJ)
+			}
+			if !compatible(t, accepted) {
+				c.newError(node.Value.Position(), "%s is declared as %s. got=%s", path, f.Type.String(), t)
+			} else if at, elem, ok := c.elementMismatch(node.Value, f.Type); ok {
+				c.newError(node.Value.Position(), "%s is declared as %s. got=%s at %s", path, f.Type.String(), elem, at)
+			}
+			return t
+		}
+		if fields = f.Fields; fields == nil {
+			return t
+		}
+	}
+	return t
+}
+
+// returns nil after reporting an unknown type
+func (c *Checker) resolveType(node *ast.TypeAnnotation) *object.TypeSpec {
+	var elem *object.TypeSpec
+	if node.Elem != nil {
+		if elem = c.resolveType(node.Elem); elem == nil {
+			return nil
+		}
+	}
+	spec, err := object.NewTypeSpec(node.Name, elem)
+	if err != nil {
+		c.newError(node.Position(), "%s", err.Error())
+		return nil
+	}
+	return spec
+}
+
+// params are typed by their annotations inside the body, and unannotated params are unknown
+func (c *Checker) checkPipeDefinition(node *ast.PipeDefinition, s *scope) {
+	pipe := &object.Pipe{Name: node.Name.Value}
+	inner := newScope(s)
+	pipe.Params = c.declareParams(node.Params, s, inner)
+	// registered before the body is checked, so the body can invoke the pipe itself
+	c.pipes[pipe.Name] = pipe
+	s.declare(pipe.Name, of(object.PIPE_OBJ), nil)
+	c.checkBody(node.Body, inner)
+}
+
+func (c *Checker) checkFunctionDefinition(node *ast.FunctionDefinition, s *scope) {
+	if _, ok := c.builtins.Lookup(node.Name.Value); ok {
+		c.newError(node.Name.Position(), "cannot define fn %s: a builtin function has that name", node.Name.Value)
+		return
+	}
+	fn := &object.Fn{Name: node.Name.Value}
+	inner := newScope(s)
+	fn.Params = c.declareParams(node.Params, s, inner)
+	// registered before the body is checked, so the body can call the fn itself
+	c.fns[fn.Name] = fn
+	s.declare(fn.Name, of(object.FN_OBJ), nil)
+	c.checkBody(node.Body, inner)
+}
+
+// bodies run when the pipe or fn is called, so they can call every top-level pipe and fn, see hoistSignatures
+func (c *Checker) checkBody(body *ast.BlockStatement, inner *scope) {
+	c.bodies++
+	c.check(body, inner)
+	c.bodies--
+}
+
+// returns the params of a pipe or fn, declaring each one in the scope of its body.
+// default values are checked in the scope the pipe or fn is defined in.
+func (c *Checker) declareParams(nodes []*ast.Parameter, s *scope, inner *scope) []object.Param {
+	params := []object.Param{}
+	for _, p := range nodes {
+		param := object.Param{Name: p.Name.Value, Variadic: p.Variadic}
+		if p.Type != nil {
+			param.Type = c.resolveType(p.Type)
+		}
+		if p.Default != nil {
+			t := c.check(p.Default, s)
+			switch {
+			case param.Type == nil:
+			case !compatible(t, param.Type.Kinds):
+				c.newError(p.Default.Position(), "default value for %q must be %s. got=%s", param.Name, param.Type.String(), t)
+			default:
+				if path, elem, ok := c.elementMismatch(p.Default, param.Type); ok {
+					c.newError(p.Default.Position(), "default value for %q must be %s. got=%s at %s", param.Name, param.Type.String(), elem, path)
+				}
+			}
+			param.Default = &object.Null{} // only marks the param as optional, since defaults aren't evaluated until the definition runs
+		}
+		switch {
+		case param.Variadic:
+			inner.declare(param.Name, of(object.ARRAY_OBJ), nil)
+		case param.Type != nil:
+			inner.declare(param.Name, Type(param.Type.Kinds), nil)
+		default:
+			inner.declare(param.Name, unknown, nil)
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func (c *Checker) checkPipeInvocation(node *ast.PipeInvocation, s *scope) {
+	argTypes := make([]Type, len(node.Call.Arguments))
+	for idx, arg := range node.Call.Arguments {
+		argTypes[idx] = c.check(arg.Value, s)
+	}
+	if node.Module != nil {
+		return // pipes of imported files are checked against their own definitions by the evaluator
+	}
+
+	pipe, ok := c.lookupPipe(node.Call.Name.Value)
+	if !ok {
+		c.newError(node.Call.Name.Position(), "pipe not found: %s", node.Call.Name.Value)
+		return
+	}
+	c.checkDefinedArguments(node.Call, pipe.Name, pipe.Params, argTypes, nil)
+}
+
+// checks args against the params of a pipe or fn the same way the evaluator binds them
+func (c *Checker) checkDefinedArguments(node *ast.CallExpression, name string, params []object.Param, argTypes []Type, leading []Type) {
+	binder := object.NewBinder(name, object.ParamSpecs(params))
+
+	for _, t := range leading {
+		idx, err := binder.Positional()
+		if err != nil {
+			c.newError(node.Position(), "cannot pass %s into %s", t, name)
+			continue
+		}
+		if param := params[idx]; param.Type != nil && !compatible(t, param.Type.Kinds) {
+			c.newError(node.Position(), "argument %q for %s must be %s. got=%s", param.Name, name, param.Type.String(), t)
+		}
+	}
+
+	for argIdx, arg := range node.Arguments {
+		idx, ok := c.bindArgument(binder, arg)
+		if !ok {
+			continue
+		}
+		param := params[idx]
+		if param.Type == nil {
+			continue
+		}
+		if !compatible(argTypes[argIdx], param.Type.Kinds) {
+			c.newError(arg.Value.Position(), "argument %q for %s must be %s. got=%s", param.Name, name, param.Type.String(), argTypes[argIdx])
+		} else if path, elem, ok := c.elementMismatch(arg.Value, param.Type); ok {
+			c.newError(arg.Value.Position(), "argument %q for %s must be %s. got=%s at %s", param.Name, name, param.Type.String(), elem, path)
+		}
+	}
+
+	for _, missing := range binder.Missing() {
+		c.newError(node.Position(), "missing required argument %q for %s", missing, name)
+	}
+}
@@ -0,0 +1,519 @@
+// Package checker infers the types of a parsed program and reports operations that would fail for every value they could see,
+// like "a" - 1 or if 5 { }, before any records are evaluated.
+//
+// values that are only known at runtime, like fields of $src without a schema, are treated as unknown and never reported,
+// so a program with no type errors can still fail at runtime.
+package checker
+
+import (
+	"fmt"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/schema"
+	"github.com/hudsn/pipelang/token"
+)
+
+type Error struct {
+	Message  string
+	Position token.Position
+	Line     int
+	Column   int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("type error at %d:%d:\n\t%s", e.Line, e.Column, e.Message)
+}
+
+type Checker struct {
+	// lexed input of the program, used to turn node positions into line and column numbers for errors.
+	input []rune
+
+	builtins *builtins.Registry
+	source   *schema.Schema
+	dest     *schema.Schema
+	pipes    map[string]*object.Pipe // the signatures of pipes defined so far. Call is never set.
+	fns      map[string]*object.Fn   // the signatures of fns defined so far. Call is never set.
+	// the signatures of every top-level pipe and fn, so bodies can call ones defined after them, like mutually recursive fns.
+	// code outside of any body only sees the ones defined so far, the same as the evaluator.
+	hoistedPipes map[string]*object.Pipe
+	hoistedFns   map[string]*object.Fn
+	bodies       int // how many pipe or fn bodies deep the checker is
+	// the type of each element of the array literals checked so far, so a typed array can point at the element that doesn't match
+	elements map[*ast.ArrayLiteral][]Type
+	errors   []*Error
+}
+
+type Option func(c *Checker)
+
+// replaces the registry used to resolve function calls. it should be the same one the evaluator uses.
+func WithRegistry(r *builtins.Registry) Option {
+	return func(c *Checker) {
+		c.builtins = r
+	}
+}
+
+// checks field access on $src against a schema, so misspelled or undeclared fields are reported and declared fields have known types.
+// without one, every field of $src is unknown.
+func WithSourceSchema(s *schema.Schema) Option {
+	return func(c *Checker) {
+		c.source = s
+	}
+}
+
+// checks values assigned to fields of $dest against the types a schema declares for them.
+func WithDestSchema(s *schema.Schema) Option {
+	return func(c *Checker) {
+		c.dest = s
+	}
+}
+
+// the input should come from lexer.InputRunes() after parsing, since the lexer may insert characters like semicolons.
+func New(input []rune, opts ...Option) *Checker {
+	c := &Checker{
+		input:    input,
+		builtins: builtins.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Check returns every type error in the program in the order they're found, or nil if there are none.
+func (c *Checker) Check(program *ast.Program) []*Error {
+	c.errors = nil
+	c.pipes = map[string]*object.Pipe{}
+	c.fns = map[string]*object.Fn{}
+	c.elements = map[*ast.ArrayLiteral][]Type{}
+	s := newScope(nil)
+	c.hoistSignatures(program, s)
+	for _, statement := range program.Statements {
+		c.check(statement, s)
+	}
+	return c.errors
+}
+
+// collects the signatures of the program's top-level pipes and fns before any bodies are checked.
+// problems with a signature are reported when its definition is checked, so they're dropped here.
+func (c *Checker) hoistSignatures(program *ast.Program, s *scope) {
+	c.hoistedPipes = map[string]*object.Pipe{}
+	c.hoistedFns = map[string]*object.Fn{}
+	errs := c.errors
+	for _, statement := range program.Statements {
+		switch node := statement.(type) {
+		case *ast.PipeDefinition:
+			params := c.declareParams(node.Params, s, newScope(s))
+			c.hoistedPipes[node.Name.Value] = &object.Pipe{Name: node.Name.Value, Params: params}
+		case *ast.FunctionDefinition:
+			if _, ok := c.builtins.Lookup(node.Name.Value); ok {
+				continue
+			}
+			params := c.declareParams(node.Params, s, newScope(s))
+			c.hoistedFns[node.Name.Value] = &object.Fn{Name: node.Name.Value, Params: params}
+		}
+	}
+	c.errors = errs
+}
+
+func (c *Checker) lookupPipe(name string) (*object.Pipe, bool) {
+	if pipe, ok := c.pipes[name]; ok {
+		return pipe, true
+	}
+	if c.bodies > 0 {
+		pipe, ok := c.hoistedPipes[name]
+		return pipe, ok
+	}
+	return nil, false
+}
+
+func (c *Checker) lookupFn(name string) (*object.Fn, bool) {
+	if fn, ok := c.fns[name]; ok {
+		return fn, true
+	}
+	if c.bodies > 0 {
+		fn, ok := c.hoistedFns[name]
+		return fn, ok
+	}
+	return nil, false
+}
+
+// returns the possible types of node. an expression that was reported as an error is unknown, so one mistake isn't reported again by everything that uses it.
+func (c *Checker) check(node ast.Node, s *scope) Type {
+	switch node := node.(type) {
+
+	// statements
+	case *ast.BlockStatement:
+		ret := of(object.NULL_OBJ)
+		for _, statement := range node.Statements {
+			ret = c.check(statement, s)
+		}
+		return ret
+	case *ast.ExpressionStatement:
+		return c.check(node.Expression, s)
+	case *ast.AssignStatement:
+		return c.checkAssignStatement(node, s)
+	case *ast.FieldAssignStatement:
+		return c.checkFieldAssignStatement(node, s)
+	case *ast.PipeDefinition:
+		c.checkPipeDefinition(node, s)
+		return of(object.NULL_OBJ)
+	case *ast.PipeInvocation:
+		c.checkPipeInvocation(node, s)
+		return of(object.NULL_OBJ)
+	case *ast.FunctionDefinition:
+		c.checkFunctionDefinition(node, s)
+		return of(object.NULL_OBJ)
+	case *ast.SchemaStatement:
+		return of(object.NULL_OBJ) // converted by the caller, and passed in WithSourceSchema
+	case *ast.ImportStatement:
+		// imported files are checked on their own, so their members aren't known here
+		s.declare(node.Alias.Value, of(object.MODULE_OBJ), nil)
+		return of(object.NULL_OBJ)
+
+	// literals
+	case *ast.IntegerLiteral:
+		return of(object.INTEGER_OBJ)
+	case *ast.FloatLiteral:
+		return of(object.FLOAT_OBJ)
+	case *ast.StringLiteral:
+		return of(object.STRING_OBJ)
+	case *ast.Boolean:
+		return of(object.BOOLEAN_OBJ)
+	case *ast.NullLiteral:
+		return of(object.NULL_OBJ)
+	case *ast.ArrayLiteral:
+		types := make([]Type, len(node.Elements))
+		for idx, el := range node.Elements {
+			types[idx] = c.check(el, s)
+		}
+		c.elements[node] = types
+		return of(object.ARRAY_OBJ)
+
+	// expressions
+	case *ast.Identifier:
+		// names the program never assigns, like $src, are set by the host
+		t, _ := s.get(node.Value)
+		return t
+	case *ast.PrefixExpression:
+		return c.checkPrefixExpression(node, c.check(node.Right, s))
+	case *ast.InfixExpression:
+		return c.checkInfixExpression(node, s)
+	case *ast.IfExpression:
+		return c.checkIfExpression(node, s)
+	case *ast.TryExpression:
+		return c.checkTryExpression(node, s)
+	case *ast.DotAccess:
+		return c.checkDotAccess(node, s)
+	case *ast.CallExpression:
+		return c.checkCall(node, s)
+	case *ast.PipeExpression:
+		return c.checkCall(node.Call, s, c.check(node.Value, s))
+	case *ast.ArrowFunctionExpression:
+		inner := newScope(s)
+		inner.declare(node.Param.Value, unknown, nil)
+		c.check(node.QueryExpression, inner)
+		return of(object.FUNCTION_OBJ)
+	}
+	return unknown
+}
+
+func (c *Checker) checkPrefixExpression(node *ast.PrefixExpression, right Type) Type {
+	if !right.Known() {
+		if node.Operator == "!" {
+			return of(object.BOOLEAN_OBJ)
+		}
+		return unknown
+	}
+
+	ret := Type{}
+	for _, k := range right {
+		switch {
+		case node.Operator == "!" && (k == object.BOOLEAN_OBJ || k == object.NULL_OBJ):
+			ret = ret.with(of(object.BOOLEAN_OBJ))
+		case node.Operator == "-" && k == object.INTEGER_OBJ:
+			ret = ret.with(of(object.INTEGER_OBJ))
+		case node.Operator == "-" && k == object.FLOAT_OBJ:
+			ret = ret.with(of(object.FLOAT_OBJ))
+		case node.Operator == "-" && isExactKind(k):
+			ret = ret.with(of(object.INTEGER_OBJ, object.UINTEGER_OBJ, object.DECIMAL_OBJ))
+		}
+	}
+	if !ret.Known() {
+		c.newError(node.Position(), "invalid operand for %s: %s", node.Operator, right)
+		return unknown
+	}
+	return ret
+}
+
+func (c *Checker) checkInfixExpression(node *ast.InfixExpression, s *scope) Type {
+	left := c.check(node.Left, s)
+	right := c.check(node.Right, s)
+
+	switch node.Operator {
+	case "&&", "||":
+		ok := true
+		if !compatible(left, builtins.Boolean) {
+			c.newError(node.Left.Position(), "invalid operand for %s: %s", node.Operator, left)
+			ok = false
+		}
+		if !compatible(right, builtins.Boolean) {
+			c.newError(node.Right.Position(), "invalid operand for %s: %s", node.Operator, right)
+			ok = false
+		}
+		if !ok {
+			return unknown
+		}
+		return of(object.BOOLEAN_OBJ)
+	}
+
+	if !left.Known() || !right.Known() {
+		if isComparison(node.Operator) {
+			return of(object.BOOLEAN_OBJ)
+		}
+		return unknown
+	}
+
+	// only report the operation when no combination of the operand types would work
+	ret := Type{}
+	for _, l := range left {
+		for _, r := range right {
+			if t, ok := infixKind(node.Operator, l, r); ok {
+				ret = ret.with(t)
+			}
+		}
+	}
+	if !ret.Known() {
+		c.newError(node.Position(), "type mismatch: %s %s %s", left, node.Operator, right)
+		return unknown
+	}
+	return ret
+}
+
+// each branch has a scope of its own, so a variable of the enclosing scope assigned in only one of them may still have its earlier type afterwards
+func (c *Checker) checkIfExpression(node *ast.IfExpression, s *scope) Type {
+	condition := c.check(node.Condition, s)
+	// null is treated like false
+	if !compatible(condition, []object.ObjectType{object.BOOLEAN_OBJ, object.NULL_OBJ}) {
+		c.newError(node.Condition.Position(), "if condition must be a boolean. got=%s", condition)
+	}
+
+	consequence := s.branch()
+	ret := c.check(node.Consequence, consequence)
+	alternative := s.branch()
+	if node.Alternative != nil {
+		ret = union(ret, c.check(node.Alternative, alternative))
+	} else {
+		ret = union(ret, of(object.NULL_OBJ))
+	}
+	s.merge(consequence, alternative)
+	return ret
+}
+
+// the try block can fail partway through, so the catch block and whatever comes after may see the types from before or during it
+func (c *Checker) checkTryExpression(node *ast.TryExpression, s *scope) Type {
+	block := s.branch()
+	ret := c.check(node.Block, block)
+
+	catch := s.branch()
+	catch.merge(block, s.branch())
+	if node.ErrorName != nil {
+		catch.declare(node.ErrorName.Value, of(object.ERROR_VALUE_OBJ), nil)
+	}
+	ret = union(ret, c.check(node.Catch, catch))
+
+	s.merge(block, catch)
+	return ret
+}
+
+func (c *Checker) checkDotAccess(node *ast.DotAccess, s *scope) Type {
+	t := c.check(node.Object, s)
+	var fields schema.Fields
+	path := node.Object.String()
+	if ident, ok := node.Object.(*ast.Identifier); ok && ident.Value == "$src" && c.source != nil {
+		if _, assigned := s.get(ident.Value); !assigned {
+			fields = c.source.Fields
+		}
+	}
+
+	item := node.Item
+	for {
+		var name *ast.Identifier
+		switch current := item.(type) {
+		case *ast.Identifier:
+			name, item = current, nil
+		case *ast.DotAccess:
+			ident, ok := current.Object.(*ast.Identifier)
+			if !ok {
+				c.checkItemArguments(current, s)
+				return unknown // reported by the evaluator as an invalid property access, unless it's a call of an imported fn
+			}
+			name, item = ident, current.Item
+		default:
+			c.checkItemArguments(current, s)
+			return unknown
+		}
+
+		if fields != nil {
+			t, fields = c.fieldType(fields, name, path)
+		} else {
+			t = c.propertyType(t, name)
+		}
+		if item == nil {
+			return t
+		}
+		path += "." + name.Value
+	}
+}
+
+// a call after a dot is a fn of an imported file, like: norm.clean(value).
+// those are checked against their own definitions by the evaluator, like pipes of imported files, so only the args are checked here.
+func (c *Checker) checkItemArguments(item ast.Expression, s *scope) {
+	switch item := item.(type) {
+	case *ast.CallExpression:
+		for _, arg := range item.Arguments {
+			c.check(arg.Value, s)
+		}
+	case *ast.DotAccess:
+		c.checkItemArguments(item.Object, s)
+		c.checkItemArguments(item.Item, s)
+	}
+}
+
+// returns the type of a field declared by a schema, and the fields declared under it.
+// the fields are nil when the field isn't a map, or its fields aren't declared.
+func (c *Checker) fieldType(fields schema.Fields, name *ast.Identifier, path string) (Type, schema.Fields) {
+	f := fields.Get(name.Value)
+	if f == nil {
+		if suggestion := fields.Suggest(name.Value); suggestion != "" {
+			c.newError(name.Position(), "%s has no field %q. did you mean %q?", path, name.Value, suggestion)
+		} else {
+			c.newError(name.Position(), "%s has no field %q", path, name.Value)
+		}
+		return unknown, nil
+	}
+	t := Type(f.Type.Kinds)
+	if f.Optional && t.Known() {
+		t = t.with(of(object.NULL_OBJ))
+	}
+	return t, f.Fields
+}
+
+func (c *Checker) propertyType(t Type, name *ast.Identifier) Type {
+	if !t.Known() {
+		return unknown
+	}
+	ret := Type{}
+	for _, k := range t {
+		switch k {
+		case object.MAP_OBJ, object.MODULE_OBJ:
+			return unknown // map fields can hold anything, and the members of imported files aren't known
+		case object.NULL_OBJ:
+			ret = ret.with(of(object.NULL_OBJ))
+		case object.ERROR_VALUE_OBJ:
+			switch name.Value {
+			case "message":
+				ret = ret.with(of(object.STRING_OBJ))
+			case "line", "column":
+				ret = ret.with(of(object.INTEGER_OBJ))
+			}
+		}
+	}
+	if !ret.Known() {
+		c.newError(name.Position(), "%s has no property %q", t, name.Value)
+		return unknown
+	}
+	return ret
+}
+
+// checks args against the function's params the same way the evaluator binds them.
+// leading types are for values passed ahead of the call's own args, like the piped value in: value | call()
+func (c *Checker) checkCall(node *ast.CallExpression, s *scope, leading ...Type) Type {
+	argTypes := make([]Type, len(node.Arguments))
+	for idx, arg := range node.Arguments {
+		argTypes[idx] = c.check(arg.Value, s)
+	}
+
+	if defined, ok := c.lookupFn(node.Name.Value); ok {
+		// the result depends on the body, which may call the fn itself
+		c.checkDefinedArguments(node, defined.Name, defined.Params, argTypes, leading)
+		return unknown
+	}
+	fn, ok := c.builtins.Lookup(node.Name.Value)
+	if !ok {
+		c.newError(node.Name.Position(), "function not found: %s", node.Name.Value)
+		return unknown
+	}
+	binder := object.NewBinder(fn.Name, fn.ParamSpecs())
+
+	for _, t := range leading {
+		idx, err := binder.Positional()
+		if err != nil {
+			c.newError(node.Position(), "cannot pass %s into %s", t, fn.Name)
+			continue
+		}
+		if param := fn.Params[idx]; !compatible(t, param.Types) {
+			c.newError(node.Position(), "argument %q for %s must be %s. got=%s", param.Name, fn.Name, joinTypes(param.Types), t)
+		}
+	}
+
+	for argIdx, arg := range node.Arguments {
+		idx, ok := c.bindArgument(binder, arg)
+		if !ok {
+			continue
+		}
+		if param := fn.Params[idx]; !compatible(argTypes[argIdx], param.Types) {
+			c.newError(arg.Value.Position(), "argument %q for %s must be %s. got=%s", param.Name, fn.Name, joinTypes(param.Types), argTypes[argIdx])
+		}
+	}
+
+	for _, name := range binder.Missing() {
+		c.newError(node.Position(), "missing required argument %q for %s", name, fn.Name)
+	}
+	return Type(fn.Returns)
+}
+
+// returns the index of the param arg fills, after reporting a binding error at the arg if it doesn't fill one
+func (c *Checker) bindArgument(binder *object.Binder, arg *ast.Argument) (int, bool) {
+	if arg.Name != nil {
+		idx, err := binder.Named(arg.Name.Value)
+		if err != nil {
+			c.newError(arg.Name.Position(), "%s", err.Error())
+			return -1, false
+		}
+		return idx, true
+	}
+	idx, err := binder.Positional()
+	if err != nil {
+		c.newError(arg.Value.Position(), "%s", err.Error())
+		return -1, false
+	}
+	return idx, true
+}
+
+func joinTypes(types []object.ObjectType) string {
+	ret := ""
+	for idx, t := range types {
+		switch {
+		case idx == 0:
+		case idx == len(types)-1:
+			ret += " or "
+		default:
+			ret += ", "
+		}
+		ret += string(t)
+	}
+	return ret
+}
+
+func (c *Checker) newError(pos token.Position, format string, a ...any) {
+	start, _ := pos.GetPosition()
+	line, col := token.LineAndColumn(c.input, max(start, 0))
+	c.errors = append(c.errors, &Error{
+		Message:  fmt.Sprintf(format, a...),
+		Position: pos,
+		Line:     line,
+		Column:   col,
+	})
+}
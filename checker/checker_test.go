@@ -0,0 +1,222 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/schema"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // the first error
+	}{
+		{`"a" - 1`, "type error at 1:1:\n\ttype mismatch: STRING - INTEGER"},
+		{`"a" * "b"`, "type error at 1:1:\n\ttype mismatch: STRING * STRING"},
+		{"if 5 { 1 }", "type error at 1:4:\n\tif condition must be a boolean. got=INTEGER"},
+		{"-'a'", "type error at 1:1:\n\tinvalid operand for -: STRING"},
+		{"!1.5", "type error at 1:1:\n\tinvalid operand for !: FLOAT"},
+		{"1 && true", "type error at 1:1:\n\tinvalid operand for &&: INTEGER"},
+		{"x = 'a'\ny = x + 1", "type error at 2:5:\n\ttype mismatch: STRING + INTEGER"},
+		{"upper('a') - 1", "type error at 1:1:\n\ttype mismatch: STRING - INTEGER"},
+		{"upper(1)", "type error at 1:7:\n\targument \"value\" for upper must be STRING. got=INTEGER"},
+		{"1 | upper", "type error at 1:5:\n\targument \"value\" for upper must be STRING. got=INTEGER"},
+		{"upper()", "type error at 1:1:\n\tmissing required argument \"value\" for upper"},
+		{"upper('a', 'b')", "type error at 1:12:\n\ttoo many arguments for upper: want at most 1"},
+		{"split('a', sep: ',', size: 1)", "type error at 1:22:\n\tunknown argument \"size\" for split"},
+		{"nope()", "type error at 1:1:\n\tfunction not found: nope"},
+		{"f = x ~> upper(x) - 1", "type error at 1:10:\n\ttype mismatch: STRING - INTEGER"},
+		{"x = 'a'\nif $src.ok { x: int = 1 }\nx - 1", "type error at 3:1:\n\ttype mismatch: STRING - INTEGER"},
+		{"x = 'a'\nif $src.ok { let x = 1 }\nx - 1", "type error at 3:1:\n\ttype mismatch: STRING - INTEGER"},
+		{"x = 1\nif $src.ok { x = 'a' } else { if $src.more { x = 'b' } else { x = 'c' } }\nx - 1", "type error at 3:1:\n\ttype mismatch: STRING - INTEGER"},
+		{"1.a", "type error at 1:3:\n\tINTEGER has no property \"a\""},
+		{"try { 1 } catch err { err.message - 1 }", "type error at 1:23:\n\ttype mismatch: STRING - INTEGER"},
+		{"now() - 1", "type error at 1:1:\n\ttype mismatch: TIME - INTEGER"},
+		{"x: float = 1", "type error at 1:12:\n\tx is declared as float. got=INTEGER"},
+		{"x: int = $src.n\nx = 'a'", "type error at 2:5:\n\tx is declared as int. got=STRING"},
+		{"ids: array<string> = ['a', 1]", "type error at 1:22:\n\tids is declared as array<string>. got=INTEGER at [1]"},
+		{"ids: array<array<int>> = [[1], [2, 'a']]", "type error at 1:26:\n\tids is declared as array<array<int>>. got=STRING at [1][1]"},
+		{"ids: array<string> = ['a']\nids = [1]", "type error at 2:7:\n\tids is declared as array<string>. got=INTEGER at [0]"},
+		{"fn f(tags: array<string> = [1]) { tags }", "type error at 1:28:\n\tdefault value for \"tags\" must be array<string>. got=INTEGER at [0]"},
+		{"fn f(tags: array<string>) { tags }\nf(['a', 2])", "type error at 2:3:\n\targument \"tags\" for f must be array<string>. got=INTEGER at [1]"},
+		{"x: int = $src.n\nx + 'a'", "type error at 2:1:\n\ttype mismatch: INTEGER|UINTEGER + STRING"},
+		{"x: integer = 1", "type error at 1:4:\n\tunknown type \"integer\". expected one of any, array, bool, decimal, duration, float, function, int, ip, map, number, string, time"},
+		{"pipe p(n: string) { n - 1 }", "type error at 1:21:\n\ttype mismatch: STRING - INTEGER"},
+		{"pipe p(n: int) { }\n| p('a')", "type error at 2:5:\n\targument \"n\" for p must be int. got=STRING"},
+		{"pipe p(n: int) { }\n| p()", "type error at 2:3:\n\tmissing required argument \"n\" for p"},
+		{"| p()", "type error at 1:3:\n\tpipe not found: p"},
+		{"fn f(n: int) { n }\nf('a')", "type error at 2:3:\n\targument \"n\" for f must be int. got=STRING"},
+		{"fn f(n) { n }\nf(1, m: 2)", "type error at 2:6:\n\tunknown argument \"m\" for f"},
+		{"fn f(n) { n }\nf()", "type error at 2:1:\n\tmissing required argument \"n\" for f"},
+		{"fn len(v) { v }", "type error at 1:4:\n\tcannot define fn len: a builtin function has that name"},
+		{"fn f() { g('a') }\nfn g(n: int) { n }", "type error at 1:12:\n\targument \"n\" for g must be int. got=STRING"},
+		// code outside of a body can only call what's defined before it, like in the evaluator
+		{"f()\nfn f() { 1 }", "type error at 1:1:\n\tfunction not found: f"},
+		{"fn f(n: int = 'a') { n }", "type error at 1:15:\n\tdefault value for \"n\" must be int. got=STRING"},
+		{"fn f(a, b = 1) { a }\nf(b: 2)", "type error at 2:1:\n\tmissing required argument \"a\" for f"},
+		{"fn f(...parts: string) { parts }\nf('a', 1)", "type error at 2:8:\n\targument \"parts\" for f must be string. got=INTEGER"},
+		{"fn f(...parts) { parts - 1 }", "type error at 1:18:\n\ttype mismatch: ARRAY - INTEGER"},
+		{"import 'a.pl' as a\na.f('a' - 1)", "type error at 2:5:\n\ttype mismatch: STRING - INTEGER"},
+	}
+	for _, tt := range tests {
+		errs := setupCheckWithInput(t, tt.input)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected a type error", tt.input)
+			continue
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, errs[0].Error()); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.input, failMsg)
+		}
+	}
+}
+
+// operations that could work for some of the values they see are left for the evaluator
+func TestNoTypeErrors(t *testing.T) {
+	tests := []string{
+		"$src.a - 1",
+		"$src.a.b | upper",
+		"x = 1\nif $src.flag { x = 'a' }\nx - 1",
+		"x = 1\ntry { x = $src.a } catch { x = 'a' }\nx - 1",
+		"if $src.ok { 1 } else { 'a' } + 1",
+		"if null { 1 }",
+		"'a' + 'b' == 'ab' && !null",
+		"now() - now() > duration('1s')",
+		"duration('1s') * 2",
+		"parse_json('1') + 1",
+		"to_int('1', strict: false) + 1",
+		"sort([3, 1], by: e ~> -e, desc: true)",
+		"1 == 'a'",
+		"try { 1 / 0 } catch err { err.line + 1 }",
+		"x: number = 1\nx = 1.5",
+		"pipe p(n: int, tags: array<string>) { n + 1 }\n| p($src.n, tags: $src.tags)",
+		"pipe p(n) { n - 1 }\n| p('a')",
+		"x = 'a'\nif $src.ok { x: int = 1\nx - 1 }",
+		"x: int = 1\nif $src.ok { x = 2 }\nx - 1",
+		"let x = 'a'\nif $src.ok { let x = 1\nx - 1 }",
+		"const limit = 10\nlimit - 1",
+		"fn fact(n: int) { if n < 2 { 1 } else { n * fact(n - 1) } }\nfact(n: 5)",
+		"pipe tag(name, value: string = 'true') { }\n| tag('a')",
+		"fn concat(sep = '', ...parts: string) { join(parts, sep) }\nconcat(',', 'a', 'b')\n'x' | concat()",
+		// bodies can call top-level fns and pipes defined after them
+		"fn f(n: int) { if n > 0 { g(n - 1) } else { 0 } }\nfn g(n: int) { f(n) }\nf(3)",
+		"pipe a() { | b() }\npipe b() { }\n| a()",
+	}
+	for _, input := range tests {
+		for _, err := range setupCheckWithInput(t, input) {
+			t.Errorf("%s: unexpected error: %s", input, err.Error())
+		}
+	}
+}
+
+func TestHoistedSignatureErrorsAreReportedOnce(t *testing.T) {
+	errs := setupCheckWithInput(t, "fn f() { g() }\nfn g(n: int = 'a') { n }")
+	got := []string{}
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := "type error at 2:15:\n\tdefault value for \"n\" must be int. got=STRING"
+	if isEq, failMsg := testutils.Equal(want, strings.Join(got, "\n")); !isEq {
+		t.Errorf("wrong errors: %s", failMsg)
+	}
+}
+
+func TestCheckReportsEveryError(t *testing.T) {
+	errs := setupCheckWithInput(t, "'a' - 1\nif 5 { true }\n1 - 'b'")
+	if isEq, failMsg := testutils.Equal(3, len(errs)); !isEq {
+		t.Fatalf("wrong number of errors: %s", failMsg)
+	}
+	// a mistake isn't reported again by the expressions that use it
+	errs = setupCheckWithInput(t, "x = 'a' - 1\ny = x * 2")
+	if isEq, failMsg := testutils.Equal(1, len(errs)); !isEq {
+		t.Fatalf("wrong number of errors: %s", failMsg)
+	}
+}
+
+func TestSourceSchema(t *testing.T) {
+	input := "schema $src { user: { name: string, age?: int, meta: map }, count: int }"
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := schema.FromProgram(program, l.InputRunes(), "$src")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  string // the first error, or empty for none
+	}{
+		{"$src.user.name + 'a'", ""},
+		{"$src.user.meta.anything", ""},
+		{"x = $src\nx.nope", ""},
+		{"$src.count - 'a'", "type error at 1:1:\n\ttype mismatch: INTEGER|UINTEGER - STRING"},
+		{"$src.usr.name", "type error at 1:6:\n\t$src has no field \"usr\". did you mean \"user\"?"},
+		{"$src.user.email", "type error at 1:11:\n\t$src.user has no field \"email\""},
+		{"$src.user.name.first", "type error at 1:16:\n\tSTRING has no property \"first\""},
+		{"$src.user.age - 1", ""},
+	}
+	for _, tt := range tests {
+		l := lexer.New([]rune(tt.input))
+		program, err := parser.New(l).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := New(l.InputRunes(), WithSourceSchema(src)).Check(program)
+		got := ""
+		if len(errs) > 0 {
+			got = errs[0].Error()
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, got); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestDestSchema(t *testing.T) {
+	dest, err := schema.FromJSONSchema([]byte(`{"properties": {"user": {"properties": {"id": {"type": "integer"}}}, "note": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input string
+		want  string // the first error, or empty for none
+	}{
+		{"$dest.user.id = 1\n$dest.note = null\n$dest.extra = 'a'", ""},
+		{"$dest.user.id = $src.id", ""},
+		{"$dest.user.id = 'a'", "type error at 1:17:\n\t$dest.user.id is declared as integer. got=STRING"},
+		{"$dest.note = 1 - 'a'", "type error at 1:14:\n\ttype mismatch: INTEGER - STRING"},
+		{"$dest.tags = ['a', $src.tag]", ""},
+		{"$dest.tags = ['a', 1]", "type error at 1:14:\n\t$dest.tags is declared as array<string>. got=INTEGER at [1]"},
+	}
+	for _, tt := range tests {
+		l := lexer.New([]rune(tt.input))
+		program, err := parser.New(l).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := New(l.InputRunes(), WithDestSchema(dest)).Check(program)
+		got := ""
+		if len(errs) > 0 {
+			got = errs[0].Error()
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, got); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func setupCheckWithInput(t *testing.T, input string) []*Error {
+	l := lexer.New([]rune(input))
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("setupCheckWithInput: %s", err.Error())
+	}
+	return New(l.InputRunes()).Check(program)
+}
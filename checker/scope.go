@@ -0,0 +1,95 @@
+package checker
+
+import "github.com/hudsn/pipelang/object"
+
+// tracks the type of each variable with the same scoping rules as the evaluator's environments:
+// assigning to a variable of an enclosing scope updates it, and assigning to a new name creates it in the current scope.
+type scope struct {
+	vars     map[string]Type
+	declared map[string]*object.TypeSpec // types of this scope's annotated variables
+	// assignments to variables of enclosing scopes. they're only applied by merge, since code like the consequence of an if may not run.
+	updates map[string]Type
+	outer   *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: map[string]Type{}, declared: map[string]*object.TypeSpec{}, updates: map[string]Type{}, outer: outer}
+}
+
+func (s *scope) get(name string) (Type, bool) {
+	if t, ok := s.vars[name]; ok {
+		return t, true
+	}
+	if t, ok := s.updates[name]; ok {
+		return t, true
+	}
+	if s.outer != nil {
+		return s.outer.get(name)
+	}
+	return nil, false
+}
+
+// returns the scope that holds a variable, or nil if none do
+func (s *scope) owner(name string) *scope {
+	for current := s; current != nil; current = current.outer {
+		if _, ok := current.vars[name]; ok {
+			return current
+		}
+	}
+	return nil
+}
+
+func (s *scope) assign(name string, t Type) {
+	if owner := s.owner(name); owner != nil && owner != s {
+		s.updates[name] = t
+		return
+	}
+	s.vars[name] = t
+}
+
+// creates a variable in this scope, shadowing any outer one with the same name
+func (s *scope) declare(name string, t Type, spec *object.TypeSpec) {
+	s.vars[name] = t
+	if spec != nil {
+		s.declared[name] = spec
+	} else {
+		delete(s.declared, name)
+	}
+}
+
+func (s *scope) declaredType(name string) (*object.TypeSpec, bool) {
+	owner := s.owner(name)
+	if owner == nil {
+		return nil, false
+	}
+	spec, ok := owner.declared[name]
+	return spec, ok
+}
+
+// returns a scope for code that may or may not run, like the consequence of an if
+func (s *scope) branch() *scope {
+	return newScope(s)
+}
+
+// assigns each variable the branches updated the union of its types in them.
+// a variable a branch didn't update keeps the type it had before the branches.
+func (s *scope) merge(branches ...*scope) {
+	names := map[string]bool{}
+	for _, b := range branches {
+		for name := range b.updates {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		before, _ := s.get(name)
+		types := []Type{}
+		for _, b := range branches {
+			if t, ok := b.updates[name]; ok {
+				types = append(types, t)
+			} else {
+				types = append(types, before)
+			}
+		}
+		s.assign(name, union(types...))
+	}
+}
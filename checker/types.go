@@ -0,0 +1,171 @@
+package checker
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// Type is the set of object types an expression can evaluate to.
+// an empty Type is unknown, like a field read from $src, and is compatible with everything.
+type Type []object.ObjectType
+
+func of(kinds ...object.ObjectType) Type {
+	return Type(kinds)
+}
+
+var (
+	unknown   = Type(nil)
+	exactType = of(object.INTEGER_OBJ, object.UINTEGER_OBJ, object.DECIMAL_OBJ, object.FLOAT_OBJ) // exact arithmetic falls back to a float for things like 1/3
+)
+
+func (t Type) Known() bool {
+	return len(t) > 0
+}
+
+func (t Type) String() string {
+	if !t.Known() {
+		return "ANY"
+	}
+	kinds := []string{}
+	for _, k := range t {
+		kinds = append(kinds, string(k))
+	}
+	return strings.Join(kinds, "|")
+}
+
+// combines the possible types of each branch. if any branch is unknown, so is the result.
+func union(types ...Type) Type {
+	ret := Type{}
+	for _, t := range types {
+		if !t.Known() {
+			return unknown
+		}
+		ret = ret.with(t)
+	}
+	return ret
+}
+
+// adds the kinds of other that t doesn't have yet
+func (t Type) with(other Type) Type {
+	ret := slices.Clone(t)
+	for _, k := range other {
+		if !slices.Contains(ret, k) {
+			ret = append(ret, k)
+		}
+	}
+	return ret
+}
+
+// reports whether a value of type t could be passed where accepted types are expected. an empty accepted list takes anything.
+func compatible(t Type, accepted []object.ObjectType) bool {
+	if !t.Known() || len(accepted) == 0 {
+		return true
+	}
+	for _, k := range t {
+		if slices.Contains(accepted, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func isComparison(operator string) bool {
+	switch operator {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func isArithmetic(operator string) bool {
+	switch operator {
+	case "+", "-", "*", "/":
+		return true
+	}
+	return false
+}
+
+func isExactKind(k object.ObjectType) bool {
+	return k == object.INTEGER_OBJ || k == object.UINTEGER_OBJ || k == object.DECIMAL_OBJ
+}
+
+func isNumberKind(k object.ObjectType) bool {
+	return isExactKind(k) || k == object.FLOAT_OBJ
+}
+
+func isTemporalKind(k object.ObjectType) bool {
+	return k == object.TIME_OBJ || k == object.DURATION_OBJ
+}
+
+// the result of an infix operator for one pair of operand types, following the same order of cases as the evaluator.
+// returns false when the evaluator would fail.
+func infixKind(operator string, left object.ObjectType, right object.ObjectType) (Type, bool) {
+	switch {
+	case left == object.INTEGER_OBJ && right == object.INTEGER_OBJ:
+		return arithmeticOrComparison(operator, of(object.INTEGER_OBJ))
+	case isExactKind(left) && isExactKind(right):
+		return arithmeticOrComparison(operator, exactType)
+	case isNumberKind(left) && isNumberKind(right):
+		return arithmeticOrComparison(operator, of(object.FLOAT_OBJ))
+	case left == object.STRING_OBJ && right == object.STRING_OBJ:
+		if operator == "+" {
+			return of(object.STRING_OBJ), true
+		}
+		if isComparison(operator) {
+			return of(object.BOOLEAN_OBJ), true
+		}
+		return nil, false
+	case isTemporalKind(left) || isTemporalKind(right):
+		return temporalKind(operator, left, right)
+	case operator == "==" || operator == "!=":
+		return of(object.BOOLEAN_OBJ), true
+	}
+	return nil, false
+}
+
+func arithmeticOrComparison(operator string, arithmetic Type) (Type, bool) {
+	switch {
+	case isArithmetic(operator):
+		return arithmetic, true
+	case isComparison(operator):
+		return of(object.BOOLEAN_OBJ), true
+	}
+	return nil, false
+}
+
+func temporalKind(operator string, left object.ObjectType, right object.ObjectType) (Type, bool) {
+	switch {
+	case left == object.TIME_OBJ && right == object.TIME_OBJ:
+		if operator == "-" {
+			return of(object.DURATION_OBJ), true
+		}
+		if isComparison(operator) {
+			return of(object.BOOLEAN_OBJ), true
+		}
+	case left == object.TIME_OBJ && right == object.DURATION_OBJ:
+		if operator == "+" || operator == "-" {
+			return of(object.TIME_OBJ), true
+		}
+	case left == object.DURATION_OBJ && right == object.TIME_OBJ:
+		if operator == "+" {
+			return of(object.TIME_OBJ), true
+		}
+	case left == object.DURATION_OBJ && right == object.DURATION_OBJ:
+		if operator == "+" || operator == "-" {
+			return of(object.DURATION_OBJ), true
+		}
+		if isComparison(operator) {
+			return of(object.BOOLEAN_OBJ), true
+		}
+	case left == object.DURATION_OBJ && (right == object.INTEGER_OBJ || right == object.FLOAT_OBJ):
+		if operator == "*" || operator == "/" {
+			return of(object.DURATION_OBJ), true
+		}
+	}
+	if operator == "==" || operator == "!=" {
+		return of(object.BOOLEAN_OBJ), true
+	}
+	return nil, false
+}
@@ -0,0 +1,240 @@
+package evaluator
+
+import (
+	"errors"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/object"
+)
+
+func (e *Evaluator) evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+	return e.callFunction(node, env)
+}
+
+// leading values are bound as positional args ahead of the call's own args, like the piped value in: value | call()
+func (e *Evaluator) callFunction(node *ast.CallExpression, env *object.Environment, leading ...object.Object) object.Object {
+	if obj, ok := env.Get(node.Name.Value); ok {
+		if fn, ok := obj.(*object.Fn); ok {
+			return e.callDefinedFunction(node, fn, env, leading)
+		}
+	}
+	fn, ok := e.builtins.Lookup(node.Name.Value)
+	if !ok {
+		return e.newError(node.Name.Position(), "function not found: %s", node.Name.Value)
+	}
+
+	args, errObj := e.bindArguments(node, fn, env, leading)
+	if errObj != nil {
+		return errObj
+	}
+
+	result, err := fn.Fn(args)
+	// failures inside a function value passed as an arg already have their own position
+	var fnErr *object.Error
+	if errors.As(err, &fnErr) {
+		return fnErr
+	}
+	if err != nil {
+		return e.newError(node.Position(), "%s: %s", fn.Name, err.Error())
+	}
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// matches call arguments to the function's params, see object.Binder. defaults fill the params no arg did.
+func (e *Evaluator) bindArguments(node *ast.CallExpression, fn *builtins.Function, env *object.Environment, leading []object.Object) (*builtins.Args, *object.Error) {
+	values := map[string]object.Object{}
+	rest := []object.Object{}
+	binder := object.NewBinder(fn.Name, fn.ParamSpecs())
+
+	for _, val := range leading {
+		idx, err := binder.Positional()
+		if err != nil {
+			return nil, e.newError(node.Position(), "cannot pass %s into %s", val.Type(), fn.Name)
+		}
+		param := fn.Params[idx]
+		switch {
+		case param.Variadic && !param.Accepts(val):
+			return nil, e.newError(node.Position(), "cannot pass %s into %s", val.Type(), fn.Name)
+		case !param.Accepts(val):
+			return nil, e.newError(node.Position(), "argument %q for %s must be %s. got=%s", param.Name, fn.Name, joinTypes(param.Types), val.Type())
+		case param.Variadic:
+			rest = append(rest, val)
+		default:
+			values[param.Name] = val
+		}
+	}
+
+	for _, arg := range node.Arguments {
+		val := e.Eval(arg.Value, env)
+		if errObj, ok := val.(*object.Error); ok {
+			return nil, errObj
+		}
+		idx, errObj := e.bindArgument(binder, arg)
+		if errObj != nil {
+			return nil, errObj
+		}
+		param := fn.Params[idx]
+		if !param.Accepts(val) {
+			return nil, e.errArgumentType(arg, fn, param, val)
+		}
+		if param.Variadic {
+			rest = append(rest, val)
+		} else {
+			values[param.Name] = val
+		}
+	}
+
+	if missing := binder.Missing(); len(missing) > 0 {
+		return nil, e.newError(node.Position(), "missing required argument %q for %s", missing[0], fn.Name)
+	}
+	for _, param := range fn.Params {
+		if _, found := values[param.Name]; !found && !param.Variadic {
+			values[param.Name] = param.Default
+		}
+	}
+	return builtins.NewArgs(values, rest), nil
+}
+
+// returns the index of the param arg fills, reporting a binding error at the arg
+func (e *Evaluator) bindArgument(binder *object.Binder, arg *ast.Argument) (int, *object.Error) {
+	if arg.Name != nil {
+		idx, err := binder.Named(arg.Name.Value)
+		if err != nil {
+			return -1, e.newError(arg.Name.Position(), "%s", err.Error())
+		}
+		return idx, nil
+	}
+	idx, err := binder.Positional()
+	if err != nil {
+		return -1, e.newError(arg.Value.Position(), "%s", err.Error())
+	}
+	return idx, nil
+}
+
+func (e *Evaluator) errArgumentType(arg *ast.Argument, fn *builtins.Function, param builtins.Param, val object.Object) *object.Error {
+	return e.newError(arg.Value.Position(), "argument %q for %s must be %s. got=%s", param.Name, fn.Name, joinTypes(param.Types), val.Type())
+}
+
+// arrow functions close over the environment they're created in, and bind their param in a scope of their own
+func (e *Evaluator) newArrowFunction(node *ast.ArrowFunctionExpression, env *object.Environment) *object.Function {
+	return &object.Function{
+		Params: []string{node.Param.Value},
+		Body:   node.QueryExpression.String(),
+		Call: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return e.newError(node.Position(), "arrow function takes 1 argument. got=%d", len(args))
+			}
+			scope := object.NewEnclosedEnvironment(env)
+			scope.Set(node.Param.Value, args[0])
+			return e.Eval(node.QueryExpression, scope)
+		},
+	}
+}
+
+func joinTypes(types []object.ObjectType) string {
+	ret := ""
+	for idx, t := range types {
+		switch {
+		case idx == 0:
+		case idx == len(types)-1:
+			ret += " or "
+		default:
+			ret += ", "
+		}
+		ret += string(t)
+	}
+	return ret
+}
+
+// Prepare runs the Precompile hooks for every string literal passed to a builtin param that has one, and computes the value of every constant of pure literals.
+// this lets things like regex patterns be compiled once up front, and reports invalid ones before any records are evaluated.
+// it also loads the files the program imports, see WithLoader.
+func (e *Evaluator) Prepare(program *ast.Program) error {
+	if err := e.foldConstants(program); err != nil {
+		return err
+	}
+	if err := e.prepareImports(program); err != nil {
+		return err
+	}
+
+	var err *object.Error
+	var visit func(node ast.Node) bool
+	// a call after a dot is a fn of an imported file rather than a builtin, like: norm.clean(value), so only its args are visited
+	var visitItem func(item ast.Expression)
+	visitItem = func(item ast.Expression) {
+		switch item := item.(type) {
+		case *ast.CallExpression:
+			for _, arg := range item.Arguments {
+				ast.Inspect(arg.Value, visit)
+			}
+		case *ast.DotAccess:
+			visitItem(item.Object)
+			visitItem(item.Item)
+		}
+	}
+	visit = func(node ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			err = e.precompileCall(node, 0)
+		case *ast.PipeExpression:
+			// the piped value shifts the call's positional args over by one, so handle the call here instead of letting it be visited on its own
+			ast.Inspect(node.Value, visit)
+			if err == nil {
+				err = e.precompileCall(node.Call, 1)
+			}
+			for _, arg := range node.Call.Arguments {
+				ast.Inspect(arg.Value, visit)
+			}
+			return false
+		case *ast.DotAccess:
+			ast.Inspect(node.Object, visit)
+			visitItem(node.Item)
+			return false
+		}
+		return err == nil
+	}
+	ast.Inspect(program, visit)
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Evaluator) precompileCall(node *ast.CallExpression, leading int) *object.Error {
+	fn, ok := e.builtins.Lookup(node.Name.Value)
+	if !ok {
+		return nil // reported when the call is evaluated
+	}
+	for argIdx, arg := range node.Arguments {
+		literal, ok := arg.Value.(*ast.StringLiteral)
+		if !ok {
+			continue
+		}
+
+		var param builtins.Param
+		var found bool
+		if arg.Name != nil {
+			param, found = fn.Param(arg.Name.Value)
+		} else if idx := argIdx + leading; idx < len(fn.Params) {
+			param, found = fn.Params[idx], true
+		} else {
+			param, found = fn.VariadicParam()
+		}
+
+		if !found || param.Precompile == nil {
+			continue
+		}
+		if err := param.Precompile(literal.Value); err != nil {
+			return e.newError(literal.Position(), "%s: invalid %s: %s", fn.Name, param.Name, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestCallArgumentBinding(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"describe('a')", "a|-|[]"},
+		{"describe('a', 'b')", "a|b|[]"},
+		{"describe('a', 'b', 1, 2)", "a|b|[1 2]"},
+		{"describe(sep: 'b', name: 'a')", "a|b|[]"},
+		{"describe('a', sep: '+')", "a|+|[]"},
+		{"try { describe(1) } catch err { err.message }", `argument "name" for describe must be STRING. got=INTEGER`},
+		{"try { describe() } catch err { err.message }", `missing required argument "name" for describe`},
+		{"try { describe('a', other: 1) } catch err { err.message }", `unknown argument "other" for describe`},
+		{"try { describe('a', name: 'b') } catch err { err.message }", `duplicate argument "name" for describe`},
+		{"try { describe('a', 'b', 'c') } catch err { err.message }", `argument "rest" for describe must be INTEGER, UINTEGER, FLOAT or DECIMAL. got=STRING`},
+		{"try { pair(1, 2, 3) } catch err { err.message }", "too many arguments for pair: want at most 2"},
+		{"try { fails() } catch err { err.message }", "fails: something went wrong"},
+		{"try { missing() } catch err { err.message }", "function not found: missing"},
+	}
+	for _, tt := range tests {
+		testStringObject(t, setupCallTestWithInput(t, tt.input), tt.want)
+	}
+}
+
+func TestCallErrorPosition(t *testing.T) {
+	input := "a = 1\ndescribe('x', other: a)"
+	got := setupCallTestWithInput(t, input)
+	errObj, ok := got.(*object.Error)
+	if !ok {
+		t.Fatalf("result is not *object.Error. got=%T (%+v)", got, got)
+	}
+	if isEq, failMsg := testutils.Equal(2, errObj.Line); !isEq {
+		t.Errorf("wrong error line: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal(15, errObj.Column); !isEq {
+		t.Errorf("wrong error column: %s", failMsg)
+	}
+}
+
+func setupCallTestWithInput(t *testing.T, input string) object.Object {
+	r := builtins.NewRegistry()
+	fns := []*builtins.Function{
+		{
+			Name: "describe",
+			Params: []builtins.Param{
+				{Name: "name", Types: []object.ObjectType{object.STRING_OBJ}},
+				{Name: "sep", Types: []object.ObjectType{object.STRING_OBJ}, Default: &object.String{Value: "-"}},
+				{Name: "rest", Types: builtins.Number, Variadic: true},
+			},
+			Fn: func(args *builtins.Args) (object.Object, error) {
+				rest := []string{}
+				for _, r := range args.Rest() {
+					rest = append(rest, r.Inspect())
+				}
+				ret := fmt.Sprintf("%s|%s|[%s]", args.String("name"), args.String("sep"), strings.Join(rest, " "))
+				return &object.String{Value: ret}, nil
+			},
+		},
+		{
+			Name:   "pair",
+			Params: []builtins.Param{{Name: "a"}, {Name: "b"}},
+			Fn:     func(args *builtins.Args) (object.Object, error) { return NULL, nil },
+		},
+		{
+			Name: "fails",
+			Fn:   func(args *builtins.Args) (object.Object, error) { return nil, fmt.Errorf("something went wrong") },
+		},
+	}
+	for _, fn := range fns {
+		if err := r.Register(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := lexer.New([]rune(input))
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("setupCallTestWithInput: %s", err.Error())
+	}
+	return New(l.InputRunes(), WithRegistry(r)).Eval(program, object.NewEnvironment())
+}
@@ -0,0 +1,45 @@
+package evaluator
+
+import (
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/object"
+)
+
+// computes constants whose values are pure literals, like const limit = 60 * 60, so they're evaluated once instead of on every record
+func (e *Evaluator) foldConstants(program *ast.Program) *object.Error {
+	var err *object.Error
+	ast.Inspect(program, func(node ast.Node) bool {
+		stmt, ok := node.(*ast.AssignStatement)
+		if err != nil || !ok || stmt.Keyword != "const" || !isPureLiteral(stmt.Value) {
+			return err == nil
+		}
+		val := e.Eval(stmt.Value, object.NewEnvironment())
+		if errObj, ok := val.(*object.Error); ok {
+			err = errObj
+			return false
+		}
+		e.constants[stmt] = val
+		return true
+	})
+	return err
+}
+
+// reports whether node only combines literals, so it has the same value every time it's evaluated
+func isPureLiteral(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean, *ast.NullLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if !isPureLiteral(el) {
+				return false
+			}
+		}
+		return true
+	case *ast.PrefixExpression:
+		return isPureLiteral(node.Right)
+	case *ast.InfixExpression:
+		return isPureLiteral(node.Left) && isPureLiteral(node.Right)
+	}
+	return false
+}
@@ -0,0 +1,594 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/imports"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/token"
+	"github.com/hudsn/pipelang/utils/mathutils"
+)
+
+var (
+	NULL  = &object.Null{}
+	TRUE  = &object.Boolean{Value: true}
+	FALSE = &object.Boolean{Value: false}
+)
+
+type Evaluator struct {
+	// lexed input of the program, used to turn node positions into line and column numbers for errors.
+	input []rune
+
+	builtins *builtins.Registry
+
+	// values of const declarations of pure literals, computed once by Prepare
+	constants map[*ast.AssignStatement]object.Object
+
+	loader  *imports.Loader
+	modules map[*ast.ImportStatement]*module // the files the program imports, loaded once by Prepare
+	file    string                           // path of the imported file being evaluated. empty for the program itself.
+
+	maxCallDepth int // how deeply fn and pipe calls can nest, so runaway recursion fails instead of exhausting the stack
+}
+
+const defaultMaxCallDepth = 200
+
+type Option func(e *Evaluator)
+
+// replaces the registry used to resolve function calls.
+func WithRegistry(r *builtins.Registry) Option {
+	return func(e *Evaluator) {
+		e.builtins = r
+	}
+}
+
+// loads the files a program imports. without a loader, importing a file is an error.
+func WithLoader(l *imports.Loader) Option {
+	return func(e *Evaluator) {
+		e.loader = l
+	}
+}
+
+// sets how deeply fn calls and pipe invocations can nest before the call fails. the default is 200.
+func WithMaxCallDepth(n int) Option {
+	return func(e *Evaluator) {
+		e.maxCallDepth = n
+	}
+}
+
+// the input should come from lexer.InputRunes() after parsing, since the lexer may insert characters like semicolons.
+func New(input []rune, opts ...Option) *Evaluator {
+	e := &Evaluator{
+		input:     input,
+		builtins:  builtins.Default(),
+		constants: map[*ast.AssignStatement]object.Object{},
+		modules:   map[*ast.ImportStatement]*module{},
+
+		maxCallDepth: defaultMaxCallDepth,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+
+	// statements
+	case *ast.Program:
+		return e.evalProgram(node, env)
+	case *ast.BlockStatement:
+		return e.evalBlockStatement(node, env)
+	case *ast.ExpressionStatement:
+		return e.Eval(node.Expression, env)
+	case *ast.AssignStatement:
+		return e.evalAssignStatement(node, env)
+	case *ast.FieldAssignStatement:
+		return e.evalFieldAssignStatement(node, env)
+	case *ast.PipeDefinition:
+		return e.definePipe(node, env)
+	case *ast.PipeInvocation:
+		return e.invokePipe(node, env)
+	case *ast.FunctionDefinition:
+		return e.defineFunction(node, env)
+	case *ast.SchemaStatement:
+		return NULL // schemas are read before evaluation, see the pipeline package
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, env)
+
+	// literals
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.Boolean:
+		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
+	case *ast.ArrayLiteral:
+		elements := []object.Object{}
+		for _, el := range node.Elements {
+			val := e.Eval(el, env)
+			if isError(val) {
+				return val
+			}
+			elements = append(elements, val)
+		}
+		return &object.Array{Elements: elements}
+
+	// expressions
+	case *ast.Identifier:
+		return e.evalIdentifier(node, env)
+	case *ast.PrefixExpression:
+		right := e.Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return e.evalPrefixExpression(node, right)
+	case *ast.InfixExpression:
+		return e.evalInfixExpression(node, env)
+	case *ast.IfExpression:
+		return e.evalIfExpression(node, env)
+	case *ast.TryExpression:
+		return e.evalTryExpression(node, env)
+	case *ast.DotAccess:
+		return e.evalDotAccess(node, env)
+	case *ast.CallExpression:
+		return e.evalCallExpression(node, env)
+	case *ast.PipeExpression:
+		val := e.Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return e.callFunction(node.Call, env, val)
+	case *ast.ArrowFunctionExpression:
+		return e.newArrowFunction(node, env)
+	}
+
+	if node == nil {
+		return NULL
+	}
+	return e.newError(node.Position(), "unsupported expression: %s", node.String())
+}
+
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object = NULL
+	for _, statement := range program.Statements {
+		if statement == nil {
+			continue
+		}
+		result = e.Eval(statement, env)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+// variables first assigned in a block are local to it, see evalAssignStatement
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	scope := object.NewEnclosedEnvironment(env)
+	var result object.Object = NULL
+	for _, statement := range block.Statements {
+		result = e.Eval(statement, scope)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+func (e *Evaluator) evalIdentifier(ident *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(ident.Value); ok {
+		return val
+	}
+	return e.newError(ident.Position(), "identifier not found: %s", ident.Value)
+}
+
+func (e *Evaluator) evalPrefixExpression(node *ast.PrefixExpression, right object.Object) object.Object {
+	switch node.Operator {
+	case "!":
+		switch right := right.(type) {
+		case *object.Boolean:
+			return nativeBoolToBooleanObject(!right.Value)
+		case *object.Null:
+			return TRUE
+		}
+		return e.newError(node.Position(), "invalid operand for !: %s", right.Type())
+	case "-":
+		switch right := right.(type) {
+		case *object.Integer:
+			ret, ok := mathutils.NegInt64(right.Value)
+			if !ok {
+				return e.newError(node.Position(), "integer overflow: -(%d)", right.Value)
+			}
+			return &object.Integer{Value: ret}
+		case *object.Float:
+			return &object.Float{Value: -right.Value}
+		case *object.UInteger, *object.Decimal:
+			r, _ := object.ToRat(right)
+			ret, _ := object.NumberFromRat(new(big.Rat).Neg(r))
+			return ret
+		}
+		return e.newError(node.Position(), "invalid operand for -: %s", right.Type())
+	}
+	return e.newError(node.Position(), "unknown operator: %s%s", node.Operator, right.Type())
+}
+
+func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	// short circuit logic operators so the right side is only evaluated when needed
+	switch node.Operator {
+	case "&&", "||":
+		leftBool, ok := left.(*object.Boolean)
+		if !ok {
+			return e.newError(node.Left.Position(), "invalid operand for %s: %s", node.Operator, left.Type())
+		}
+		if (node.Operator == "&&" && !leftBool.Value) || (node.Operator == "||" && leftBool.Value) {
+			return leftBool
+		}
+		right := e.Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		if _, ok := right.(*object.Boolean); !ok {
+			return e.newError(node.Right.Position(), "invalid operand for %s: %s", node.Operator, right.Type())
+		}
+		return right
+	}
+
+	right := e.Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return e.evalIntegerInfixExpression(node, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case isExactNumber(left) && isExactNumber(right):
+		return e.evalExactInfixExpression(node, left, right)
+	case isNumber(left) && isNumber(right):
+		return e.evalFloatInfixExpression(node, toFloat(left), toFloat(right))
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return e.evalStringInfixExpression(node, left.(*object.String).Value, right.(*object.String).Value)
+	case isTemporal(left) || isTemporal(right):
+		return e.evalTemporalInfixExpression(node, left, right)
+	case node.Operator == "==":
+		return nativeBoolToBooleanObject(object.Equal(left, right))
+	case node.Operator == "!=":
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	}
+	return e.newError(node.Position(), "type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
+}
+
+func (e *Evaluator) evalIntegerInfixExpression(node *ast.InfixExpression, left int64, right int64) object.Object {
+	var ret int64
+	var ok bool
+	switch node.Operator {
+	case "+":
+		ret, ok = mathutils.AddInt64(left, right)
+	case "-":
+		ret, ok = mathutils.SubInt64(left, right)
+	case "*":
+		ret, ok = mathutils.MulInt64(left, right)
+	case "/":
+		if right == 0 {
+			return e.newError(node.Position(), "division by zero")
+		}
+		ret, ok = mathutils.DivInt64(left, right)
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
+	default:
+		return e.newError(node.Position(), "unknown operator: %s %s %s", object.INTEGER_OBJ, node.Operator, object.INTEGER_OBJ)
+	}
+	if !ok {
+		return e.newError(node.Position(), "integer overflow: %d %s %d", left, node.Operator, right)
+	}
+	return &object.Integer{Value: ret}
+}
+
+func (e *Evaluator) evalFloatInfixExpression(node *ast.InfixExpression, left float64, right float64) object.Object {
+	switch node.Operator {
+	case "+":
+		return &object.Float{Value: left + right}
+	case "-":
+		return &object.Float{Value: left - right}
+	case "*":
+		return &object.Float{Value: left * right}
+	case "/":
+		if right == 0 {
+			return e.newError(node.Position(), "division by zero")
+		}
+		return &object.Float{Value: left / right}
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
+	}
+	return e.newError(node.Position(), "unknown operator: %s %s %s", object.FLOAT_OBJ, node.Operator, object.FLOAT_OBJ)
+}
+
+func (e *Evaluator) evalStringInfixExpression(node *ast.InfixExpression, left string, right string) object.Object {
+	switch node.Operator {
+	case "+":
+		return &object.String{Value: left + right}
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
+	}
+	return e.newError(node.Position(), "unknown operator: %s %s %s", object.STRING_OBJ, node.Operator, object.STRING_OBJ)
+}
+
+// handles time and duration arithmetic and comparisons:
+// time - time = duration, time +/- duration = time, duration +/- duration = duration, and duration * or / a number
+func (e *Evaluator) evalTemporalInfixExpression(node *ast.InfixExpression, left object.Object, right object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Time:
+		switch right := right.(type) {
+		case *object.Time:
+			switch node.Operator {
+			case "-":
+				return &object.Duration{Value: left.Value.Sub(right.Value)}
+			case "==":
+				return nativeBoolToBooleanObject(left.Value.Equal(right.Value))
+			case "!=":
+				return nativeBoolToBooleanObject(!left.Value.Equal(right.Value))
+			case "<":
+				return nativeBoolToBooleanObject(left.Value.Before(right.Value))
+			case "<=":
+				return nativeBoolToBooleanObject(!left.Value.After(right.Value))
+			case ">":
+				return nativeBoolToBooleanObject(left.Value.After(right.Value))
+			case ">=":
+				return nativeBoolToBooleanObject(!left.Value.Before(right.Value))
+			}
+		case *object.Duration:
+			switch node.Operator {
+			case "+":
+				return &object.Time{Value: left.Value.Add(right.Value)}
+			case "-":
+				return &object.Time{Value: left.Value.Add(-right.Value)}
+			}
+		}
+	case *object.Duration:
+		switch right := right.(type) {
+		case *object.Time:
+			if node.Operator == "+" {
+				return &object.Time{Value: right.Value.Add(left.Value)}
+			}
+		case *object.Duration:
+			switch node.Operator {
+			case "+", "-":
+				sum, ok := mathutils.AddInt64(int64(left.Value), int64(right.Value))
+				if node.Operator == "-" {
+					sum, ok = mathutils.SubInt64(int64(left.Value), int64(right.Value))
+				}
+				if !ok {
+					return e.newError(node.Position(), "duration overflow: %s %s %s", left.Value, node.Operator, right.Value)
+				}
+				return &object.Duration{Value: time.Duration(sum)}
+			case "==":
+				return nativeBoolToBooleanObject(left.Value == right.Value)
+			case "!=":
+				return nativeBoolToBooleanObject(left.Value != right.Value)
+			case "<":
+				return nativeBoolToBooleanObject(left.Value < right.Value)
+			case "<=":
+				return nativeBoolToBooleanObject(left.Value <= right.Value)
+			case ">":
+				return nativeBoolToBooleanObject(left.Value > right.Value)
+			case ">=":
+				return nativeBoolToBooleanObject(left.Value >= right.Value)
+			}
+		case *object.Integer, *object.Float:
+			factor := toFloat(right)
+			switch node.Operator {
+			case "*":
+				return e.scaleDuration(node, left, right, float64(left.Value)*factor)
+			case "/":
+				if factor == 0 {
+					return e.newError(node.Position(), "division by zero")
+				}
+				return e.scaleDuration(node, left, right, float64(left.Value)/factor)
+			}
+		}
+	}
+
+	switch node.Operator {
+	case "==":
+		return FALSE
+	case "!=":
+		return TRUE
+	}
+	return e.newError(node.Position(), "type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
+}
+
+func (e *Evaluator) evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	var isTrue bool
+	switch condition := condition.(type) {
+	case *object.Boolean:
+		isTrue = condition.Value
+	case *object.Null: // missing values are common in real data, so treat null like false.
+		isTrue = false
+	default:
+		return e.newError(node.Condition.Position(), "if condition must be a boolean. got=%s", condition.Type())
+	}
+
+	if isTrue {
+		return e.Eval(node.Consequence, env)
+	}
+	if node.Alternative != nil {
+		return e.Eval(node.Alternative, env)
+	}
+	return NULL
+}
+
+func (e *Evaluator) evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := e.Eval(node.Block, env)
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		return result
+	}
+	// the error is only bound inside the catch block
+	scope := object.NewEnclosedEnvironment(env)
+	if node.ErrorName != nil {
+		scope.Set(node.ErrorName.Value, errObj.ToValue())
+	}
+	return e.Eval(node.Catch, scope)
+}
+
+// dot access chains are nested to the right, so a.b.c is parsed as a.(b.c)
+func (e *Evaluator) evalDotAccess(node *ast.DotAccess, env *object.Environment) object.Object {
+	obj := e.Eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	return e.evalDotItem(obj, node.Item, env)
+}
+
+func (e *Evaluator) evalDotItem(obj object.Object, item ast.Expression, env *object.Environment) object.Object {
+	switch item := item.(type) {
+	case *ast.Identifier:
+		return e.getProperty(obj, item)
+	case *ast.CallExpression:
+		// a fn from an imported file, like: norm.clean(value)
+		if m, ok := obj.(*object.Module); ok {
+			return e.callModuleFunction(m, item, env)
+		}
+	case *ast.DotAccess:
+		next := e.evalDotItem(obj, item.Object, env)
+		if isError(next) {
+			return next
+		}
+		return e.evalDotItem(next, item.Item, env)
+	}
+	return e.newError(item.Position(), "invalid property access: %s", item.String())
+}
+
+func (e *Evaluator) getProperty(obj object.Object, name *ast.Identifier) object.Object {
+	switch obj := obj.(type) {
+	case *object.ErrorValue:
+		switch name.Value {
+		case "message":
+			return &object.String{Value: obj.Message}
+		case "line":
+			return &object.Integer{Value: int64(obj.Line)}
+		case "column":
+			return &object.Integer{Value: int64(obj.Column)}
+		}
+	case *object.Map:
+		if val, ok := obj.Pairs[name.Value]; ok {
+			return val
+		}
+		return NULL
+	case *object.Null:
+		return NULL
+	case *object.Module:
+		if val, ok := obj.Members[name.Value]; ok {
+			return val
+		}
+		return e.newError(name.Position(), "module %s has no member %q", obj.Name, name.Value)
+	}
+	return e.newError(name.Position(), "%s has no property %q", obj.Type(), name.Value)
+}
+
+//
+// helpers
+//
+
+func (e *Evaluator) newError(pos token.Position, format string, a ...any) *object.Error {
+	start, _ := pos.GetPosition()
+	line, col := token.LineAndColumn(e.input, max(start, 0))
+	return &object.Error{
+		Message:  fmt.Sprintf(format, a...),
+		Position: pos,
+		Line:     line,
+		Column:   col,
+		File:     e.file,
+	}
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+func isTemporal(obj object.Object) bool {
+	return obj.Type() == object.TIME_OBJ || obj.Type() == object.DURATION_OBJ
+}
+
+func isNumber(obj object.Object) bool {
+	return isExactNumber(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.UInteger:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	case *object.Decimal:
+		f, _ := obj.Value.Float64()
+		return f
+	}
+	return 0
+}
+
+// scaled is the product or quotient of a duration and a number, which has to fit a duration again
+func (e *Evaluator) scaleDuration(node *ast.InfixExpression, left *object.Duration, right object.Object, scaled float64) object.Object {
+	if !mathutils.FloatFitsInt64(scaled) {
+		return e.newError(node.Position(), "duration overflow: %s %s %s", left.Value, node.Operator, right.Inspect())
+	}
+	return &object.Duration{Value: time.Duration(scaled)}
+}
+
+func nativeBoolToBooleanObject(val bool) *object.Boolean {
+	if val {
+		return TRUE
+	}
+	return FALSE
+}
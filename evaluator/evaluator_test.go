@@ -0,0 +1,513 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"5", 5},
+		{"-5", -5},
+		{"5 + 5 * 2", 15},
+		{"(5 + 5) * 2", 20},
+		{"20 / 3", 6},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, setupEvalWithInput(t, tt.input), tt.want)
+	}
+}
+
+func TestFloatExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"1.5", 1.5},
+		{"1.5 + 1", 2.5},
+		{"-2.5 * 2", -5},
+	}
+	for _, tt := range tests {
+		testFloatObject(t, setupEvalWithInput(t, tt.input), tt.want)
+	}
+}
+
+func TestBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"true", true},
+		{"!true", false},
+		{"1 < 2", true},
+		{"1.5 >= 2", false},
+		{"'a' == 'a'", true},
+		{"'a' != 'b'", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"null == null", true},
+		{"[1] == ['1']", false},
+		{"[['a, b']] == [['a', 'b']]", false},
+		{"[1, [2.0, 'x']] == [1.0, [2, 'x']]", true},
+		{"[1, 2] != [1]", true},
+		{"parse_json('{\"a\": [1, {\"b\": null}]}') == parse_json('{\"a\": [1, {\"b\": null}]}')", true},
+		{"parse_json('{\"a\": 1}') == parse_json('{\"a\": \"1\"}')", false},
+		{"parse_json('{\"a\": 1}') == parse_json('{\"b\": 1}')", false},
+	}
+	for _, tt := range tests {
+		testBooleanObject(t, setupEvalWithInput(t, tt.input), tt.want)
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{"if true { 1 }", 1},
+		{"if false { 1 }", nil},
+		{"if 1 > 2 { 1 } else { 2 }", 2},
+		{"if false { 1 } else if true { 3 } else { 2 }", 3},
+		{"if null { 1 } else { 2 }", 2},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if tt.want == nil {
+			testNullObject(t, got)
+			continue
+		}
+		testIntegerObject(t, got, int64(tt.want.(int)))
+	}
+}
+
+func TestAssignStatement(t *testing.T) {
+	input := `a = 5
+	b = a * 2
+	b + 1`
+	testIntegerObject(t, setupEvalWithInput(t, input), 11)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		line    int
+		column  int
+	}{
+		{"5 + true", "type mismatch: INTEGER + BOOLEAN", 1, 1},
+		{"a = 1\nb = a / 0", "division by zero", 2, 5},
+		{"missing", "identifier not found: missing", 1, 1},
+		{"if 5 { 1 }", "if condition must be a boolean. got=INTEGER", 1, 4},
+		{"-'a'", "invalid operand for -: STRING", 1, 1},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		errObj, ok := got.(*object.Error)
+		if !ok {
+			t.Fatalf("result is not *object.Error. got=%T (%+v)", got, got)
+		}
+		if isEq, failMsg := testutils.Equal(tt.message, errObj.Message); !isEq {
+			t.Errorf("wrong error message: %s", failMsg)
+		}
+		if isEq, failMsg := testutils.Equal(tt.line, errObj.Line); !isEq {
+			t.Errorf("wrong error line: %s", failMsg)
+		}
+		if isEq, failMsg := testutils.Equal(tt.column, errObj.Column); !isEq {
+			t.Errorf("wrong error column: %s", failMsg)
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{"x: float = 1.5\nx", "1.5"},
+		{"x: int = 1\nx = 2\nx", "2"},
+		{"ids: array<int> = [1, 2]\nids", "[1, 2]"},
+		{"x: any = 1\nx = 'a'\nx", "a"},
+		{"x: float = 1", "runtime error at 1:12:\n\tx is declared as float. got=INTEGER"},
+		{"x: int = 1\nx = 'a'", "runtime error at 2:5:\n\tx is declared as int. got=STRING"},
+		{"ids: array<int> = [1, 'a']", "runtime error at 1:19:\n\tids is declared as array<int>. got=STRING at [1]"},
+		{"ids: array<array<int>> = [[1], [2, 'a']]", "runtime error at 1:26:\n\tids is declared as array<array<int>>. got=STRING at [1][1]"},
+		{"m: map<int> = parse_json('{\"b\": \"x\", \"a\": 1, \"c\": true}')", "runtime error at 1:15:\n\tm is declared as map<int>. got=STRING at [\"b\"]"},
+		{"x: integer = 1", "runtime error at 1:4:\n\tunknown type \"integer\". expected one of any, array, bool, decimal, duration, float, function, int, ip, map, number, string, time"},
+		{"x: int<string> = 1", "runtime error at 1:4:\n\ttype int does not take an element type"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestPipeInvocation(t *testing.T) {
+	def := "pipe enrich(threshold: int, tags: array<string>) {\n  if threshold > 10 { 1 / 0 }\n}\n"
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{def + "| enrich(1, ['a'])", "null"},
+		{def + "| enrich(tags: [], threshold: 1)", "null"},
+		{def + "enrich", "pipe enrich(threshold: int, tags: array<string>)"},
+		{def + "| enrich(20, ['a'])", "runtime error at 2:23:\n\tdivision by zero"},
+		{def + "| enrich('1', ['a'])", "runtime error at 4:10:\n\targument \"threshold\" for enrich must be int. got=STRING"},
+		{def + "| enrich(1, [1])", "runtime error at 4:13:\n\targument \"tags\" for enrich must be array<string>. got=INTEGER at [0]"},
+		{def + "| enrich(1)", "runtime error at 4:3:\n\tmissing required argument \"tags\" for enrich"},
+		{def + "| enrich(1, [], 2)", "runtime error at 4:17:\n\ttoo many arguments for enrich: want at most 2"},
+		{def + "| enrich(1, [], size: 2)", "runtime error at 4:17:\n\tunknown argument \"size\" for enrich"},
+		{"| missing()", "runtime error at 1:3:\n\tpipe not found: missing"},
+		{"pipe p(n) { n }\nn", "runtime error at 2:1:\n\tidentifier not found: n"},
+		{"pipe p() { | p() }\n| p()", "runtime error at 1:14:\n\tmaximum call depth of 200 exceeded calling p"},
+		{"pipe p() { | q() }\npipe q() { | p() }\n| p()", "runtime error at 2:14:\n\tmaximum call depth of 200 exceeded calling p"},
+		// the limit counts fn calls and pipe invocations together
+		{"fn f() { g() }\npipe p() { f() }\nfn g() { | p() }\ng()", "runtime error at 2:12:\n\tmaximum call depth of 200 exceeded calling f"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestFunctionDefinition(t *testing.T) {
+	fact := "fn fact(n: int) {\n  if n < 2 { 1 } else { n * fact(n - 1) }\n}\n"
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{fact + "fact(5)", "120"},
+		{fact + "fact(n: 10)", "3628800"},
+		{fact + "5 | fact()", "120"},
+		{fact + "fact", "fn fact(n: int)"},
+		{fact + "fact('5')", "runtime error at 4:6:\n\targument \"n\" for fact must be int. got=STRING"},
+		{fact + "fact()", "runtime error at 4:1:\n\tmissing required argument \"n\" for fact"},
+		{fact + "fact(1, 2)", "runtime error at 4:9:\n\ttoo many arguments for fact: want at most 1"},
+		{"fn host(h, suffix) { lower(h) + suffix }\nhost(suffix: '.local', h: 'WEB')", "web.local"},
+		// functions close over the environment they're defined in
+		{"domain = '.corp'\nfn host(h) { h + domain }\ndomain = '.lab'\nhost('a')", "a.lab"},
+		{"fn f() { x = 1 }\nf()\nx", "runtime error at 3:1:\n\tidentifier not found: x"},
+		{"fn loop(n) { loop(n + 1) }\nloop(0)", "runtime error at 1:14:\n\tmaximum call depth of 200 exceeded calling loop"},
+		{"fn upper(s) { s }", "runtime error at 1:4:\n\tcannot define fn upper: a builtin function has that name"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestDefaultAndVariadicParams(t *testing.T) {
+	tag := "$dest.a = 1\npipe tag(name, value = 'true') { $dest.tag = name + '=' + value }\n"
+	concat := "fn concat(sep: string = '', ...parts: string) { join(parts, sep) }\n"
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{tag + "| tag('a')\n$dest.tag", "a=true"},
+		{tag + "| tag('a', value: 'no')\n$dest.tag", "a=no"},
+		{tag + "| tag(value: 'no')", "runtime error at 3:3:\n\tmissing required argument \"name\" for tag"},
+		{concat + "concat('-', 'a', 'b', 'c')", "a-b-c"},
+		{concat + "concat()", ""},
+		{concat + "concat(sep: ',')", ""},
+		{concat + "'x' | concat('a', 'b')", "axb"},
+		{concat + "concat(',', 'a', 1)", "runtime error at 2:18:\n\targument \"parts\" for concat must be string. got=INTEGER"},
+		{concat + "concat(parts: 'a')", "runtime error at 2:8:\n\tunknown argument \"parts\" for concat"},
+		{"fn count(...values) { len(values) }\ncount(1, [2], 'x')", "3"},
+		// defaults are evaluated once, in the environment the definition runs in
+		{"const suffix = '.local'\nfn host(h, s = suffix) { h + s }\nhost('a')", "a.local"},
+		{"fn f(n: int = 'a') { n }", "runtime error at 1:15:\n\tdefault value for \"n\" must be int. got=STRING"},
+		{"fn f(a, b = 1) { a + b }\nf(b: 2)", "runtime error at 2:1:\n\tmissing required argument \"a\" for f"},
+		{"fn f(a = 1) { a }\nf(1, 2)", "runtime error at 2:6:\n\ttoo many arguments for f: want at most 1"},
+		{"fn f(...a) { a }\nf", "fn f(...a)"},
+		{"fn f(a: string = 'x', b = 2) { a }\nf", "fn f(a: string = \"x\", b = 2)"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	input := "fn down(n) { if n == 0 { 0 } else { down(n - 1) } }\ndown(5)"
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := New(l.InputRunes(), WithMaxCallDepth(5)).Eval(program, object.NewEnvironment())
+	if isEq, failMsg := testutils.Equal("runtime error at 1:37:\n\tmaximum call depth of 5 exceeded calling down", got.Inspect()); !isEq {
+		t.Errorf("wrong result: %s", failMsg)
+	}
+	got = New(l.InputRunes(), WithMaxCallDepth(6)).Eval(program, object.NewEnvironment())
+	if isEq, failMsg := testutils.Equal("0", got.Inspect()); !isEq {
+		t.Errorf("wrong result: %s", failMsg)
+	}
+}
+
+func TestFieldAssignStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{"$dest.a = 1\n$dest", `{"a": 1}`},
+		{"$dest.user.name = 'a'\n$dest.user.id = 2\n$dest", `{"user": {"id": 2, "name": a}}`},
+		{"$dest = 'a'\n$dest.b = 1", "runtime error at 2:7:\n\tcannot set field \"b\" on STRING"},
+		{"$dest.a = 1\n$dest.a.b = 2", "runtime error at 2:9:\n\tcannot set field \"b\" on INTEGER"},
+		{"$dest.a = 1 / 0", "runtime error at 1:11:\n\tdivision by zero"},
+		{"$dest.a = 1\npipe tag(v) { $dest.tag = v }\n| tag('x')\n$dest.tag", "x"},
+		// maps are copied, so setting a field of $dest doesn't change a map it was copied from
+		{"x = parse_json('{\"a\": 1}')\n$dest = x\n$dest.a = 2\nx.a", "1"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestScoping(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		// assigning to an outer variable from a block updates it
+		{"x = 1\nif true { x = 2 }\nx", "2"},
+		{"x = 1\ntry { x = 2\n1 / 0 } catch { x = x + 1 }\nx", "3"},
+		// variables first assigned in a block are local to it
+		{"if true { y = 2 }\ny", "runtime error at 2:1:\n\tidentifier not found: y"},
+		{"try { 1 / 0 } catch err { err.message }\nerr", "runtime error at 2:1:\n\tidentifier not found: err"},
+		// annotations declare a new variable, shadowing the outer one inside the block
+		{"x = 'a'\nif true { x: int = 1\nx = x + 1 }\nx", "a"},
+		{"x: int = 1\nif true { x = 'a' }", "runtime error at 2:15:\n\tx is declared as int. got=STRING"},
+		// closures see the variables of the scope they're created in, including later changes
+		{"n = 1\nf = e ~> e + n\nn = 10\nsort([3, 1], by: f)", "[1, 3]"},
+		{"n = 1\npipe bump() { n = n + 1 }\n| bump()\n| bump()\nn", "3"},
+		// params shadow outer variables without changing them
+		{"v = 'outer'\npipe p(v) { v = 'inner' }\n| p('x')\nv", "outer"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{"const limit = 10\nlimit + 1", "11"},
+		{"const limit = 10\nlimit = 20", "runtime error at 2:1:\n\tcannot assign to constant limit"},
+		{"const limit = 10\nif true { limit = 20 }", "runtime error at 2:11:\n\tcannot assign to constant limit"},
+		{"const limit = 10\nlet limit = 20", "runtime error at 2:5:\n\tcannot redeclare constant limit"},
+		{"const limit = 10\nif true { const limit = 20\nlimit }", "20"},
+		{"const limit: string = 10", "runtime error at 1:23:\n\tlimit is declared as string. got=INTEGER"},
+		{"let x = 'a'\nif true { let x = 1 }\nx", "a"},
+		{"x: int = 1\nlet x = 'a'\nx = 2.5\nx", "2.5"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestFoldConstants(t *testing.T) {
+	input := "const hour = 60 * 60\nconst names = ['a', upper('b')]\nconst bad = 1 / 0"
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(l.InputRunes())
+	err = e.Prepare(program)
+	if err == nil {
+		t.Fatal("expected an error for a constant that can't be computed")
+	}
+	if isEq, failMsg := testutils.Equal("runtime error at 3:13:\n\tdivision by zero", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+
+	program.Statements = program.Statements[:2]
+	e = New(l.InputRunes())
+	if err := e.Prepare(program); err != nil {
+		t.Fatal(err)
+	}
+	// names calls a builtin, so it's evaluated when the declaration runs instead
+	if isEq, failMsg := testutils.Equal(1, len(e.constants)); !isEq {
+		t.Fatalf("wrong number of folded constants: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal("3600", e.constants[program.Statements[0].(*ast.AssignStatement)].Inspect()); !isEq {
+		t.Errorf("wrong folded value: %s", failMsg)
+	}
+}
+
+func TestTryCatchExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{"try { 1 } catch { 2 }", 1},
+		{"try { 1 / 0 } catch { 2 }", 2},
+		{"try { 1 / 0 } catch err { err.message }", "division by zero"},
+		{"try { a = 1\n a + 'x' } catch err { err.line }", 2},
+		{"x = try { missing } catch err { null }\n x == null", true},
+		{"try { try { 1 / 0 } catch { missing } } catch outer { outer.message }", "identifier not found: missing"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		switch want := tt.want.(type) {
+		case int:
+			testIntegerObject(t, got, int64(want))
+		case string:
+			testStringObject(t, got, want)
+		case bool:
+			testBooleanObject(t, got, want)
+		}
+	}
+}
+
+func TestCaughtErrorIsValue(t *testing.T) {
+	input := `e = try { 1 / 0 } catch err { err }
+	e`
+	got := setupEvalWithInput(t, input)
+	errVal, ok := got.(*object.ErrorValue)
+	if !ok {
+		t.Fatalf("result is not *object.ErrorValue. got=%T (%+v)", got, got)
+	}
+	if isEq, failMsg := testutils.Equal("division by zero", errVal.Message); !isEq {
+		t.Errorf("wrong error message: %s", failMsg)
+	}
+}
+
+func TestArrowFunction(t *testing.T) {
+	got := setupEvalWithInput(t, "offset = 1\nf = n ~> n + offset\nf")
+	fn, ok := got.(*object.Function)
+	if !ok {
+		t.Fatalf("result is not *object.Function. got=%T (%s)", got, got.Inspect())
+	}
+	if isEq, failMsg := testutils.Equal("n ~> (n + offset)", fn.Inspect()); !isEq {
+		t.Errorf("wrong inspect value: %s", failMsg)
+	}
+	testIntegerObject(t, fn.Call(&object.Integer{Value: 2}), 3)
+
+	// the param only exists inside the function
+	want := "identifier not found: n"
+	if errObj, ok := setupEvalWithInput(t, "f = n ~> n\nn").(*object.Error); !ok || errObj.Message != want {
+		t.Errorf("expected error %q", want)
+	}
+}
+
+// values decoded from JSON keep their exact type, and mixing them with integers stays exact
+func TestExactNumberExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // inspected result
+	}{
+		{`parse_json('18446744073709551615') - 1`, "18446744073709551614"},
+		{`parse_json('1.00000000000000000001') + 1`, "2.00000000000000000001"},
+		{`parse_json('1.00000000000000000001') * 2`, "2.00000000000000000002"},
+		{`parse_json('18446744073709551615') / 2`, "9223372036854775807"},
+		{`parse_json('18446744073709551616') - parse_json('18446744073709551615')`, "1"},
+		{`parse_json('1.00000000000000000001') / 3`, "0.3333333333333333"},
+		{`parse_json('18446744073709551615') > 9223372036854775807`, "true"},
+		{`parse_json('18446744073709551615') == parse_json('18446744073709551615')`, "true"},
+		{`-parse_json('18446744073709551615')`, "-18446744073709551615"},
+		{`parse_json('18446744073709551615') + 0.5`, "18446744073709552000"},
+	}
+	for _, tt := range tests {
+		got := setupEvalWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, got.Inspect()); !isEq {
+			t.Errorf("%s: wrong result: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, want int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not *object.Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, result.Value); !isEq {
+		t.Errorf("wrong integer value: %s", failMsg)
+		return false
+	}
+	return true
+}
+
+func testFloatObject(t *testing.T, obj object.Object, want float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not *object.Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, result.Value); !isEq {
+		t.Errorf("wrong float value: %s", failMsg)
+		return false
+	}
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, want string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not *object.String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, result.Value); !isEq {
+		t.Errorf("wrong string value: %s", failMsg)
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, want bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not *object.Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if isEq, failMsg := testutils.Equal(want, result.Value); !isEq {
+		t.Errorf("wrong boolean value: %s", failMsg)
+		return false
+	}
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	if obj != NULL {
+		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
+		return false
+	}
+	return true
+}
+
+func setupEvalWithInput(t *testing.T, input string) object.Object {
+	l := lexer.New([]rune(input))
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("setupEvalWithInput: %s", err.Error())
+	}
+	return New(l.InputRunes()).Eval(program, object.NewEnvironment())
+}
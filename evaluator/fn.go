@@ -0,0 +1,53 @@
+package evaluator
+
+import (
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/object"
+)
+
+// functions close over the environment they're defined in like pipes do, but return the value of their body.
+// a fn can't take the name of a builtin, so a call always means the same thing to the checker and the evaluator.
+func (e *Evaluator) defineFunction(node *ast.FunctionDefinition, env *object.Environment) object.Object {
+	if _, ok := e.builtins.Lookup(node.Name.Value); ok {
+		return e.newError(node.Name.Position(), "cannot define fn %s: a builtin function has that name", node.Name.Value)
+	}
+	params, errObj := e.resolveParams(node.Params, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	env.Set(node.Name.Value, &object.Fn{
+		Name:   node.Name.Value,
+		Params: params,
+		Call: func(args map[string]object.Object, depth int) object.Object {
+			scope := object.NewCallEnvironment(env, depth)
+			for name, val := range args {
+				scope.Set(name, val)
+			}
+			return e.Eval(node.Body, scope)
+		},
+	})
+	return NULL
+}
+
+func (e *Evaluator) callDefinedFunction(node *ast.CallExpression, fn *object.Fn, env *object.Environment, leading []object.Object) object.Object {
+	depth := env.CallDepth() + 1
+	if depth > e.maxCallDepth {
+		return e.newError(node.Position(), "maximum call depth of %d exceeded calling %s", e.maxCallDepth, fn.Name)
+	}
+	args, errObj := e.bindDefinedArguments(node, fn.Name, fn.Params, env, leading)
+	if errObj != nil {
+		return errObj
+	}
+	return fn.Call(args, depth)
+}
+
+// args are evaluated in the caller's environment, and the body runs in the environment of the file that defines the fn
+func (e *Evaluator) callModuleFunction(m *object.Module, node *ast.CallExpression, env *object.Environment) object.Object {
+	member, ok := m.Members[node.Name.Value]
+	fn, isFn := member.(*object.Fn)
+	if !ok || !isFn {
+		return e.newError(node.Name.Position(), "fn not found: %s.%s", m.Name, node.Name.Value)
+	}
+	return e.callDefinedFunction(node, fn, env, nil)
+}
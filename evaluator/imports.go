@@ -0,0 +1,74 @@
+package evaluator
+
+import (
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/imports"
+	"github.com/hudsn/pipelang/object"
+)
+
+// an imported file, with an evaluator of its own so errors in its code point at its own lines
+type module struct {
+	path      string
+	program   *ast.Program
+	evaluator *Evaluator
+}
+
+// loads and prepares each file imported at the top level of the program. imports anywhere else are reported when they're evaluated.
+func (e *Evaluator) prepareImports(program *ast.Program) error {
+	for _, statement := range program.Statements {
+		node, ok := statement.(*ast.ImportStatement)
+		if !ok {
+			continue
+		}
+		if e.loader == nil {
+			return e.newError(node.Position(), "cannot import %q: no import resolver is configured", node.Path.Value)
+		}
+		loaded, err := e.loader.Load(node.Path.Value)
+		if err != nil {
+			return err
+		}
+		child := e.forModule(loaded)
+		if err := child.Prepare(loaded.Program); err != nil {
+			return err
+		}
+		e.modules[node] = &module{path: loaded.Path, program: loaded.Program, evaluator: child}
+	}
+	return nil
+}
+
+func (e *Evaluator) forModule(m *imports.Module) *Evaluator {
+	return &Evaluator{
+		input:     m.Input,
+		builtins:  e.builtins,
+		constants: map[*ast.AssignStatement]object.Object{},
+		loader:    e.loader,
+		modules:   map[*ast.ImportStatement]*module{},
+		file:      m.Path,
+
+		maxCallDepth: e.maxCallDepth,
+	}
+}
+
+// evaluates the imported file in a scope enclosed by env, so its pipes can still read and set $src and $dest,
+// and binds its pipes and constants to the alias as a constant.
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	m, ok := e.modules[node]
+	if !ok {
+		if e.loader == nil {
+			return e.newError(node.Position(), "cannot import %q: no import resolver is configured", node.Path.Value)
+		}
+		return e.newError(node.Position(), "imports must be at the top level of a file")
+	}
+
+	scope := object.NewEnclosedEnvironment(env)
+	result := m.evaluator.Eval(m.program, scope)
+	if isError(result) {
+		return result
+	}
+	members := map[string]object.Object{}
+	for _, name := range scope.Names() {
+		members[name], _ = scope.Get(name)
+	}
+	env.SetConstant(node.Alias.Value, &object.Module{Name: node.Alias.Value, Path: m.path, Members: members})
+	return NULL
+}
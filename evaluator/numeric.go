@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"math/big"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/object"
+)
+
+// uintegers and decimals only hold values exactly, so arithmetic between them and integers is done with exact rationals.
+// results are narrowed back down to an integer or uinteger when they fit in one.
+func (e *Evaluator) evalExactInfixExpression(node *ast.InfixExpression, left object.Object, right object.Object) object.Object {
+	l, _ := object.ToRat(left)
+	r, _ := object.ToRat(right)
+
+	var ret *big.Rat
+	switch node.Operator {
+	case "+":
+		ret = new(big.Rat).Add(l, r)
+	case "-":
+		ret = new(big.Rat).Sub(l, r)
+	case "*":
+		ret = new(big.Rat).Mul(l, r)
+	case "/":
+		if r.Sign() == 0 {
+			return e.newError(node.Position(), "division by zero")
+		}
+		// dividing whole numbers truncates, the same as integer division
+		if left.Type() != object.DECIMAL_OBJ && right.Type() != object.DECIMAL_OBJ {
+			ret = new(big.Rat).SetInt(new(big.Int).Quo(l.Num(), r.Num()))
+			break
+		}
+		ret = new(big.Rat).Quo(l, r)
+	case "==":
+		return nativeBoolToBooleanObject(l.Cmp(r) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(l.Cmp(r) != 0)
+	case "<":
+		return nativeBoolToBooleanObject(l.Cmp(r) < 0)
+	case "<=":
+		return nativeBoolToBooleanObject(l.Cmp(r) <= 0)
+	case ">":
+		return nativeBoolToBooleanObject(l.Cmp(r) > 0)
+	case ">=":
+		return nativeBoolToBooleanObject(l.Cmp(r) >= 0)
+	default:
+		return e.newError(node.Position(), "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
+	}
+
+	if num, ok := object.NumberFromRat(ret); ok {
+		return num
+	}
+	// quotients like 1/3 have no exact decimal form
+	f, _ := ret.Float64()
+	return &object.Float{Value: f}
+}
+
+func isExactNumber(obj object.Object) bool {
+	switch obj.Type() {
+	case object.INTEGER_OBJ, object.UINTEGER_OBJ, object.DECIMAL_OBJ:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package evaluator
+
+import (
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/object"
+)
+
+// assigning to a variable of an enclosing scope updates it, and assigning to a new name creates it in the current scope.
+// a declaration like let x = 1, or an annotation like x: int = 1, always creates a new variable in the current scope, shadowing any outer one with the same name.
+// annotated variables keep their declared type, so every later assignment to them is checked too, and constants can't be assigned again at all.
+func (e *Evaluator) evalAssignStatement(node *ast.AssignStatement, env *object.Environment) object.Object {
+	name := node.Name.Value
+	val, folded := e.constants[node]
+	if !folded {
+		val = e.Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+	}
+
+	if node.Keyword == "" && node.Type == nil {
+		if env.IsConstant(name) {
+			return e.newError(node.Name.Position(), "cannot assign to constant %s", name)
+		}
+		if spec, ok := env.DeclaredType(name); ok {
+			if m := spec.Check(val); m != nil {
+				return e.errDeclaredType(node, spec, m)
+			}
+		}
+		env.Update(name, val)
+		return val
+	}
+
+	if env.IsLocalConstant(name) {
+		return e.newError(node.Name.Position(), "cannot redeclare constant %s", name)
+	}
+	var spec *object.TypeSpec
+	if node.Type != nil {
+		var errObj *object.Error
+		if spec, errObj = e.resolveType(node.Type); errObj != nil {
+			return errObj
+		}
+		if m := spec.Check(val); m != nil {
+			return e.errDeclaredType(node, spec, m)
+		}
+	}
+	if node.Keyword == "const" {
+		env.SetConstant(name, val)
+	} else {
+		env.Set(name, val)
+	}
+	env.Declare(name, spec)
+	return val
+}
+
+func (e *Evaluator) errDeclaredType(node *ast.AssignStatement, spec *object.TypeSpec, m *object.Mismatch) *object.Error {
+	return e.newError(node.Value.Position(), "%s is declared as %s. got=%s", node.Name.Value, spec.String(), m)
+}
+
+// maps along the path are copied rather than changed in place, since the same map may also be held by other variables, like after $dest = $src
+func (e *Evaluator) evalFieldAssignStatement(node *ast.FieldAssignStatement, env *object.Environment) object.Object {
+	val := e.Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+	current, _ := env.Get(node.Object.Value)
+	updated, errObj := e.setField(current, node.Path, val)
+	if errObj != nil {
+		return errObj
+	}
+	env.Update(node.Object.Value, updated) // so a pipe's changes to $dest outlive its own scope
+	return val
+}
+
+// returns a copy of obj with the field at path set to val. a missing or null obj is treated as an empty map.
+func (e *Evaluator) setField(obj object.Object, path []*ast.Identifier, val object.Object) (object.Object, *object.Error) {
+	ret := object.NewMap()
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+	case *object.Map:
+		for k, v := range obj.Pairs {
+			ret.Pairs[k] = v
+		}
+	default:
+		return nil, e.newError(path[0].Position(), "cannot set field %q on %s", path[0].Value, obj.Type())
+	}
+
+	name := path[0]
+	if len(path) == 1 {
+		ret.Pairs[name.Value] = val
+		return ret, nil
+	}
+	nested, errObj := e.setField(ret.Pairs[name.Value], path[1:], val)
+	if errObj != nil {
+		return nil, errObj
+	}
+	ret.Pairs[name.Value] = nested
+	return ret, nil
+}
+
+func (e *Evaluator) resolveType(node *ast.TypeAnnotation) (*object.TypeSpec, *object.Error) {
+	var elem *object.TypeSpec
+	if node.Elem != nil {
+		var errObj *object.Error
+		elem, errObj = e.resolveType(node.Elem)
+		if errObj != nil {
+			return nil, errObj
+		}
+	}
+	spec, err := object.NewTypeSpec(node.Name, elem)
+	if err != nil {
+		return nil, e.newError(node.Position(), "%s", err.Error())
+	}
+	return spec, nil
+}
+
+// pipes close over the environment they're defined in, and bind their params in a scope of their own.
+// like fn calls, pipe invocations count toward the call depth limit, so a pipe that invokes itself forever fails instead of exhausting the stack.
+func (e *Evaluator) definePipe(node *ast.PipeDefinition, env *object.Environment) object.Object {
+	params, errObj := e.resolveParams(node.Params, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	env.Set(node.Name.Value, &object.Pipe{
+		Name:   node.Name.Value,
+		Params: params,
+		Call: func(args map[string]object.Object, depth int) object.Object {
+			scope := object.NewCallEnvironment(env, depth)
+			for name, val := range args {
+				scope.Set(name, val)
+			}
+			result := e.Eval(node.Body, scope)
+			if isError(result) {
+				return result
+			}
+			return NULL
+		},
+	})
+	return NULL
+}
+
+func (e *Evaluator) invokePipe(node *ast.PipeInvocation, env *object.Environment) object.Object {
+	obj, ok := env.Get(node.Call.Name.Value)
+	name := node.Call.Name.Value
+	if node.Module != nil {
+		// a pipe from an imported file, like: | norm.clean()
+		mod, isModule := env.Get(node.Module.Value)
+		m, _ := mod.(*object.Module)
+		if !isModule || m == nil {
+			return e.newError(node.Module.Position(), "module not found: %s", node.Module.Value)
+		}
+		obj, ok = m.Members[name]
+		name = node.Module.Value + "." + name
+	}
+	pipe, isPipe := obj.(*object.Pipe)
+	if !ok || !isPipe {
+		return e.newError(node.Call.Name.Position(), "pipe not found: %s", name)
+	}
+	depth := env.CallDepth() + 1
+	if depth > e.maxCallDepth {
+		return e.newError(node.Call.Position(), "maximum call depth of %d exceeded calling %s", e.maxCallDepth, name)
+	}
+	args, errObj := e.bindDefinedArguments(node.Call, pipe.Name, pipe.Params, env, nil)
+	if errObj != nil {
+		return errObj
+	}
+	return pipe.Call(args, depth)
+}
+
+// default values are evaluated once, when the pipe or fn is defined, in the environment it's defined in
+func (e *Evaluator) resolveParams(nodes []*ast.Parameter, env *object.Environment) ([]object.Param, *object.Error) {
+	params := []object.Param{}
+	for _, p := range nodes {
+		param := object.Param{Name: p.Name.Value, Variadic: p.Variadic}
+		if p.Type != nil {
+			spec, errObj := e.resolveType(p.Type)
+			if errObj != nil {
+				return nil, errObj
+			}
+			param.Type = spec
+		}
+		if p.Default != nil {
+			val := e.Eval(p.Default, env)
+			if errObj, ok := val.(*object.Error); ok {
+				return nil, errObj
+			}
+			if m := param.Check(val); m != nil {
+				return nil, e.newError(p.Default.Position(), "default value for %q must be %s. got=%s", param.Name, param.Type.String(), m)
+			}
+			param.Default = val
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// matches call arguments to the params of a pipe or fn the same way builtin calls are bound, checking each value against its param's annotation.
+// the args a variadic param collects are bound to it as an array, and defaults fill the params no arg did.
+// leading values are bound as positional args ahead of the call's own args, like the piped value in: value | normalize()
+func (e *Evaluator) bindDefinedArguments(node *ast.CallExpression, name string, params []object.Param, env *object.Environment, leading []object.Object) (map[string]object.Object, *object.Error) {
+	values := map[string]object.Object{}
+	rest := []object.Object{}
+	binder := object.NewBinder(name, object.ParamSpecs(params))
+
+	bind := func(param object.Param, val object.Object) {
+		if param.Variadic {
+			rest = append(rest, val)
+		} else {
+			values[param.Name] = val
+		}
+	}
+
+	for _, val := range leading {
+		idx, err := binder.Positional()
+		if err != nil {
+			return nil, e.newError(node.Position(), "cannot pass %s into %s", val.Type(), name)
+		}
+		param := params[idx]
+		if m := param.Check(val); m != nil {
+			return nil, e.newError(node.Position(), "argument %q for %s must be %s. got=%s", param.Name, name, param.Type.String(), m)
+		}
+		bind(param, val)
+	}
+
+	for _, arg := range node.Arguments {
+		val := e.Eval(arg.Value, env)
+		if errObj, ok := val.(*object.Error); ok {
+			return nil, errObj
+		}
+		idx, errObj := e.bindArgument(binder, arg)
+		if errObj != nil {
+			return nil, errObj
+		}
+		param := params[idx]
+		if m := param.Check(val); m != nil {
+			return nil, e.newError(arg.Value.Position(), "argument %q for %s must be %s. got=%s", param.Name, name, param.Type.String(), m)
+		}
+		bind(param, val)
+	}
+
+	if missing := binder.Missing(); len(missing) > 0 {
+		return nil, e.newError(node.Position(), "missing required argument %q for %s", missing[0], name)
+	}
+	for _, param := range params {
+		if _, found := values[param.Name]; found {
+			continue
+		}
+		if param.Variadic {
+			values[param.Name] = &object.Array{Elements: rest}
+		} else {
+			values[param.Name] = param.Default
+		}
+	}
+	return values, nil
+}
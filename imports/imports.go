@@ -0,0 +1,125 @@
+// Package imports loads the files a program imports, like import "common/normalize.pl" as norm.
+// the host decides where files come from by supplying a Resolver.
+package imports
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/token"
+)
+
+// Resolver returns the source of an imported file. paths are slash separated and relative to the root of the resolver.
+type Resolver interface {
+	Resolve(path string) ([]byte, error)
+}
+
+type fsResolver struct {
+	fsys fs.FS
+}
+
+// FS resolves imports from a file system, like os.DirFS("pipelines") or an embed.FS.
+func FS(fsys fs.FS) Resolver {
+	return fsResolver{fsys: fsys}
+}
+
+func (r fsResolver) Resolve(path string) ([]byte, error) {
+	return fs.ReadFile(r.fsys, path)
+}
+
+// Map resolves imports from sources held in memory, keyed by path.
+type Map map[string]string
+
+func (m Map) Resolve(path string) ([]byte, error) {
+	src, ok := m[path]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(src), nil
+}
+
+// Module is a parsed file. it only has pipe and fn definitions, constants and imports of its own.
+type Module struct {
+	Path    string
+	Program *ast.Program
+	// lexed input of the file, used to turn node positions into line and column numbers for errors.
+	Input []rune
+}
+
+// Loader parses each imported file once, no matter how many files import it.
+type Loader struct {
+	resolver Resolver
+	modules  map[string]*Module
+	order    []*Module
+}
+
+func NewLoader(r Resolver) *Loader {
+	return &Loader{resolver: r, modules: map[string]*Module{}}
+}
+
+// Load returns the parsed file at path, loading every file it imports too. an import cycle is an error.
+func (l *Loader) Load(path string) (*Module, error) {
+	return l.load(path, nil)
+}
+
+// Modules returns every file loaded so far, each after the files it imports.
+func (l *Loader) Modules() []*Module {
+	return l.order
+}
+
+func (l *Loader) load(importPath string, stack []string) (*Module, error) {
+	importPath = path.Clean(importPath)
+	for idx, p := range stack {
+		if p == importPath {
+			cycle := append(slices.Clone(stack[idx:]), importPath)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if mod, ok := l.modules[importPath]; ok {
+		return mod, nil
+	}
+
+	src, err := l.resolver.Resolve(importPath)
+	if err != nil {
+		return nil, fmt.Errorf("import %q: %w", importPath, err)
+	}
+	lex := lexer.New([]rune(string(src)))
+	program, err := parser.New(lex).ParseProgram()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", importPath, err)
+	}
+	mod := &Module{Path: importPath, Program: program, Input: lex.InputRunes()}
+
+	stack = append(stack, importPath)
+	for _, statement := range program.Statements {
+		switch statement := statement.(type) {
+		case *ast.PipeDefinition, *ast.FunctionDefinition:
+		case *ast.AssignStatement:
+			if statement.Keyword != "const" {
+				return nil, mod.newError(statement.Position(), "imported files can only declare variables with const")
+			}
+		case *ast.ImportStatement:
+			if _, err := l.load(statement.Path.Value, stack); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, mod.newError(statement.Position(), "imported files can only contain pipe, fn, const and import statements")
+		}
+	}
+
+	l.modules[importPath] = mod
+	l.order = append(l.order, mod)
+	return mod, nil
+}
+
+func (m *Module) newError(pos token.Position, msg string) error {
+	start, _ := pos.GetPosition()
+	line, col := token.LineAndColumn(m.Input, max(start, 0))
+	return fmt.Errorf("import error at %s:%d:%d:\n\t%s", m.Path, line, col, msg)
+}
@@ -0,0 +1,87 @@
+package imports
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestLoad(t *testing.T) {
+	l := NewLoader(Map{
+		"main.pl":           "import 'common/strings.pl' as str\nimport 'common/strings.pl' as again\npipe p() { | str.trim() }",
+		"common/strings.pl": "const limit = 10\npipe trim() { $dest.a = 1 }",
+	})
+	mod, err := l.Load("./main.pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEq, failMsg := testutils.Equal("main.pl", mod.Path); !isEq {
+		t.Errorf("wrong path: %s", failMsg)
+	}
+	again, err := l.Load("main.pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod != again {
+		t.Errorf("expected the loaded file to be cached")
+	}
+
+	paths := []string{}
+	for _, m := range l.Modules() {
+		paths = append(paths, m.Path)
+	}
+	if isEq, failMsg := testutils.Equal("common/strings.pl,main.pl", strings.Join(paths, ",")); !isEq {
+		t.Errorf("wrong modules: %s", failMsg)
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := fstest.MapFS{"lib/a.pl": {Data: []byte("pipe a() { }")}}
+	mod, err := NewLoader(FS(fsys)).Load("lib/a.pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEq, failMsg := testutils.Equal(1, len(mod.Program.Statements)); !isEq {
+		t.Errorf("wrong statement count: %s", failMsg)
+	}
+
+	_, err = NewLoader(FS(fsys)).Load("lib/missing.pl")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist. got=%v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	files := Map{
+		"a.pl":       "import 'b.pl' as b",
+		"b.pl":       "import 'a.pl' as a",
+		"self.pl":    "import 'self.pl' as self",
+		"assign.pl":  "x = 1",
+		"expr.pl":    "pipe p() { }\n$dest.a = 1",
+		"invalid.pl": "pipe (a) { }",
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a.pl", "import cycle: a.pl -> b.pl -> a.pl"},
+		{"self.pl", "import cycle: self.pl -> self.pl"},
+		{"missing.pl", "import \"missing.pl\": file does not exist"},
+		{"assign.pl", "import error at assign.pl:1:1:\n\timported files can only declare variables with const"},
+		{"expr.pl", "import error at expr.pl:2:1:\n\timported files can only contain pipe, fn, const and import statements"},
+		{"invalid.pl", "invalid.pl: parse error at 1:6:\n\tunexpected sequence: ("},
+	}
+	for _, tt := range tests {
+		_, err := NewLoader(files).Load(tt.path)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.path)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.path, failMsg)
+		}
+	}
+}
@@ -43,12 +43,16 @@ func (l *Lexer) NextToken() token.Token {
 	case ':':
 		tok = newToken(token.COLON, l.currentChar)
 		tok.SetPosition(l.currentIdx, l.nextIdx)
+	case '?':
+		tok = newToken(token.QUESTION, l.currentChar)
+		tok.SetPosition(l.currentIdx, l.nextIdx)
 	case '.':
+		start := l.currentIdx
 		tok = l.handleDot()
 		if tok.Type == token.FLOAT {
 			return tok
 		}
-		tok.SetPosition(l.currentIdx, l.nextIdx)
+		tok.SetPosition(start, l.nextIdx)
 	case ',':
 		tok = newToken(token.COMMA, l.currentChar)
 		tok.SetPosition(l.currentIdx, l.nextIdx)
@@ -286,6 +290,12 @@ func (l *Lexer) handleDot() token.Token {
 	if isDigit(l.peekNext()) {
 		return l.readNumber()
 	}
+	if l.peekNext() == '.' && l.nextIdx+1 < len(l.input) && l.input[l.nextIdx+1] == '.' {
+		l.readNext()
+		l.readNext()
+		tok.Type = token.ELLIPSIS
+		tok.Value = "..."
+	}
 	return *tok
 }
 
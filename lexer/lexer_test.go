@@ -83,6 +83,44 @@ func TestLexArrow(t *testing.T) {
 	checkTestCase(t, input, cases)
 }
 
+func TestLexEllipsis(t *testing.T) {
+	input := "...parts a.b"
+
+	cases := []testCase{
+		{
+			value:     "...",
+			tokenType: token.ELLIPSIS,
+			start:     0,
+			end:       3,
+		},
+		{
+			value:     "parts",
+			tokenType: token.IDENT,
+			start:     3,
+			end:       8,
+		},
+		{
+			value:     "a",
+			tokenType: token.IDENT,
+			start:     9,
+			end:       10,
+		},
+		{
+			value:     ".",
+			tokenType: token.DOT,
+			start:     10,
+			end:       11,
+		},
+		{
+			value:     "b",
+			tokenType: token.IDENT,
+			start:     11,
+			end:       12,
+		},
+	}
+	checkTestCase(t, input, cases)
+}
+
 func TestLexInt(t *testing.T) {
 	input := "1234"
 
@@ -312,7 +350,7 @@ func TestLexSpecialKeywords(t *testing.T) {
 }
 
 func TestLexDelimiters(t *testing.T) {
-	input := "|()[]{}.,:;"
+	input := "|()[]{}.,:;?"
 	cases := []testCase{
 		{
 			value:     "|",
@@ -380,12 +418,18 @@ func TestLexDelimiters(t *testing.T) {
 			start:     10,
 			end:       11,
 		},
+		{
+			value:     "?",
+			tokenType: token.QUESTION,
+			start:     11,
+			end:       12,
+		},
 	}
 
 	checkTestCase(t, input, cases)
 }
 func TestLexKeywords(t *testing.T) {
-	input := "true false pipe if else null"
+	input := "true false pipe if else null try catch schema let const import as fn"
 	cases := []testCase{
 		{
 			value:     "true",
@@ -423,6 +467,54 @@ func TestLexKeywords(t *testing.T) {
 			start:     24,
 			end:       28,
 		},
+		{
+			value:     "try",
+			tokenType: token.TRY,
+			start:     29,
+			end:       32,
+		},
+		{
+			value:     "catch",
+			tokenType: token.CATCH,
+			start:     33,
+			end:       38,
+		},
+		{
+			value:     "schema",
+			tokenType: token.SCHEMA,
+			start:     39,
+			end:       45,
+		},
+		{
+			value:     "let",
+			tokenType: token.LET,
+			start:     46,
+			end:       49,
+		},
+		{
+			value:     "const",
+			tokenType: token.CONST,
+			start:     50,
+			end:       55,
+		},
+		{
+			value:     "import",
+			tokenType: token.IMPORT,
+			start:     56,
+			end:       62,
+		},
+		{
+			value:     "as",
+			tokenType: token.AS,
+			start:     63,
+			end:       65,
+		},
+		{
+			value:     "fn",
+			tokenType: token.FNDEF,
+			start:     66,
+			end:       68,
+		},
 	}
 
 	checkTestCase(t, input, cases)
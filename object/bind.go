@@ -0,0 +1,74 @@
+package object
+
+import "fmt"
+
+// ParamSpec is what binding needs to know about a param of a builtin, pipe or fn.
+type ParamSpec struct {
+	Name     string
+	Variadic bool
+	Required bool
+}
+
+// Binder matches the args of a call to params one at a time, so the checker and the evaluator bind every call the same way.
+// positional args fill params in order (spilling into a variadic tail if there is one), and named args fill the param with the same name.
+// callers check each value against the param it fills, and fill whatever is left with defaults.
+type Binder struct {
+	name   string
+	params []ParamSpec
+	next   int // the position of the next arg, named ones included
+	bound  map[string]bool
+}
+
+func NewBinder(name string, params []ParamSpec) *Binder {
+	return &Binder{name: name, params: params, bound: map[string]bool{}}
+}
+
+// ParamSpecs describes the params of a pipe or fn for a Binder.
+func ParamSpecs(params []Param) []ParamSpec {
+	ret := make([]ParamSpec, len(params))
+	for idx, p := range params {
+		ret[idx] = ParamSpec{Name: p.Name, Variadic: p.Variadic, Required: p.IsRequired()}
+	}
+	return ret
+}
+
+// Positional returns the index of the param the next positional arg fills.
+func (b *Binder) Positional() (int, error) {
+	idx := b.next
+	b.next++
+	if idx < len(b.params) && !b.params[idx].Variadic {
+		b.bound[b.params[idx].Name] = true
+		return idx, nil
+	}
+	if last := len(b.params) - 1; last >= 0 && b.params[last].Variadic {
+		return last, nil
+	}
+	return -1, fmt.Errorf("too many arguments for %s: want at most %d", b.name, len(b.params))
+}
+
+// Named returns the index of the param a named arg fills.
+func (b *Binder) Named(name string) (int, error) {
+	b.next++
+	for idx, p := range b.params {
+		if p.Name != name || p.Variadic {
+			continue
+		}
+		if b.bound[name] {
+			return -1, fmt.Errorf("duplicate argument %q for %s", name, b.name)
+		}
+		b.bound[name] = true
+		return idx, nil
+	}
+	return -1, fmt.Errorf("unknown argument %q for %s", name, b.name)
+}
+
+// Missing returns the names of the required params no arg filled.
+func (b *Binder) Missing() []string {
+	ret := []string{}
+	for _, p := range b.params {
+		if p.Required && !b.bound[p.Name] {
+			ret = append(ret, p.Name)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,112 @@
+package object
+
+import "slices"
+
+type Environment struct {
+	store     map[string]Object
+	declared  map[string]*TypeSpec // types of annotated variables, like x: int = 1
+	constants map[string]bool      // variables declared with const, which can't be assigned again
+	depth     int                  // how many fn and pipe calls deep the scope is, see NewCallEnvironment
+	outer     *Environment
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object), declared: make(map[string]*TypeSpec), constants: make(map[string]bool)}
+}
+
+// creates a scope whose own variables shadow the outer ones, like the param of an arrow function or the variables of a block.
+// assigning to an outer variable from the new scope should go through Update, so the change outlives the scope.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	env.depth = outer.depth
+	return env
+}
+
+// creates the scope of a fn call or pipe invocation. its own variables are enclosed by the environment it was defined in,
+// while the depth counts the calls that led to it, so runaway recursion can be stopped.
+func NewCallEnvironment(outer *Environment, depth int) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.depth = depth
+	return env
+}
+
+// CallDepth returns how many fn and pipe calls deep the scope is. it's 0 outside of any fn or pipe.
+func (e *Environment) CallDepth() int {
+	return e.depth
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// Names returns the names of the variables set in this environment, not counting outer ones, in sorted order.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Update replaces a variable in the closest environment that has it, or sets it in this one when none do.
+func (e *Environment) Update(name string, val Object) Object {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return val
+		}
+	}
+	return e.Set(name, val)
+}
+
+// Declare sets the type every later value of a variable in this environment must have. a nil type accepts any value.
+func (e *Environment) Declare(name string, t *TypeSpec) {
+	if t == nil {
+		delete(e.declared, name)
+		return
+	}
+	e.declared[name] = t
+}
+
+// SetConstant sets a variable in this environment that can't be assigned again.
+func (e *Environment) SetConstant(name string, val Object) Object {
+	e.constants[name] = true
+	return e.Set(name, val)
+}
+
+// IsConstant reports whether a variable was set with SetConstant, from the environment that holds the variable.
+func (e *Environment) IsConstant(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.constants[name]
+		}
+	}
+	return false
+}
+
+// IsLocalConstant is like IsConstant, but only looks at this environment.
+func (e *Environment) IsLocalConstant(name string) bool {
+	return e.constants[name]
+}
+
+// DeclaredType returns the type a variable was annotated with, from the environment that holds the variable.
+func (e *Environment) DeclaredType(name string) (*TypeSpec, bool) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			t, ok := env.declared[name]
+			return t, ok
+		}
+	}
+	return nil, false
+}
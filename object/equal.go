@@ -0,0 +1,44 @@
+package object
+
+// Equal reports whether a and b hold the same value, the same way == compares them.
+// numbers are compared by value across their types, like 1 and 1.0, and arrays and maps are compared element by element and key by key.
+func Equal(a Object, b Object) bool {
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for idx, el := range a.Elements {
+			if !Equal(el, b.Elements[idx]) {
+				return false
+			}
+		}
+		return true
+	case *Map:
+		b, ok := b.(*Map)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for k, v := range a.Pairs {
+			other, ok := b.Pairs[k]
+			if !ok || !Equal(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+
+	left, leftIsNum := ToRat(a)
+	right, rightIsNum := ToRat(b)
+	if leftIsNum && rightIsNum {
+		// like arithmetic, a float makes the comparison a float one
+		if a.Type() == FLOAT_OBJ || b.Type() == FLOAT_OBJ {
+			l, _ := left.Float64()
+			r, _ := right.Float64()
+			return l == r
+		}
+		return left.Cmp(right) == 0
+	}
+	return a.Type() == b.Type() && a.Inspect() == b.Inspect()
+}
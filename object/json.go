@@ -0,0 +1,121 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// DecodeJSON decodes a single JSON value. numbers keep their exact value, see ParseNumber.
+func DecodeJSON(data []byte) (Object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return FromJSON(decoded)
+}
+
+// FromJSON converts a value from a json.Decoder with UseNumber set.
+func FromJSON(value any) (Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return &Null{}, nil
+	case bool:
+		return &Boolean{Value: value}, nil
+	case string:
+		return &String{Value: value}, nil
+	case json.Number:
+		return ParseNumber(value.String())
+	case []any:
+		elements := make([]Object, len(value))
+		for idx, el := range value {
+			obj, err := FromJSON(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[idx] = obj
+		}
+		return &Array{Elements: elements}, nil
+	case map[string]any:
+		ret := NewMap()
+		for k, v := range value {
+			obj, err := FromJSON(v)
+			if err != nil {
+				return nil, err
+			}
+			ret.Pairs[k] = obj
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("unsupported JSON value %T", value)
+}
+
+// EncodeJSON writes obj as JSON with map keys in sorted order. an empty indent writes everything on one line.
+func EncodeJSON(obj Object, indent string) ([]byte, error) {
+	native, err := ToJSON(obj)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(native); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ToJSON converts obj into values encoding/json writes without losing precision.
+// times, durations and IPs are written in the same string form they inspect as.
+func ToJSON(obj Object) (any, error) {
+	switch obj := obj.(type) {
+	case *Null:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *UInteger:
+		return obj.Value, nil
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, fmt.Errorf("%s can't be written as JSON", obj.Inspect())
+		}
+		return obj.Value, nil
+	case *Decimal:
+		return json.Number(obj.Inspect()), nil
+	case *Time, *Duration, *IP:
+		return obj.Inspect(), nil
+	case *Array:
+		ret := make([]any, len(obj.Elements))
+		for idx, el := range obj.Elements {
+			native, err := ToJSON(el)
+			if err != nil {
+				return nil, err
+			}
+			ret[idx] = native
+		}
+		return ret, nil
+	case *Map:
+		ret := make(map[string]any, len(obj.Pairs))
+		for k, v := range obj.Pairs {
+			native, err := ToJSON(v)
+			if err != nil {
+				return nil, err
+			}
+			ret[k] = native
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("%s values can't be written as JSON", obj.Type())
+}
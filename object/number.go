@@ -0,0 +1,130 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// the number of digits after the decimal point needed to write r exactly, or -1 when r doesn't terminate (like 1/3).
+// r terminates when its reduced denominator only has 2 and 5 as prime factors.
+func decimalPlaces(r *big.Rat) int {
+	denom := new(big.Int).Set(r.Denom())
+	twos := removeFactor(denom, 2)
+	fives := removeFactor(denom, 5)
+	if !denom.IsInt64() || denom.Int64() != 1 {
+		return -1
+	}
+	return max(twos, fives)
+}
+
+// divides n by factor in place for as long as it divides evenly, and returns how many times it did
+func removeFactor(n *big.Int, factor int64) int {
+	count := 0
+	f := big.NewInt(factor)
+	q, m := new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(n, f, m)
+		if m.Sign() != 0 {
+			return count
+		}
+		n.Set(q)
+		count++
+	}
+}
+
+// ToRat returns the exact value of an integer, uinteger, float or decimal.
+func ToRat(obj Object) (*big.Rat, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return new(big.Rat).SetInt64(obj.Value), true
+	case *UInteger:
+		return new(big.Rat).SetUint64(obj.Value), true
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(obj.Value), true
+	case *Decimal:
+		return obj.Value, true
+	}
+	return nil, false
+}
+
+// NumberFromRat returns the narrowest exact type for r: an integer, then a uinteger, then a decimal.
+// it returns false when r has no exact decimal form.
+func NumberFromRat(r *big.Rat) (Object, bool) {
+	if r.IsInt() {
+		num := r.Num()
+		switch {
+		case num.IsInt64():
+			return &Integer{Value: num.Int64()}, true
+		case num.IsUint64():
+			return &UInteger{Value: num.Uint64()}, true
+		}
+		return &Decimal{Value: r}, true
+	}
+	if decimalPlaces(r) < 0 {
+		return nil, false
+	}
+	return &Decimal{Value: r}, true
+}
+
+// the largest exponent ParseNumber decodes exactly. 1e1000000 would take a megabyte to hold exactly, so past this a number is decoded as a float.
+const maxExactExponent = 400
+
+// ParseNumber parses the text of a JSON number into the type that holds it exactly:
+// an integer, a uinteger for integers past the int64 range, a float when the float prints back as the same value,
+// and a decimal for anything else like long fractions or values out of the float range.
+// numbers with an exponent past maxExactExponent are decoded as floats, and are out of range if they don't fit one.
+func ParseNumber(text string) (Object, error) {
+	if idx := strings.IndexAny(text, "eE"); idx >= 0 {
+		exp, err := strconv.Atoi(strings.TrimPrefix(text[idx+1:], "+"))
+		if err != nil || exp > maxExactExponent || exp < -maxExactExponent {
+			f, err := strconv.ParseFloat(text, 64)
+			if errors.Is(err, strconv.ErrRange) {
+				return nil, fmt.Errorf("number %s is out of range", text)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", text)
+			}
+			return &Float{Value: f}, nil
+		}
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", text)
+	}
+	if isIntegerText(text) {
+		num := r.Num()
+		switch {
+		case num.IsInt64():
+			return &Integer{Value: num.Int64()}, nil
+		case num.IsUint64():
+			return &UInteger{Value: num.Uint64()}, nil
+		}
+		return &Decimal{Value: r}, nil
+	}
+	f, _ := r.Float64()
+	if !math.IsInf(f, 0) {
+		if shortest, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64)); ok && shortest.Cmp(r) == 0 {
+			return &Float{Value: f}, nil
+		}
+	}
+	return &Decimal{Value: r}, nil
+}
+
+func isIntegerText(text string) bool {
+	for idx, c := range text {
+		if c == '-' && idx == 0 {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
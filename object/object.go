@@ -0,0 +1,327 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hudsn/pipelang/token"
+)
+
+type ObjectType string
+
+const (
+	INTEGER_OBJ  ObjectType = "INTEGER"
+	UINTEGER_OBJ ObjectType = "UINTEGER"
+	FLOAT_OBJ    ObjectType = "FLOAT"
+	DECIMAL_OBJ  ObjectType = "DECIMAL"
+	STRING_OBJ   ObjectType = "STRING"
+	BOOLEAN_OBJ  ObjectType = "BOOLEAN"
+	NULL_OBJ     ObjectType = "NULL"
+	ARRAY_OBJ    ObjectType = "ARRAY"
+	MAP_OBJ      ObjectType = "MAP"
+
+	TIME_OBJ     ObjectType = "TIME"
+	DURATION_OBJ ObjectType = "DURATION"
+	IP_OBJ       ObjectType = "IP"
+
+	FUNCTION_OBJ ObjectType = "FUNCTION"
+	PIPE_OBJ     ObjectType = "PIPE"
+	MODULE_OBJ   ObjectType = "MODULE"
+	FN_OBJ       ObjectType = "FN"
+
+	ERROR_OBJ       ObjectType = "ERROR"
+	ERROR_VALUE_OBJ ObjectType = "ERROR_VALUE"
+)
+
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+//
+
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string  { return strconv.FormatInt(i.Value, 10) }
+
+//
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string  { return strconv.FormatFloat(f.Value, 'f', -1, 64) }
+
+//
+
+// UInteger holds integers above the int64 range, like 64 bit ids decoded from JSON
+type UInteger struct {
+	Value uint64
+}
+
+func (u *UInteger) Type() ObjectType { return UINTEGER_OBJ }
+func (u *UInteger) Inspect() string  { return strconv.FormatUint(u.Value, 10) }
+
+//
+
+// Decimal is an exact number for values that neither an integer nor a float can hold without losing precision.
+// the value is always a terminating decimal, so it can be written back out exactly.
+type Decimal struct {
+	Value *big.Rat
+}
+
+func (d *Decimal) Type() ObjectType { return DECIMAL_OBJ }
+func (d *Decimal) Inspect() string {
+	if d.Value.IsInt() {
+		return d.Value.Num().String()
+	}
+	return d.Value.FloatString(decimalPlaces(d.Value))
+}
+
+//
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+//
+
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string  { return strconv.FormatBool(b.Value) }
+
+//
+
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string  { return "null" }
+
+//
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	elements := []string{}
+	for _, el := range a.Elements {
+		elements = append(elements, el.Inspect())
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+//
+
+type Map struct {
+	Pairs map[string]Object
+}
+
+func NewMap() *Map {
+	return &Map{Pairs: make(map[string]Object)}
+}
+
+// keys in sorted order, so that output built from a map is deterministic
+func (m *Map) Keys() []string {
+	keys := make([]string, 0, len(m.Pairs))
+	for k := range m.Pairs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (m *Map) Type() ObjectType { return MAP_OBJ }
+func (m *Map) Inspect() string {
+	pairs := []string{}
+	for _, k := range m.Keys() {
+		pairs = append(pairs, fmt.Sprintf("%q: %s", k, m.Pairs[k].Inspect()))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
+//
+
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType { return TIME_OBJ }
+func (t *Time) Inspect() string  { return t.Value.Format(time.RFC3339Nano) }
+
+//
+
+type Duration struct {
+	Value time.Duration
+}
+
+func (d *Duration) Type() ObjectType { return DURATION_OBJ }
+func (d *Duration) Inspect() string  { return d.Value.String() }
+
+//
+
+type IP struct {
+	Value netip.Addr
+}
+
+func (ip *IP) Type() ObjectType { return IP_OBJ }
+func (ip *IP) Inspect() string  { return ip.Value.String() }
+
+//
+
+// Function is a callable value, like the arrow function passed in: count_by($src.events, e ~> e.status)
+type Function struct {
+	Params []string
+	Body   string // source form of the body, for Inspect
+	// returns an *Error when the call fails
+	Call func(args ...Object) Object
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	return fmt.Sprintf("%s ~> %s", strings.Join(f.Params, ", "), f.Body)
+}
+
+// Pipe is a pipe definition bound to the environment it was defined in.
+type Pipe struct {
+	Name   string
+	Params []Param
+	// takes a value for every param and the call depth of the invocation, and returns an *Error when the pipe fails
+	Call func(args map[string]Object, depth int) Object
+}
+
+// Fn is a function definition bound to the environment it was defined in.
+type Fn struct {
+	Name   string
+	Params []Param
+	// takes a value for every param and the call depth of the caller, and returns an *Error when the function fails
+	Call func(args map[string]Object, depth int) Object
+}
+
+func (f *Fn) Type() ObjectType { return FN_OBJ }
+func (f *Fn) Inspect() string {
+	params := []string{}
+	for _, param := range f.Params {
+		params = append(params, param.String())
+	}
+	return fmt.Sprintf("fn %s(%s)", f.Name, strings.Join(params, ", "))
+}
+
+type Param struct {
+	Name     string
+	Type     *TypeSpec // nil when the param takes any value. for a variadic param, the type of each value it collects.
+	Default  Object    // nil for required params
+	Variadic bool      // collects every remaining positional arg into an array. only valid on the last param, and can't be passed by name.
+}
+
+func (p Param) IsRequired() bool {
+	return p.Default == nil && !p.Variadic
+}
+
+// Check returns the part of val that doesn't match the param's annotation, or nil when it matches or the param has none
+func (p Param) Check(val Object) *Mismatch {
+	if p.Type == nil {
+		return nil
+	}
+	return p.Type.Check(val)
+}
+
+func (p *Pipe) Type() ObjectType { return PIPE_OBJ }
+func (p *Pipe) Inspect() string {
+	params := []string{}
+	for _, param := range p.Params {
+		params = append(params, param.String())
+	}
+	return fmt.Sprintf("pipe %s(%s)", p.Name, strings.Join(params, ", "))
+}
+
+// Module is an imported file, like norm in: import "common/normalize.pl" as norm
+type Module struct {
+	Name    string
+	Path    string
+	Members map[string]Object // the file's pipes, fns and constants
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("module %s (%s)", m.Name, m.Path)
+}
+
+func (p Param) String() string {
+	ret := p.Name
+	if p.Variadic {
+		ret = "..." + ret
+	}
+	if p.Type != nil {
+		ret += ": " + p.Type.String()
+	}
+	if p.Default != nil {
+		if s, ok := p.Default.(*String); ok {
+			ret += " = " + strconv.Quote(s.Value)
+		} else {
+			ret += " = " + p.Default.Inspect()
+		}
+	}
+	return ret
+}
+
+//
+// errors
+//
+
+// Error is a runtime failure that propagates up through evaluation until it is either caught by a try/catch or returned to the host.
+type Error struct {
+	Message  string
+	Position token.Position
+	Line     int
+	Column   int
+	File     string // the path of the imported file the error happened in. empty for the program itself.
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string {
+	if e.File != "" {
+		return fmt.Sprintf("runtime error at %s:%d:%d:\n\t%s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("runtime error at %d:%d:\n\t%s", e.Line, e.Column, e.Message)
+}
+func (e *Error) Error() string { return e.Inspect() }
+
+// ErrorValue is a caught Error bound as a regular value (ex: the err in catch err { ... }), so referencing it doesn't re-raise it.
+type ErrorValue struct {
+	Message  string
+	Position token.Position
+	Line     int
+	Column   int
+}
+
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+func (ev *ErrorValue) Inspect() string {
+	return fmt.Sprintf("error at %d:%d: %s", ev.Line, ev.Column, ev.Message)
+}
+
+// converts a propagating error into a plain value.
+func (e *Error) ToValue() *ErrorValue {
+	return &ErrorValue{
+		Message:  e.Message,
+		Position: e.Position,
+		Line:     e.Line,
+		Column:   e.Column,
+	}
+}
@@ -0,0 +1,101 @@
+package object
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// the type names scripts can use in annotations, like x: int = 1
+var typeNames = map[string][]ObjectType{
+	"any":      nil,
+	"int":      {INTEGER_OBJ, UINTEGER_OBJ},
+	"float":    {FLOAT_OBJ},
+	"decimal":  {DECIMAL_OBJ},
+	"number":   {INTEGER_OBJ, UINTEGER_OBJ, FLOAT_OBJ, DECIMAL_OBJ},
+	"string":   {STRING_OBJ},
+	"bool":     {BOOLEAN_OBJ},
+	"array":    {ARRAY_OBJ},
+	"map":      {MAP_OBJ},
+	"time":     {TIME_OBJ},
+	"duration": {DURATION_OBJ},
+	"ip":       {IP_OBJ},
+	"function": {FUNCTION_OBJ},
+}
+
+// TypeSpec is a type written in a script, like int or array<string>.
+type TypeSpec struct {
+	Name  string
+	Kinds []ObjectType // the value types it accepts. empty for any.
+	Elem  *TypeSpec    // the element type of an array, or the value type of a map. nil accepts any elements.
+}
+
+// NewTypeSpec looks up a type name. only array and map take an element type.
+func NewTypeSpec(name string, elem *TypeSpec) (*TypeSpec, error) {
+	kinds, ok := typeNames[name]
+	if !ok {
+		names := []string{}
+		for n := range typeNames {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown type %q. expected one of %s", name, strings.Join(names, ", "))
+	}
+	if elem != nil && name != "array" && name != "map" {
+		return nil, fmt.Errorf("type %s does not take an element type", name)
+	}
+	return &TypeSpec{Name: name, Kinds: kinds, Elem: elem}, nil
+}
+
+func (t *TypeSpec) String() string {
+	if t.Elem != nil {
+		return fmt.Sprintf("%s<%s>", t.Name, t.Elem.String())
+	}
+	return t.Name
+}
+
+// Matches reports whether obj is a value of type t, including every element when t has an element type.
+func (t *TypeSpec) Matches(obj Object) bool {
+	return t.Check(obj) == nil
+}
+
+// Mismatch is the part of a value that doesn't match a TypeSpec.
+type Mismatch struct {
+	Path string // where the value is, like [1] or ["name"][0]. empty for the value itself.
+	Got  ObjectType
+}
+
+func (m *Mismatch) String() string {
+	if m.Path == "" {
+		return string(m.Got)
+	}
+	return fmt.Sprintf("%s at %s", m.Got, m.Path)
+}
+
+// Check returns the first part of obj that doesn't match t, checking every element when t has an element type, or nil when obj matches.
+// map values are checked in key order, so the same value always reports the same mismatch.
+func (t *TypeSpec) Check(obj Object) *Mismatch {
+	if len(t.Kinds) > 0 && !slices.Contains(t.Kinds, obj.Type()) {
+		return &Mismatch{Got: obj.Type()}
+	}
+	if t.Elem == nil {
+		return nil
+	}
+	switch obj := obj.(type) {
+	case *Array:
+		for idx, el := range obj.Elements {
+			if m := t.Elem.Check(el); m != nil {
+				return &Mismatch{Path: fmt.Sprintf("[%d]%s", idx, m.Path), Got: m.Got}
+			}
+		}
+	case *Map:
+		for _, k := range slices.Sorted(maps.Keys(obj.Pairs)) {
+			if m := t.Elem.Check(obj.Pairs[k]); m != nil {
+				return &Mismatch{Path: fmt.Sprintf("[%q]%s", k, m.Path), Got: m.Got}
+			}
+		}
+	}
+	return nil
+}
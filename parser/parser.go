@@ -27,6 +27,7 @@ const (
 	LOWEST
 	ARROW          // ~>
 	ASSIGN         // =
+	PIPE           // |
 	LOGIC_OP       // || &&
 	EQUALITY       // == !=
 	COMPARISON     // < > <= >=
@@ -38,6 +39,7 @@ const (
 
 var precedenceMap = map[token.TokenType]int{
 	token.ASSIGN:    ASSIGN,
+	token.PIPECHAR:  PIPE,
 	token.EQ:        EQUALITY,
 	token.NOT_EQ:    EQUALITY,
 	token.LT:        COMPARISON,
@@ -81,9 +83,16 @@ func (p *Parser) registerFuncs() {
 	p.registerPrefixFunc(token.TRUE, p.parseBoolean)
 	p.registerPrefixFunc(token.FALSE, p.parseBoolean)
 	p.registerPrefixFunc(token.IDENT, p.parseIdentifier)
+	p.registerPrefixFunc(token.SRC, p.parseIdentifier)
+	p.registerPrefixFunc(token.DEST, p.parseIdentifier)
+	p.registerPrefixFunc(token.ENV, p.parseIdentifier)
+	p.registerPrefixFunc(token.VAR, p.parseIdentifier)
 	p.registerPrefixFunc(token.STRING, p.parseString)
+	p.registerPrefixFunc(token.NULL, p.parseNull)
 	p.registerPrefixFunc(token.IF, p.parseIfExpression)
+	p.registerPrefixFunc(token.TRY, p.parseTryExpression)
 	p.registerPrefixFunc(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefixFunc(token.LSQUARE, p.parseArrayLiteral)
 	p.registerPrefixFunc(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixFunc(token.EXCLAMATION, p.parsePrefixExpression)
 
@@ -102,6 +111,7 @@ func (p *Parser) registerFuncs() {
 	p.registerInfixFunc(token.ARROW, p.parseArrowFunctionExpression)
 	p.registerInfixFunc(token.LPAREN, p.parseCallExpression)
 	p.registerInfixFunc(token.DOT, p.parseDotAccessExpression)
+	p.registerInfixFunc(token.PIPECHAR, p.parsePipeExpression)
 	// TODO
 	// p.registerInfixFunc(token.LSQUARE, p.parseIndexExpression)
 
@@ -139,13 +149,29 @@ func (p *Parser) parseStatement() ast.Statement {
 			p.progressTokens()
 			return p.parseAssignStatement(ident)
 		}
+		if p.isPeekToken(token.COLON) {
+			return p.parseAnnotatedAssignStatement()
+		}
 		return p.parseExpressionStatement()
 	case token.PIPEDEF:
-		// TODO handle pipedef
-		return nil
+		return p.parsePipeDefinition()
+	case token.FNDEF:
+		return p.parseFunctionDefinition()
 	case token.PIPECHAR:
-		// TODO handle pipe invocation
-		return nil
+		return p.parsePipeInvocation()
+	case token.SCHEMA:
+		return p.parseSchemaStatement()
+	case token.LET, token.CONST:
+		return p.parseDeclaration()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.DEST:
+		if p.isPeekToken(token.ASSIGN) {
+			ident := p.parseIdentifier()
+			p.progressTokens()
+			return p.parseAssignStatement(ident)
+		}
+		return p.parseDestStatement()
 	default:
 		// handle rest of expressions
 		return p.parseExpressionStatement()
@@ -218,7 +244,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 		err := newParsingError(parseErr, p.lexer.InputRunes(), p.currentToken)
 		p.errors = append(p.errors, err)
 	}
-	ret.Value = int(val)
+	ret.Value = val
 	return ret
 }
 
@@ -253,6 +279,10 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.currentToken, Value: val}
 }
 
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.NullLiteral{Token: p.currentToken}
+}
+
 func (p *Parser) parseString() ast.Expression {
 	return &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Value}
 }
@@ -367,6 +397,38 @@ func (p *Parser) parseExpressionList(endType token.TokenType) []ast.Expression {
 	return ret
 }
 
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	ret := &ast.ArrayLiteral{Token: p.currentToken}
+	ret.Elements = p.parseExpressionList(token.RSQUARE)
+	if ret.Elements == nil {
+		return nil
+	}
+	_, end := p.currentToken.Position.GetPosition()
+	ret.EndPos = end
+	return ret
+}
+
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	ret := &ast.PipeExpression{Token: p.currentToken, Value: left}
+	p.progressTokens()
+
+	// only take the call itself, so that a | b() | c() chains left to right and a | b() == c compares the piped result
+	right := p.parseExpression(PREFIX)
+	switch right := right.(type) {
+	case *ast.CallExpression:
+		ret.Call = right
+	case *ast.Identifier: // allow a bare function name when there are no other args, like: value | upper
+		_, end := right.Token.Position.GetPosition()
+		ret.Call = &ast.CallExpression{Token: right.Token, Name: right, Arguments: []*ast.Argument{}, EndPos: end}
+	case nil:
+		return nil
+	default:
+		p.errUnexpectedToken(right.GetToken())
+		return nil
+	}
+	return ret
+}
+
 func (p *Parser) parseArrowFunctionExpression(left ast.Expression) ast.Expression {
 	ret := &ast.ArrowFunctionExpression{Token: p.currentToken}
 
@@ -387,7 +449,12 @@ func (p *Parser) parseArrowFunctionExpression(left ast.Expression) ast.Expressio
 func (p *Parser) parseDotAccessExpression(left ast.Expression) ast.Expression {
 	ret := &ast.DotAccess{Token: p.currentToken, Object: left}
 	p.progressTokens()
-	ret.Item = p.parseExpression(LOWEST)
+	// field names come from data, so a keyword after a dot is just a name, like $src.try
+	if p.currentToken.Type != token.IDENT && token.IsKeyword(p.currentToken.Value) {
+		p.currentToken.Type = token.IDENT
+	}
+	// only keep chaining further accessors and calls so that something like $src.a + 1 stays an infix expression
+	ret.Item = p.parseExpression(PREFIX)
 	return ret
 }
 
@@ -428,6 +495,41 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return ret
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	ret := &ast.TryExpression{Token: p.currentToken}
+
+	if !p.mustNextToken(token.LCURLY) {
+		return nil
+	}
+	ret.Block = p.parseBlockStatement()
+	if ret.Block == nil {
+		return nil
+	}
+
+	if !p.mustNextToken(token.CATCH) {
+		return nil
+	}
+	ret.CatchToken = p.currentToken
+
+	if p.isPeekToken(token.IDENT) {
+		p.progressTokens()
+		ret.ErrorName = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Value}
+	}
+
+	if !p.mustNextToken(token.LCURLY) {
+		return nil
+	}
+	ret.Catch = p.parseBlockStatement()
+	if ret.Catch == nil {
+		return nil
+	}
+
+	if p.isPeekToken(token.SEMICOLON) {
+		p.progressTokens()
+	}
+	return ret
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	ret := &ast.BlockStatement{OpenToken: p.currentToken}
 	ret.Statements = []ast.Statement{}
@@ -468,6 +570,378 @@ func (p *Parser) parseAssignStatement(expr ast.Expression) *ast.AssignStatement
 	return ret
 }
 
+// either sets a field, like $dest.user.name = "a", or reads from $dest like any other expression. enter on $dest.
+func (p *Parser) parseDestStatement() ast.Statement {
+	startToken := p.currentToken
+	expr := p.parseExpression(LOWEST)
+	access, ok := expr.(*ast.DotAccess)
+	if !ok || !p.isPeekToken(token.ASSIGN) {
+		stmt := &ast.ExpressionStatement{Token: startToken, Expression: expr}
+		if p.isPeekToken(token.SEMICOLON) {
+			p.progressTokens()
+		}
+		return stmt
+	}
+
+	object, _ := access.Object.(*ast.Identifier)
+	path, ok := fieldPath(access.Item)
+	if !ok {
+		err := fmt.Errorf("expect a field path like $dest.a.b on the left side of assign statement")
+		p.errors = append(p.errors, newParsingError(err, p.lexer.InputRunes(), startToken))
+		return nil
+	}
+	ret := &ast.FieldAssignStatement{Object: object, Path: path}
+
+	p.progressTokens() // to =
+	ret.Token = p.currentToken
+	p.progressTokens()
+	ret.Value = p.parseExpression(LOWEST)
+	if p.isPeekToken(token.SEMICOLON) {
+		p.progressTokens()
+	}
+	return ret
+}
+
+// flattens the item of a dot access into its names, like a.b.c. returns false when any part isn't a plain name.
+func fieldPath(item ast.Expression) ([]*ast.Identifier, bool) {
+	switch item := item.(type) {
+	case *ast.Identifier:
+		return []*ast.Identifier{item}, true
+	case *ast.DotAccess:
+		name, ok := item.Object.(*ast.Identifier)
+		if !ok {
+			return nil, false
+		}
+		rest, ok := fieldPath(item.Item)
+		return append([]*ast.Identifier{name}, rest...), ok
+	}
+	return nil, false
+}
+
+// like: let x = 1, or const limit: int = 100. enter on the keyword.
+func (p *Parser) parseDeclaration() ast.Statement {
+	keyword := p.currentToken.Value
+	if !p.mustNextToken(token.IDENT) {
+		return nil
+	}
+	var ret *ast.AssignStatement
+	if p.isPeekToken(token.COLON) {
+		if ret, _ = p.parseAnnotatedAssignStatement().(*ast.AssignStatement); ret == nil {
+			return nil
+		}
+	} else {
+		ident := p.parseIdentifier()
+		if !p.mustNextToken(token.ASSIGN) {
+			return nil
+		}
+		ret = p.parseAssignStatement(ident)
+	}
+	ret.Keyword = keyword
+	return ret
+}
+
+// like: x: float = 1
+func (p *Parser) parseAnnotatedAssignStatement() ast.Statement {
+	ident := p.parseIdentifier()
+	p.progressTokens() // to colon
+	if !p.mustNextToken(token.IDENT) {
+		return nil
+	}
+	annotation := p.parseTypeAnnotation()
+	if annotation == nil || !p.mustNextToken(token.ASSIGN) {
+		return nil
+	}
+	ret := p.parseAssignStatement(ident)
+	ret.Type = annotation
+	return ret
+}
+
+// parses a type name and its element type if it has one, like string or array<string>.
+// enter on the type name.
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	ret := &ast.TypeAnnotation{Token: p.currentToken, Name: p.currentToken.Value}
+	if p.isPeekToken(token.LT) {
+		p.progressTokens() // to <
+		if !p.mustNextToken(token.IDENT) {
+			return nil
+		}
+		ret.Elem = p.parseTypeAnnotation()
+		if ret.Elem == nil || !p.mustNextToken(token.GT) {
+			return nil
+		}
+	}
+	_, end := p.currentToken.Position.GetPosition()
+	ret.EndPos = end
+	return ret
+}
+
+// like: pipe enrich(threshold: int, tags: array<string>) { ... }
+func (p *Parser) parsePipeDefinition() ast.Statement {
+	ret := &ast.PipeDefinition{Token: p.currentToken}
+	var ok bool
+	if ret.Name, ret.Params, ret.Body, ok = p.parseDefinition(); !ok {
+		return nil
+	}
+	return ret
+}
+
+func (p *Parser) parseFunctionDefinition() ast.Statement {
+	ret := &ast.FunctionDefinition{Token: p.currentToken}
+	var ok bool
+	if ret.Name, ret.Params, ret.Body, ok = p.parseDefinition(); !ok {
+		return nil
+	}
+	return ret
+}
+
+func (p *Parser) errParam(param *ast.Parameter, format string, a ...any) {
+	err := fmt.Errorf(format, a...)
+	p.errors = append(p.errors, newParsingError(err, p.lexer.InputRunes(), param.Name.Token))
+}
+
+// parses the name(params) { body } shared by pipe and fn definitions, entering on the keyword
+func (p *Parser) parseDefinition() (*ast.Identifier, []*ast.Parameter, *ast.BlockStatement, bool) {
+	if !p.mustNextToken(token.IDENT) {
+		return nil, nil, nil, false
+	}
+	name := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Value}
+	if !p.mustNextToken(token.LPAREN) {
+		return nil, nil, nil, false
+	}
+	params := p.parseParameters()
+	if params == nil || !p.mustNextToken(token.LCURLY) {
+		return nil, nil, nil, false
+	}
+	body := p.parseBlockStatement()
+	if body == nil {
+		return nil, nil, nil, false
+	}
+	if p.isPeekToken(token.SEMICOLON) {
+		p.progressTokens()
+	}
+	return name, params, body, true
+}
+
+func (p *Parser) parseParameters() []*ast.Parameter {
+	// enter on the opening '(' and end on the closing ')'
+	ret := []*ast.Parameter{}
+	if p.isPeekToken(token.RPAREN) {
+		p.progressTokens()
+		return ret
+	}
+
+	for {
+		variadic := p.isPeekToken(token.ELLIPSIS)
+		if variadic {
+			p.progressTokens() // to ellipsis
+		}
+		if !p.mustNextToken(token.IDENT) {
+			return nil
+		}
+		param := &ast.Parameter{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Value}, Variadic: variadic}
+		if p.isPeekToken(token.COLON) {
+			p.progressTokens() // to colon
+			if !p.mustNextToken(token.IDENT) {
+				return nil
+			}
+			param.Type = p.parseTypeAnnotation()
+			if param.Type == nil {
+				return nil
+			}
+		}
+		if p.isPeekToken(token.ASSIGN) {
+			p.progressTokens() // to =
+			p.progressTokens() // to the default value
+			param.Default = p.parseExpression(LOWEST)
+			if param.Default == nil {
+				return nil
+			}
+		}
+		for _, existing := range ret {
+			if existing.Name.Value == param.Name.Value {
+				p.errParam(param, "duplicate param %q", param.Name.Value)
+			}
+		}
+		if param.Variadic && param.Default != nil {
+			p.errParam(param, "variadic param %q can't have a default value", param.Name.Value)
+		}
+		ret = append(ret, param)
+
+		if !p.isPeekToken(token.COMMA) {
+			break
+		}
+		if param.Variadic {
+			p.errParam(param, "variadic param %q must be the last param", param.Name.Value)
+		}
+		p.progressTokens() // to comma
+	}
+
+	if !p.mustNextToken(token.RPAREN) {
+		return nil
+	}
+	return ret
+}
+
+// like: | enrich(threshold: 5), or | enrich when there are no args
+func (p *Parser) parsePipeInvocation() ast.Statement {
+	ret := &ast.PipeInvocation{Token: p.currentToken}
+	p.progressTokens()
+	if p.isCurrentToken(token.IDENT) && p.isPeekToken(token.DOT) {
+		ret.Module = p.parseIdentifier().(*ast.Identifier)
+		p.progressTokens() // to .
+		if !p.mustNextToken(token.IDENT) {
+			return nil
+		}
+	}
+
+	call := p.parseExpression(PREFIX)
+	switch call := call.(type) {
+	case *ast.CallExpression:
+		ret.Call = call
+	case *ast.Identifier:
+		_, end := call.Token.Position.GetPosition()
+		ret.Call = &ast.CallExpression{Token: call.Token, Name: call, Arguments: []*ast.Argument{}, EndPos: end}
+	case nil:
+		return nil
+	default:
+		p.errUnexpectedToken(call.GetToken())
+		return nil
+	}
+
+	if p.isPeekToken(token.SEMICOLON) {
+		p.progressTokens()
+	}
+	return ret
+}
+
+// like: import "common/normalize.pl" as norm
+func (p *Parser) parseImportStatement() ast.Statement {
+	ret := &ast.ImportStatement{Token: p.currentToken}
+	if !p.mustNextToken(token.STRING) {
+		return nil
+	}
+	ret.Path = p.parseString().(*ast.StringLiteral)
+	if !p.mustNextToken(token.AS) || !p.mustNextToken(token.IDENT) {
+		return nil
+	}
+	ret.Alias = p.parseIdentifier().(*ast.Identifier)
+	if p.isPeekToken(token.SEMICOLON) {
+		p.progressTokens()
+	}
+	return ret
+}
+
+// like: schema $src { user: { name: string }, tags?: array<string> }, or schema $dest { level: string enum("info", "warn") }
+func (p *Parser) parseSchemaStatement() ast.Statement {
+	ret := &ast.SchemaStatement{Token: p.currentToken}
+	if !p.isPeekToken(token.SRC) && !p.isPeekToken(token.DEST) {
+		p.errUnexpectedToken(p.peekToken)
+		return nil
+	}
+	p.progressTokens()
+	ret.Target = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Value}
+	if !p.mustNextToken(token.LCURLY) {
+		return nil
+	}
+	ret.Fields = p.parseSchemaFields()
+	if ret.Fields == nil {
+		return nil
+	}
+	ret.CloseToken = p.currentToken
+	if p.isPeekToken(token.SEMICOLON) {
+		p.progressTokens()
+	}
+	return ret
+}
+
+// enter on the opening '{' and end on the closing '}'. fields are separated by commas or new lines.
+// names can be quoted for keys that aren't valid identifiers, like "user agent": string
+func (p *Parser) parseSchemaFields() []*ast.SchemaField {
+	ret := []*ast.SchemaField{}
+	for {
+		p.progressTokens()
+		for p.isCurrentToken(token.SEMICOLON) || p.isCurrentToken(token.COMMA) {
+			p.progressTokens()
+		}
+		if p.isCurrentToken(token.RCURLY) {
+			return ret
+		}
+		if !p.isCurrentToken(token.IDENT) && !p.isCurrentToken(token.STRING) {
+			p.errUnexpected()
+			return nil
+		}
+
+		field := &ast.SchemaField{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Value}}
+		for _, existing := range ret {
+			if existing.Name.Value == field.Name.Value {
+				err := fmt.Errorf("duplicate field %q", field.Name.Value)
+				p.errors = append(p.errors, newParsingError(err, p.lexer.InputRunes(), field.Name.Token))
+			}
+		}
+		if p.isPeekToken(token.QUESTION) {
+			p.progressTokens()
+			field.Optional = true
+		}
+		if !p.mustNextToken(token.COLON) {
+			return nil
+		}
+
+		p.progressTokens()
+		switch p.currentToken.Type {
+		case token.LCURLY:
+			if field.Fields = p.parseSchemaFields(); field.Fields == nil {
+				return nil
+			}
+		case token.IDENT:
+			if field.Type = p.parseTypeAnnotation(); field.Type == nil {
+				return nil
+			}
+			for p.isPeekToken(token.IDENT) {
+				p.progressTokens()
+				constraint := p.parseSchemaConstraint()
+				if constraint == nil {
+					return nil
+				}
+				field.Constraints = append(field.Constraints, constraint)
+			}
+		default:
+			p.errUnexpected()
+			return nil
+		}
+		_, end := p.currentToken.Position.GetPosition()
+		field.EndPos = end
+		ret = append(ret, field)
+	}
+}
+
+// like: enum("info", "warn"). enter on the name and end on the closing ')'.
+// the args are checked when the schema is converted, see the schema package.
+func (p *Parser) parseSchemaConstraint() *ast.SchemaConstraint {
+	ret := &ast.SchemaConstraint{Name: p.parseIdentifier().(*ast.Identifier)}
+	if !p.mustNextToken(token.LPAREN) {
+		return nil
+	}
+	p.progressTokens()
+	for !p.isCurrentToken(token.RPAREN) {
+		arg := p.parseExpression(LOWEST)
+		if arg == nil {
+			return nil
+		}
+		ret.Args = append(ret.Args, arg)
+		if p.isPeekToken(token.COMMA) {
+			p.progressTokens()
+			p.progressTokens()
+			continue
+		}
+		if !p.mustNextToken(token.RPAREN) {
+			return nil
+		}
+	}
+	_, end := p.currentToken.Position.GetPosition()
+	ret.EndPos = end
+	return ret
+}
+
 //HELPERS
 
 func (p *Parser) registerPrefixFunc(tokenType token.TokenType, fn prefixFunc) {
@@ -501,22 +975,6 @@ func (p *Parser) mustNextToken(tokenType token.TokenType) bool {
 	return false
 }
 
-func getLineAndColumn(inputRunes []rune, targetIdx int) (int, int) {
-	// 1 indexing b/c its more useful for people-facing errors in editors
-	line := 1
-	col := 1
-	for _, r := range inputRunes[:targetIdx] {
-		switch r {
-		case '\n': // reset if newline
-			line++
-			col = 1
-		default:
-			col++
-		}
-	}
-	return line, col
-}
-
 // error
 
 // generic error for unexpected sequences (missing operator funcs, parsing statements where the order is incorrect, etc...)
@@ -542,9 +1000,9 @@ func (p *Parser) errUnexpectedToken(t token.Token) {
 	p.errors = append(p.errors, newParsingError(e, p.lexer.InputRunes(), t))
 }
 
-func newParsingError(innerErr error, inputRunes []rune, token token.Token) error {
-	start, _ := token.Position.GetPosition()
-	line, col := getLineAndColumn(inputRunes, start)
+func newParsingError(innerErr error, inputRunes []rune, tok token.Token) error {
+	start, _ := tok.Position.GetPosition()
+	line, col := token.LineAndColumn(inputRunes, start)
 	return fmt.Errorf("parse error at %d:%d:\n\t%w", line, col, innerErr)
 }
 
@@ -65,11 +65,102 @@ func TestInfixExpression(t *testing.T) {
 }
 
 func TestPipeDefStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"pipe noop() { }", "pipe noop() {  }"},
+		{"pipe enrich(threshold: int, tags: array<string>, extra) {\n x = threshold\n}", "pipe enrich(threshold: int, tags: array<string>, extra) { x = threshold }"},
+		{"pipe nested(m: map<array<int>>) { m }", "pipe nested(m: map<array<int>>) { m }"},
+		{"pipe tag(name, value = 'true') { }", "pipe tag(name, value = \"true\") {  }"},
+		{"pipe tag(name, value: string = 'a' + 'b', ...rest: int) { }", "pipe tag(name, value: string = (\"a\" + \"b\"), ...rest: int) {  }"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Fatalf("expected len of program to be 1. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.PipeDefinition)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.PipeDefinition. got=%T", program.Statements[0])
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, stmt.String()); !isEq {
+			t.Errorf("wrong pipe definition: %s", failMsg)
+		}
+	}
 
+	invalid := []struct {
+		input string
+		want  string
+	}{
+		{"pipe (a) { }", "parse error at 1:6:\n\tunexpected sequence: ("},
+		{"pipe p(a, a) { }", "parse error at 1:11:\n\tduplicate param \"a\""},
+		{"pipe p(a: array<int) { }", "parse error at 1:20:\n\tunexpected sequence: )"},
+		{"pipe p(...a, b) { }", "parse error at 1:11:\n\tvariadic param \"a\" must be the last param"},
+		{"pipe p(...a = []) { }", "parse error at 1:11:\n\tvariadic param \"a\" can't have a default value"},
+		{"pipe p(a = ) { }", "parse error at 1:12:\n\tunexpected sequence: )"},
+	}
+	for _, tt := range invalid {
+		_, err := New(lexer.New([]rune(tt.input))).ParseProgram()
+		if err == nil {
+			t.Fatalf("%s: expected a parse error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("wrong error: %s", failMsg)
+		}
+	}
 }
 
-func TestPipeCallStatement(t *testing.T) {
+func TestFunctionDefinition(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"fn host(h: string) { lower(h) }", "fn host(h: string) { lower(h) }"},
+		{"fn fact(n) {\n if n < 2 { 1 } else { n * fact(n - 1) }\n}", "fn fact(n) { if (n < 2) { 1 } else { (n * fact((n - 1))) } }"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		stmt, ok := program.Statements[0].(*ast.FunctionDefinition)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.FunctionDefinition. got=%T", program.Statements[0])
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, stmt.String()); !isEq {
+			t.Errorf("wrong fn definition: %s", failMsg)
+		}
+	}
 
+	_, err := New(lexer.New([]rune("fn f(a, a) { }"))).ParseProgram()
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if isEq, failMsg := testutils.Equal("parse error at 1:9:\n\tduplicate param \"a\"", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+}
+
+func TestPipeCallStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"| enrich(5, tags: ['a'])", "| enrich(5, tags: [\"a\"])"},
+		{"| normalize", "| normalize()"},
+		{"| norm.clean(field: 'a')", "| norm.clean(field: \"a\")"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Fatalf("expected len of program to be 1. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.PipeInvocation)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.PipeInvocation. got=%T", program.Statements[0])
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, stmt.String()); !isEq {
+			t.Errorf("wrong pipe invocation: %s", failMsg)
+		}
+	}
 }
 
 func TestNamedArgsValid(t *testing.T) {
@@ -188,6 +279,45 @@ func TestDotAccessExpression(t *testing.T) {
 	}
 }
 
+func TestKeywordFieldNames(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$dest.x = $src.try", "$dest.x = $src.try"},
+		{"$dest.catch = $src.a.catch", "$dest.catch = $src.a.catch"},
+		{"$dest.schema = $src.schema.version", "$dest.schema = $src.schema.version"},
+		{"$dest.let = $src.let", "$dest.let = $src.let"},
+		{"$dest.x = $src.const.value", "$dest.x = $src.const.value"},
+		{"$dest.x = $src.as", "$dest.x = $src.as"},
+		{"$dest.import = $src.import", "$dest.import = $src.import"},
+		{"$dest.fn = $src.fn", "$dest.fn = $src.fn"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, program.String()); !isEq {
+			t.Errorf("%s: wrong program: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestMemAccessorExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$src.message", "$src.message"},
+		{"$dest.a.b", "$dest.a.b"},
+		{"upper($src.name) + $env.suffix", "(upper($src.name) + $env.suffix)"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, program.String()); !isEq {
+			t.Errorf("wrong accessor expression: %s", failMsg)
+		}
+	}
+}
+
 func TestFunctionCallExpression(t *testing.T) {
 	input := "myFunc(a, b, c)"
 	program := setupTestWithInput(t, input)
@@ -251,6 +381,46 @@ func TestArrowFunctionExpression(t *testing.T) {
 	testInfixExpression(t, arrow.QueryExpression, "c", "||", "d")
 }
 
+func TestPipeExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a | upper()", "(a | upper())"},
+		{"a | upper", "(a | upper())"},
+		{"a + b | replace('x', new: 'y') | lower()", "(((a + b) | replace(\"x\", new: \"y\")) | lower())"},
+		{"x = a | f() == b", "x = ((a | f()) == b)"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		if isEq, failMsg := testutils.Equal(tt.want, program.String()); !isEq {
+			t.Errorf("wrong pipe expression: %s", failMsg)
+		}
+	}
+}
+
+func TestArrayLiteral(t *testing.T) {
+	input := "[1, 'two', three]"
+	program := setupTestWithInput(t, input)
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected len of parsed program to be 1. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	arr, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(arr.Elements) != 3 {
+		t.Fatalf("expected len of array elements to be 3. got=%d", len(arr.Elements))
+	}
+	testLiteralExpression(t, arr.Elements[0], 1)
+	testLiteralExpression(t, arr.Elements[1], "two")
+	testLiteralExpression(t, arr.Elements[2], "three")
+}
+
 func TestAssignStatement(t *testing.T) {
 	input := "a = 2"
 	program := setupTestWithInput(t, input)
@@ -265,6 +435,171 @@ func TestAssignStatement(t *testing.T) {
 
 }
 
+func TestSchemaStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"schema $src { }", "schema $src {  }"},
+		{"schema $src { user: { name: string, age?: int }, tags?: array<string> }", "schema $src { user: { name: string, age?: int }, tags?: array<string> }"},
+		{"schema $src {\n \"user-agent\": string\n ip: ip\n}", "schema $src { user-agent: string, ip: ip }"},
+		{"schema $dest { level: string enum('info', 'warn') maxlen(4)\n code: int enum(1, 2) }", "schema $dest { level: string enum(\"info\", \"warn\") maxlen(4), code: int enum(1, 2) }"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Fatalf("expected len of program to be 1. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.SchemaStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.SchemaStatement. got=%T", program.Statements[0])
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, stmt.String()); !isEq {
+			t.Errorf("wrong schema: %s", failMsg)
+		}
+	}
+
+	invalid := []struct {
+		input string
+		want  string
+	}{
+		{"schema x { }", "parse error at 1:8:\n\tunexpected sequence: x"},
+		{"schema $src { a: int, a: string }", "parse error at 1:23:\n\tduplicate field \"a\""},
+		{"schema $dest { a: string maxlen 4 }", "parse error at 1:33:\n\tunexpected sequence: 4"},
+	}
+	for _, tt := range invalid {
+		_, err := New(lexer.New([]rune(tt.input))).ParseProgram()
+		if err == nil {
+			t.Fatalf("%s: expected a parse error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("wrong error: %s", failMsg)
+		}
+	}
+}
+
+func TestDeclarationStatement(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		keyword string
+	}{
+		{"let x = 1", "let x = 1", "let"},
+		{"const limit: int = 60 * 60", "const limit: int = (60 * 60)", "const"},
+		{"x = 1", "x = 1", ""},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		stmt, ok := program.Statements[0].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.AssignStatement. got=%T", program.Statements[0])
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, stmt.String()); !isEq {
+			t.Errorf("wrong statement: %s", failMsg)
+		}
+		if isEq, failMsg := testutils.Equal(tt.keyword, stmt.Keyword); !isEq {
+			t.Errorf("wrong keyword: %s", failMsg)
+		}
+	}
+
+	invalid := []struct {
+		input string
+		want  string
+	}{
+		{"const = 1", "parse error at 1:7:\n\tunexpected sequence: ="},
+		{"let x", "parse error at 1:6:\n\tunexpected sequence: ;"},
+	}
+	for _, tt := range invalid {
+		_, err := New(lexer.New([]rune(tt.input))).ParseProgram()
+		if err == nil {
+			t.Fatalf("%s: expected a parse error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("wrong error: %s", failMsg)
+		}
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	program := setupTestWithInput(t, "import \"common/normalize.pl\" as norm\n| norm.clean()")
+	stmt, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	if isEq, failMsg := testutils.Equal("import \"common/normalize.pl\" as norm", stmt.String()); !isEq {
+		t.Errorf("wrong import: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal("norm", stmt.Alias.Value); !isEq {
+		t.Errorf("wrong alias: %s", failMsg)
+	}
+
+	invalid := []struct {
+		input string
+		want  string
+	}{
+		{"import common as norm", "parse error at 1:8:\n\tunexpected sequence: common"},
+		{"import 'a.pl'", "parse error at 1:14:\n\tunexpected sequence: ;"},
+	}
+	for _, tt := range invalid {
+		_, err := New(lexer.New([]rune(tt.input))).ParseProgram()
+		if err == nil {
+			t.Fatalf("%s: expected a parse error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("wrong error: %s", failMsg)
+		}
+	}
+}
+
+func TestFieldAssignStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$dest.a = 1", "$dest.a = 1"},
+		{"$dest.user.name = upper($src.name)", "$dest.user.name = upper($src.name)"},
+	}
+	for _, tt := range tests {
+		program := setupTestWithInput(t, tt.input)
+		stmt, ok := program.Statements[0].(*ast.FieldAssignStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.FieldAssignStatement. got=%T", program.Statements[0])
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, stmt.String()); !isEq {
+			t.Errorf("wrong statement: %s", failMsg)
+		}
+	}
+
+	// reading from $dest is still an expression, and assigning all of it is a regular assignment
+	program := setupTestWithInput(t, "$dest = $src\n$dest.a == 1")
+	if _, ok := program.Statements[0].(*ast.AssignStatement); !ok {
+		t.Errorf("program.Statements[0] is not *ast.AssignStatement. got=%T", program.Statements[0])
+	}
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+		t.Errorf("program.Statements[1] is not *ast.ExpressionStatement. got=%T", program.Statements[1])
+	}
+
+	_, err := New(lexer.New([]rune("$dest.a() = 1"))).ParseProgram()
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if isEq, failMsg := testutils.Equal("parse error at 1:1:\n\texpect a field path like $dest.a.b on the left side of assign statement", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+}
+
+func TestAnnotatedAssignStatement(t *testing.T) {
+	program := setupTestWithInput(t, "ids: array<int> = [1, 2]")
+	stmt, ok := program.Statements[0].(*ast.AssignStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.AssignStatement. got=%T", program.Statements[0])
+	}
+	testIdentifier(t, stmt.Name, "ids")
+	if isEq, failMsg := testutils.Equal("ids: array<int> = [1, 2]", stmt.String()); !isEq {
+		t.Errorf("wrong assign statement: %s", failMsg)
+	}
+}
+
 func TestFloatLiteral(t *testing.T) {
 	tests := []struct {
 		input string
@@ -445,6 +780,53 @@ func TestIfElseStatement(t *testing.T) {
 	testLiteralExpression(t, blockEntry.Expression, 6)
 }
 
+func TestTryCatchExpression(t *testing.T) {
+	input := `try {
+				1
+			} catch err {
+				2
+			}`
+
+	program := setupTestWithInput(t, input)
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected len of parsed program to be 1. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	tryExp, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.TryExpression. got=%T", stmt.Expression)
+	}
+	if len(tryExp.Block.Statements) != 1 {
+		t.Fatalf("expected len of try block to be 1. got=%d", len(tryExp.Block.Statements))
+	}
+	blockEntry, ok := tryExp.Block.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("tryExp.Block.Statements[0] is not *ast.ExpressionStatement. got=%T", tryExp.Block.Statements[0])
+	}
+	testLiteralExpression(t, blockEntry.Expression, 1)
+	testIdentifier(t, tryExp.ErrorName, "err")
+	if len(tryExp.Catch.Statements) != 1 {
+		t.Fatalf("expected len of catch block to be 1. got=%d", len(tryExp.Catch.Statements))
+	}
+	catchEntry, ok := tryExp.Catch.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("tryExp.Catch.Statements[0] is not *ast.ExpressionStatement. got=%T", tryExp.Catch.Statements[0])
+	}
+	testLiteralExpression(t, catchEntry.Expression, 2)
+}
+
+func TestTryWithoutCatch(t *testing.T) {
+	input := "try { 1 }"
+	p := New(lexer.New([]rune(input)))
+	_, err := p.ParseProgram()
+	if err == nil {
+		t.Fatal("expected an error for a try block without a catch block. got no error")
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -553,7 +935,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
-func testIntegerLiteral(t *testing.T, integerExpression ast.Expression, value int) bool {
+func testIntegerLiteral(t *testing.T, integerExpression ast.Expression, value int64) bool {
 	integer, ok := integerExpression.(*ast.IntegerLiteral)
 	if !ok {
 		t.Fatalf("testIntegerLiteral: passed expression is not an *ast.IntegerLiteral. got type=%T", integerExpression)
@@ -593,9 +975,9 @@ func testLiteralExpression(t *testing.T, expression ast.Expression, value any) b
 
 	switch v := value.(type) {
 	case int:
-		return testIntegerLiteral(t, expression, v)
+		return testIntegerLiteral(t, expression, int64(v))
 	case int64:
-		return testIntegerLiteral(t, expression, int(v))
+		return testIntegerLiteral(t, expression, v)
 	case float64:
 		return testFloatLiteral(t, expression, v)
 	case bool:
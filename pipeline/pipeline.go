@@ -0,0 +1,249 @@
+// Package pipeline compiles a program once and runs it against each record,
+// validating each record against the schema for $src and each document it emits against the schema for $dest.
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/builtins"
+	"github.com/hudsn/pipelang/checker"
+	"github.com/hudsn/pipelang/evaluator"
+	"github.com/hudsn/pipelang/imports"
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/resolver"
+	"github.com/hudsn/pipelang/schema"
+)
+
+type Pipeline struct {
+	program   *ast.Program
+	evaluator *evaluator.Evaluator
+	source    *schema.Schema // nil when records aren't validated
+	dest      *schema.Schema // nil when output isn't validated
+	policy    schema.Policy
+	warnings  []*resolver.Error
+}
+
+type config struct {
+	registry *builtins.Registry
+	source   *schema.Schema
+	dest     *schema.Schema
+	policy   schema.Policy
+	imports  imports.Resolver
+	maxDepth int
+}
+
+type Option func(c *config)
+
+// replaces the registry used to resolve function calls, for both type checking and evaluation.
+func WithRegistry(r *builtins.Registry) Option {
+	return func(c *config) {
+		c.registry = r
+	}
+}
+
+// validates $src against a schema loaded by the host, like one from schema.FromJSONSchema.
+// it's an error to also declare a schema for $src in the program.
+func WithSourceSchema(s *schema.Schema) Option {
+	return func(c *config) {
+		c.source = s
+	}
+}
+
+// validates the $dest each record produces against a schema loaded by the host.
+// it's an error to also declare a schema for $dest in the program.
+func WithDestSchema(s *schema.Schema) Option {
+	return func(c *config) {
+		c.dest = s
+	}
+}
+
+// sets what happens to records that don't match the schema for $src, or whose $dest doesn't match the schema for $dest.
+// the default is schema.Abort.
+func WithSchemaPolicy(p schema.Policy) Option {
+	return func(c *config) {
+		c.policy = p
+	}
+}
+
+// lets the program import files through r, like imports.FS(os.DirFS("pipelines")).
+// without one, importing a file is an error.
+func WithImportResolver(r imports.Resolver) Option {
+	return func(c *config) {
+		c.imports = r
+	}
+}
+
+// sets how deeply fn calls and pipe invocations can nest before a record fails, see evaluator.WithMaxCallDepth.
+func WithMaxCallDepth(n int) Option {
+	return func(c *config) {
+		c.maxDepth = n
+	}
+}
+
+// Compile parses, resolves and type checks a program, and returns every error it finds joined together.
+// problems that don't stop the program from running, like unused variables, are kept as warnings instead.
+func Compile(source string, opts ...Option) (*Pipeline, error) {
+	cfg := &config{registry: builtins.Default(), policy: schema.Abort}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	l := lexer.New([]rune(source))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+	input := l.InputRunes()
+
+	src, err := resolveSchema(program, input, "$src", cfg.source)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := resolveSchema(program, input, "$dest", cfg.dest)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := []error{}
+	var warnings []*resolver.Error
+	for _, resolveErr := range resolver.New(input).Resolve(program) {
+		if resolveErr.Warning {
+			warnings = append(warnings, resolveErr)
+		} else {
+			errs = append(errs, resolveErr)
+		}
+	}
+
+	checkOpts := []checker.Option{checker.WithRegistry(cfg.registry)}
+	if src != nil {
+		checkOpts = append(checkOpts, checker.WithSourceSchema(src))
+	}
+	if dest != nil {
+		checkOpts = append(checkOpts, checker.WithDestSchema(dest))
+	}
+	for _, typeErr := range checker.New(input, checkOpts...).Check(program) {
+		errs = append(errs, typeErr)
+	}
+	evalOpts := []evaluator.Option{evaluator.WithRegistry(cfg.registry)}
+	if cfg.maxDepth > 0 {
+		evalOpts = append(evalOpts, evaluator.WithMaxCallDepth(cfg.maxDepth))
+	}
+	if cfg.imports != nil {
+		loader := imports.NewLoader(cfg.imports)
+		moduleErrs, err := checkImports(program, loader, cfg.registry)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, moduleErrs...)
+		evalOpts = append(evalOpts, evaluator.WithLoader(loader))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	ev := evaluator.New(input, evalOpts...)
+	if err := ev.Prepare(program); err != nil {
+		return nil, err
+	}
+	return &Pipeline{program: program, evaluator: ev, source: src, dest: dest, policy: cfg.policy, warnings: warnings}, nil
+}
+
+// Warnings returns the problems Compile found that don't stop the program from running, like unused variables.
+func (p *Pipeline) Warnings() []*resolver.Error {
+	return p.warnings
+}
+
+// loads the files the program imports, and resolves and type checks each one on its own.
+// their errors are prefixed with the file's path. unused variables of imported files aren't reported, since their constants are meant to be used by the files importing them.
+func checkImports(program *ast.Program, loader *imports.Loader, registry *builtins.Registry) ([]error, error) {
+	for _, statement := range program.Statements {
+		if node, ok := statement.(*ast.ImportStatement); ok {
+			if _, err := loader.Load(node.Path.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	errs := []error{}
+	for _, mod := range loader.Modules() {
+		for _, resolveErr := range resolver.New(mod.Input).Resolve(mod.Program) {
+			if !resolveErr.Warning {
+				errs = append(errs, fmt.Errorf("%s: %w", mod.Path, resolveErr))
+			}
+		}
+		for _, typeErr := range checker.New(mod.Input, checker.WithRegistry(registry)).Check(mod.Program) {
+			errs = append(errs, fmt.Errorf("%s: %w", mod.Path, typeErr))
+		}
+	}
+	return errs, nil
+}
+
+// returns the schema the program declares for the target, or the one the host passed in
+func resolveSchema(program *ast.Program, input []rune, target string, passed *schema.Schema) (*schema.Schema, error) {
+	declared, err := schema.FromProgram(program, input, target)
+	if err != nil {
+		return nil, err
+	}
+	if declared == nil {
+		return passed, nil
+	}
+	if passed != nil {
+		return nil, fmt.Errorf("the program declares a schema for %s, so one can't also be passed to Compile", target)
+	}
+	return declared, nil
+}
+
+type Result struct {
+	Value            object.Object      // the value of the program's last statement. nil when the record was dropped before it was evaluated.
+	Output           object.Object      // the value of $dest. nil when the record or its output was dropped.
+	Dropped          bool               // set when the record or its output didn't match its schema under the schema.Drop policy
+	Violations       []schema.Violation // every way the record didn't match the schema for $src
+	OutputViolations []schema.Violation // every way $dest didn't match the schema for $dest
+}
+
+// Run evaluates the program against one record, starting with $dest as an empty map.
+// a runtime error is returned as an *object.Error, and a record or output that doesn't match its schema
+// under the schema.Abort policy as a *schema.ValidationError.
+func (p *Pipeline) Run(src object.Object) (*Result, error) {
+	ret := &Result{}
+	if p.source != nil {
+		ret.Violations = p.source.Validate(src)
+		if len(ret.Violations) > 0 {
+			switch p.policy {
+			case schema.Abort:
+				return nil, &schema.ValidationError{Target: "$src", Violations: ret.Violations}
+			case schema.Drop:
+				ret.Dropped = true
+				return ret, nil
+			}
+		}
+	}
+
+	env := object.NewEnvironment()
+	env.Set("$src", src)
+	env.Set("$dest", object.NewMap())
+	val := p.evaluator.Eval(p.program, env)
+	if errObj, ok := val.(*object.Error); ok {
+		return nil, errObj
+	}
+	ret.Value = val
+	ret.Output, _ = env.Get("$dest")
+
+	if p.dest != nil {
+		ret.OutputViolations = p.dest.Validate(ret.Output)
+		if len(ret.OutputViolations) > 0 {
+			switch p.policy {
+			case schema.Abort:
+				return nil, &schema.ValidationError{Target: "$dest", Violations: ret.OutputViolations}
+			case schema.Drop:
+				ret.Output = nil
+				ret.Dropped = true
+			}
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,223 @@
+package pipeline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/imports"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/schema"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+const program = "schema $src { user: { name: string }, count?: int }\n$src.user.name"
+
+func TestSchemaPolicies(t *testing.T) {
+	valid := decode(t, `{"user": {"name": "a"}}`)
+	invalid := decode(t, `{"user": {"name": 1}}`)
+
+	p := setupPipeline(t, program)
+	res, err := p.Run(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEq, failMsg := testutils.Equal("a", res.Value.Inspect()); !isEq {
+		t.Errorf("wrong value: %s", failMsg)
+	}
+	_, err = p.Run(invalid)
+	var validationErr *schema.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a *schema.ValidationError. got=%v", err)
+	}
+	if isEq, failMsg := testutils.Equal("$src does not match its schema: user.name: must be string. got=INTEGER", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+
+	p = setupPipeline(t, program, WithSchemaPolicy(schema.Drop))
+	res, err = p.Run(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Dropped || res.Value != nil || len(res.Violations) != 1 {
+		t.Errorf("expected the record to be dropped with 1 violation. got=%+v", res)
+	}
+
+	p = setupPipeline(t, program, WithSchemaPolicy(schema.Tag))
+	res, err = p.Run(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Dropped || res.Value.Inspect() != "1" || len(res.Violations) != 1 {
+		t.Errorf("expected the record to be processed with 1 violation. got=%+v", res)
+	}
+}
+
+func TestOutputSchema(t *testing.T) {
+	input := "schema $dest { level: string enum('info', 'warn'), message: string maxlen(5) }\n" +
+		"$dest.level = $src.level\n$dest.message = $src.message"
+	valid := decode(t, `{"level": "info", "message": "hi"}`)
+	invalid := decode(t, `{"level": "debug", "message": "too long"}`)
+
+	p := setupPipeline(t, input)
+	res, err := p.Run(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEq, failMsg := testutils.Equal(`{"level": info, "message": hi}`, res.Output.Inspect()); !isEq {
+		t.Errorf("wrong output: %s", failMsg)
+	}
+	_, err = p.Run(invalid)
+	var validationErr *schema.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a *schema.ValidationError. got=%v", err)
+	}
+	want := `$dest does not match its schema: level: must be one of "info", "warn". got="debug"; message: must be at most 5 characters. got=8`
+	if isEq, failMsg := testutils.Equal(want, err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+
+	p = setupPipeline(t, input, WithSchemaPolicy(schema.Drop))
+	res, err = p.Run(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Dropped || res.Output != nil || len(res.OutputViolations) != 2 {
+		t.Errorf("expected the output to be dropped with 2 violations. got=%+v", res)
+	}
+
+	p = setupPipeline(t, input, WithSchemaPolicy(schema.Tag))
+	res, err = p.Run(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Dropped || res.Output == nil || len(res.OutputViolations) != 2 {
+		t.Errorf("expected the output to be kept with 2 violations. got=%+v", res)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	hostSchema, err := schema.FromJSONSchema([]byte(`{"properties": {"user": {"type": "string"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input string
+		opts  []Option
+		want  string
+	}{
+		{"$src.usr", []Option{WithSourceSchema(hostSchema)}, "type error at 1:6:\n\t$src has no field \"usr\". did you mean \"user\"?"},
+		{"schema $src { a: int }\n$src.a + 'x'\n$src.b", nil, "type error at 2:1:\n\ttype mismatch: INTEGER|UINTEGER + STRING\ntype error at 3:6:\n\t$src has no field \"b\". did you mean \"a\"?"},
+		{"total = count + 1\n'a' - 1", nil, "resolve error at 1:9:\n\tidentifier not found: count\ntype error at 2:1:\n\ttype mismatch: STRING - INTEGER"},
+		{"const limit = 10\nlimit = 20\nlimit", nil, "resolve error at 2:1:\n\tcannot assign to constant limit"},
+		{"const limit = 10 / 0\nlimit", nil, "runtime error at 1:15:\n\tdivision by zero"},
+		{"schema $src { a: int }", []Option{WithSourceSchema(hostSchema)}, "the program declares a schema for $src, so one can't also be passed to Compile"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.input, tt.opts...)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	p := setupPipeline(t, "x = 1\nif $src.ok { y = 2 }\nx")
+	got := []string{}
+	for _, w := range p.Warnings() {
+		got = append(got, w.Error())
+	}
+	if isEq, failMsg := testutils.Equal("resolve warning at 2:14:\n\ty is assigned but never used", strings.Join(got, "\n")); !isEq {
+		t.Errorf("wrong warnings: %s", failMsg)
+	}
+}
+
+func TestImports(t *testing.T) {
+	files := imports.Map{
+		"common/normalize.pl": "import 'common/strings.pl' as str\nconst prefix = 'user:'\n" +
+			"pipe clean(field: string) { | str.lower(value: $src.name)\n$dest.id = prefix + field }",
+		"common/strings.pl": "pipe lower(value) { $dest.name = lower(value) }",
+		"broken.pl":         "pipe fail() { $dest.a = 1 / 0 }",
+		"typed.pl":          "pipe bad() { 'a' - 1 }",
+		"math.pl":           "fn double(n: int) { n * 2 }\nfn quadruple(n: int) { double(double(n)) }",
+	}
+	p := setupPipeline(t, "import 'common/normalize.pl' as norm\n| norm.clean(field: 'a')\nnorm.prefix", WithImportResolver(files))
+	res, err := p.Run(decode(t, `{"name": "ADA"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEq, failMsg := testutils.Equal(`{"id": user:a, "name": ada}`, res.Output.Inspect()); !isEq {
+		t.Errorf("wrong output: %s", failMsg)
+	}
+	if isEq, failMsg := testutils.Equal("user:", res.Value.Inspect()); !isEq {
+		t.Errorf("wrong value: %s", failMsg)
+	}
+
+	p = setupPipeline(t, "import 'math.pl' as m\nx = 3\n$dest.n = m.quadruple(m.double(n: x))", WithImportResolver(files))
+	res, err = p.Run(decode(t, `{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEq, failMsg := testutils.Equal(`{"n": 24}`, res.Output.Inspect()); !isEq {
+		t.Errorf("wrong output: %s", failMsg)
+	}
+	for input, want := range map[string]string{
+		"import 'math.pl' as m\nm.triple(1)":   "runtime error at 2:3:\n\tfn not found: m.triple",
+		"import 'math.pl' as m\nm.double('a')": "runtime error at 2:10:\n\targument \"n\" for double must be int. got=STRING",
+	} {
+		_, err = setupPipeline(t, input, WithImportResolver(files)).Run(decode(t, `{}`))
+		if err == nil {
+			t.Fatalf("%s: expected a runtime error", input)
+		}
+		if isEq, failMsg := testutils.Equal(want, err.Error()); !isEq {
+			t.Errorf("%s: wrong error: %s", input, failMsg)
+		}
+	}
+
+	p = setupPipeline(t, "import 'broken.pl' as b\n| b.fail()", WithImportResolver(files))
+	_, err = p.Run(decode(t, `{}`))
+	if err == nil {
+		t.Fatal("expected a runtime error")
+	}
+	if isEq, failMsg := testutils.Equal("runtime error at broken.pl:1:25:\n\tdivision by zero", err.Error()); !isEq {
+		t.Errorf("wrong error: %s", failMsg)
+	}
+
+	tests := []struct {
+		input string
+		opts  []Option
+		want  string
+	}{
+		{"import 'a.pl' as a\n| a.p()", nil, "runtime error at 1:1:\n\tcannot import \"a.pl\": no import resolver is configured"},
+		{"import 'missing.pl' as m\n| m.p()", []Option{WithImportResolver(files)}, "import \"missing.pl\": file does not exist"},
+		{"import 'typed.pl' as t\n| t.bad()", []Option{WithImportResolver(files)}, "typed.pl: type error at 1:14:\n\ttype mismatch: STRING - INTEGER"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.input, tt.opts...)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("%s: wrong error: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func setupPipeline(t *testing.T, input string, opts ...Option) *Pipeline {
+	p, err := Compile(input, opts...)
+	if err != nil {
+		t.Fatalf("setupPipeline: %s", err.Error())
+	}
+	return p
+}
+
+func decode(t *testing.T, doc string) object.Object {
+	obj, err := object.DecodeJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
@@ -0,0 +1,283 @@
+// Package resolver checks that every variable a program reads is assigned in a scope that's visible where it's read,
+// and reports variables that are assigned but never read.
+//
+// it follows the evaluator's scoping rules: blocks, pipe bodies, arrow functions and catch blocks each have a scope of their own.
+// assigning to a variable of an enclosing scope updates it, assigning to a new name creates it in the current scope,
+// and a declaration like let x = 1 or an annotation like x: int = 1 always creates a new variable that shadows any outer one.
+// variables declared with const can't be assigned or declared again in the same scope.
+// the alias of an import is a constant too, and imports can only be at the top level of a file.
+// names starting with $, like $src, are set by the host and never reported.
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/token"
+)
+
+type Error struct {
+	Message  string
+	Position token.Position
+	Line     int
+	Column   int
+	Warning  bool // set for unused variables, which don't stop a program from running
+}
+
+func (e *Error) Error() string {
+	kind := "resolve error"
+	if e.Warning {
+		kind = "resolve warning"
+	}
+	return fmt.Sprintf("%s at %d:%d:\n\t%s", kind, e.Line, e.Column, e.Message)
+}
+
+type Resolver struct {
+	// lexed input of the program, used to turn node positions into line and column numbers for errors.
+	input []rune
+
+	globals  map[string]bool
+	assigned map[string]bool // every name the program assigns anywhere, to tell a misplaced variable from a missing one
+	errors   []*Error
+}
+
+type Option func(r *Resolver)
+
+// names the host sets before running the program, besides the ones starting with $.
+func WithGlobals(names ...string) Option {
+	return func(r *Resolver) {
+		for _, name := range names {
+			r.globals[name] = true
+		}
+	}
+}
+
+// the input should come from lexer.InputRunes() after parsing, since the lexer may insert characters like semicolons.
+func New(input []rune, opts ...Option) *Resolver {
+	r := &Resolver{
+		input:   input,
+		globals: map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Resolve returns every problem in the program ordered by position, or nil if there are none.
+// unused variables are included as warnings.
+func (r *Resolver) Resolve(program *ast.Program) []*Error {
+	r.errors = nil
+	r.assigned = map[string]bool{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node, ok := node.(*ast.AssignStatement); ok {
+			r.assigned[node.Name.Value] = true
+		}
+		return true
+	})
+
+	s := newScope(nil)
+	for _, statement := range program.Statements {
+		r.resolve(statement, s)
+	}
+	r.closeScope(s)
+
+	sort.SliceStable(r.errors, func(i, j int) bool {
+		if r.errors[i].Line != r.errors[j].Line {
+			return r.errors[i].Line < r.errors[j].Line
+		}
+		return r.errors[i].Column < r.errors[j].Column
+	})
+	return r.errors
+}
+
+func (r *Resolver) resolve(node ast.Node, s *scope) {
+	switch node := node.(type) {
+
+	// statements
+	case *ast.BlockStatement:
+		inner := newScope(s)
+		for _, statement := range node.Statements {
+			r.resolve(statement, inner)
+		}
+		r.closeScope(inner)
+	case *ast.ExpressionStatement:
+		r.resolve(node.Expression, s)
+	case *ast.AssignStatement:
+		// the value is resolved first, so x = x + 1 needs an earlier x
+		r.resolve(node.Value, s)
+		r.resolveAssignment(node, s)
+	case *ast.FieldAssignStatement:
+		r.resolve(node.Value, s)
+		r.resolveName(node.Object, s)
+	case *ast.PipeDefinition:
+		r.resolveDefinition(node.Name, node.Params, node.Body, s)
+	case *ast.FunctionDefinition:
+		r.resolveDefinition(node.Name, node.Params, node.Body, s)
+	case *ast.PipeInvocation:
+		// a pipe that isn't found is reported by the checker and the evaluator
+		if node.Module != nil {
+			r.resolveName(node.Module, s)
+		} else if v := s.lookup(node.Call.Name.Value); v != nil {
+			v.used = true
+		}
+		r.resolveArguments(node.Call, s)
+	case *ast.ImportStatement:
+		if s.outer != nil {
+			r.newError(node.Position(), false, "imports must be at the top level of a file")
+			return
+		}
+		v := s.declare(node.Alias, true)
+		v.constant, v.imported = true, true
+
+	// expressions
+	case *ast.Identifier:
+		r.resolveName(node, s)
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			r.resolve(el, s)
+		}
+	case *ast.PrefixExpression:
+		r.resolve(node.Right, s)
+	case *ast.InfixExpression:
+		r.resolve(node.Left, s)
+		r.resolve(node.Right, s)
+	case *ast.IfExpression:
+		r.resolve(node.Condition, s)
+		r.resolve(node.Consequence, s)
+		if node.Alternative != nil {
+			r.resolve(node.Alternative, s)
+		}
+	case *ast.TryExpression:
+		r.resolve(node.Block, s)
+		inner := newScope(s)
+		if node.ErrorName != nil {
+			inner.declare(node.ErrorName, false)
+		}
+		r.resolve(node.Catch, inner)
+		r.closeScope(inner)
+	case *ast.DotAccess:
+		// the rest of the chain is field names, not variables, apart from the args of imported fns
+		r.resolve(node.Object, s)
+		r.resolveItem(node.Item, s)
+	case *ast.CallExpression:
+		r.resolveCall(node, s)
+	case *ast.PipeExpression:
+		r.resolve(node.Value, s)
+		r.resolveCall(node.Call, s)
+	case *ast.ArrowFunctionExpression:
+		inner := newScope(s)
+		inner.declare(node.Param, false)
+		r.resolve(node.QueryExpression, inner)
+		r.closeScope(inner)
+	}
+}
+
+func (r *Resolver) resolveAssignment(node *ast.AssignStatement, s *scope) {
+	name := node.Name.Value
+	if strings.HasPrefix(name, "$") {
+		return // set by the host, like $dest = $src, so never a local
+	}
+	if node.Keyword == "" && node.Type == nil {
+		v := s.lookup(name)
+		switch {
+		case v == nil:
+			s.declare(node.Name, true)
+		case v.constant:
+			r.newError(node.Name.Position(), false, "cannot assign to constant %s", name)
+		}
+		return
+	}
+
+	// declarations and annotations create a new variable, except for one already in this scope
+	if v, ok := s.vars[name]; ok && v.constant {
+		r.newError(node.Name.Position(), false, "cannot redeclare constant %s", name)
+		return
+	}
+	v := s.declare(node.Name, true)
+	v.constant = node.Keyword == "const"
+}
+
+// declares a pipe or fn before its body is resolved, so the body can invoke it.
+// default values are resolved in the scope the pipe or fn is defined in.
+func (r *Resolver) resolveDefinition(name *ast.Identifier, params []*ast.Parameter, body *ast.BlockStatement, s *scope) {
+	for _, p := range params {
+		if p.Default != nil {
+			r.resolve(p.Default, s)
+		}
+	}
+	s.declare(name, false)
+	inner := newScope(s)
+	for _, p := range params {
+		inner.declare(p.Name, false)
+	}
+	r.resolve(body, inner)
+	r.closeScope(inner)
+}
+
+// a function that isn't a fn in scope is a builtin, which is reported by the checker and the evaluator if it's missing
+func (r *Resolver) resolveCall(node *ast.CallExpression, s *scope) {
+	if v := s.lookup(node.Name.Value); v != nil {
+		v.used = true
+	}
+	r.resolveArguments(node, s)
+}
+
+// a call after a dot is a fn of an imported file, like: norm.clean(value)
+func (r *Resolver) resolveItem(item ast.Expression, s *scope) {
+	switch item := item.(type) {
+	case *ast.CallExpression:
+		r.resolveArguments(item, s)
+	case *ast.DotAccess:
+		r.resolveItem(item.Object, s)
+		r.resolveItem(item.Item, s)
+	}
+}
+
+func (r *Resolver) resolveArguments(node *ast.CallExpression, s *scope) {
+	for _, arg := range node.Arguments {
+		r.resolve(arg.Value, s)
+	}
+}
+
+func (r *Resolver) resolveName(node *ast.Identifier, s *scope) {
+	if strings.HasPrefix(node.Value, "$") || r.globals[node.Value] {
+		return
+	}
+	if v := s.lookup(node.Value); v != nil {
+		v.used = true
+		return
+	}
+	if r.assigned[node.Value] {
+		r.newError(node.Position(), false, "%s is used before it is assigned, or outside the block that assigns it", node.Value)
+		return
+	}
+	r.newError(node.Position(), false, "identifier not found: %s", node.Value)
+}
+
+// reports the variables of a scope that were never read
+func (r *Resolver) closeScope(s *scope) {
+	for _, v := range s.vars {
+		switch {
+		case v.used || !v.reportUnused:
+		case v.imported:
+			r.newError(v.name.Position(), true, "%s is imported but never used", v.name.Value)
+		default:
+			r.newError(v.name.Position(), true, "%s is assigned but never used", v.name.Value)
+		}
+	}
+}
+
+func (r *Resolver) newError(pos token.Position, warning bool, format string, a ...any) {
+	start, _ := pos.GetPosition()
+	line, col := token.LineAndColumn(r.input, max(start, 0))
+	r.errors = append(r.errors, &Error{
+		Message:  fmt.Sprintf(format, a...),
+		Position: pos,
+		Line:     line,
+		Column:   col,
+		Warning:  warning,
+	})
+}
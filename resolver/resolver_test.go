@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string // every error and warning
+	}{
+		{"x = 1\nx + $src.a", nil},
+		{"y + 1", []string{"resolve error at 1:1:\n\tidentifier not found: y"}},
+		{"y + 1\ny = 2\ny", []string{"resolve error at 1:1:\n\ty is used before it is assigned, or outside the block that assigns it"}},
+		{"x = x + 1", []string{"resolve warning at 1:1:\n\tx is assigned but never used", "resolve error at 1:5:\n\tx is used before it is assigned, or outside the block that assigns it"}},
+		{"if $src.ok { y = 1\ny }\ny", []string{"resolve error at 3:1:\n\ty is used before it is assigned, or outside the block that assigns it"}},
+		{"try { 1 } catch err { err.message }\nerr", []string{"resolve error at 2:1:\n\tidentifier not found: err"}},
+		{"x = 1\nif $src.ok { x = 2 }", []string{"resolve warning at 1:1:\n\tx is assigned but never used"}},
+		{"$dest = $src", nil},
+		{"if $src.ok { $dest = $src.user }", nil},
+		{"if $src.ok { y = 1 }", []string{"resolve warning at 1:14:\n\ty is assigned but never used"}},
+		// an annotation shadows the outer variable, so the outer one is never read
+		{"x = 1\nif $src.ok { x: int = 2\nx }", []string{"resolve warning at 1:1:\n\tx is assigned but never used"}},
+		// closures, params and catch names
+		{"n = 1\nsort([1], by: e ~> e + n)", nil},
+		{"pipe p(a, unused) { a }\n| p(1, 2)", nil},
+		{"pipe p() { missing }", []string{"resolve error at 1:12:\n\tidentifier not found: missing"}},
+		{"pipe count() { | count() }", nil},
+		{"try { 1 } catch err { 2 }", nil},
+		{"$dest.a = $src.b", nil},
+		// declarations and constants
+		{"x = 1\nif $src.ok { let x = 2\nx }\nx", nil},
+		{"const limit = 10\nlimit = 20\nlimit", []string{"resolve error at 2:1:\n\tcannot assign to constant limit"}},
+		{"const limit = 10\nif $src.ok { limit = 20 }\nlimit", []string{"resolve error at 2:14:\n\tcannot assign to constant limit"}},
+		{"const limit = 10\nlet limit = 20\nlimit", []string{"resolve error at 2:5:\n\tcannot redeclare constant limit"}},
+		{"const limit = 10\nif $src.ok { const limit = 20\nlimit }\nlimit", nil},
+		{"const limit = 10", []string{"resolve warning at 1:7:\n\tlimit is assigned but never used"}},
+		// functions
+		{"fn f(n) { n + 1 }\nf(1)", nil},
+		{"fn fact(n) { if n < 2 { 1 } else { n * fact(n - 1) } }", nil},
+		{"fn f() { missing }", []string{"resolve error at 1:10:\n\tidentifier not found: missing"}},
+		{"const sep = ','\nfn f(a, s = sep, ...rest) { a + s }\nf(1)", nil},
+		{"fn f(a = missing) { a }\nf()", []string{"resolve error at 1:10:\n\tidentifier not found: missing"}},
+		// imports
+		{"import 'a.pl' as a\n| a.clean()", nil},
+		{"import 'a.pl' as a", []string{"resolve warning at 1:18:\n\ta is imported but never used"}},
+		{"import 'a.pl' as a\na = 1\na", []string{"resolve error at 2:1:\n\tcannot assign to constant a"}},
+		{"| a.clean()", []string{"resolve error at 1:3:\n\tidentifier not found: a"}},
+		{"import 'a.pl' as a\nx = 1\na.double(n: x)", nil},
+		{"import 'a.pl' as a\na.double(missing)", []string{"resolve error at 2:10:\n\tidentifier not found: missing"}},
+		{"if $src.ok { import 'a.pl' as a }", []string{"resolve error at 1:14:\n\timports must be at the top level of a file"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, err := range setupResolveWithInput(t, tt.input) {
+			got = append(got, err.Error())
+		}
+		if isEq, failMsg := testutils.Equal(strings.Join(tt.want, "\n---\n"), strings.Join(got, "\n---\n")); !isEq {
+			t.Errorf("%s: wrong errors: %s", tt.input, failMsg)
+		}
+	}
+}
+
+func TestResolveGlobals(t *testing.T) {
+	l := lexer.New([]rune("threshold + 1"))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := New(l.InputRunes(), WithGlobals("threshold")).Resolve(program); len(errs) > 0 {
+		t.Errorf("unexpected error: %s", errs[0].Error())
+	}
+}
+
+func setupResolveWithInput(t *testing.T, input string) []*Error {
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatalf("setupResolveWithInput: %s", err.Error())
+	}
+	return New(l.InputRunes()).Resolve(program)
+}
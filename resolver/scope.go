@@ -0,0 +1,40 @@
+package resolver
+
+import "github.com/hudsn/pipelang/ast"
+
+type variable struct {
+	name         *ast.Identifier // where the variable was created
+	used         bool
+	reportUnused bool // false for names that are bound rather than assigned, like params and pipes
+	constant     bool
+	imported     bool // set for the alias of an import, like norm in: import "common/normalize.pl" as norm
+}
+
+type scope struct {
+	vars  map[string]*variable
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: map[string]*variable{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) *variable {
+	for current := s; current != nil; current = current.outer {
+		if v, ok := current.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// creates a variable in this scope, shadowing any outer one with the same name, and returns it.
+// creating one the scope already has returns the existing one, so it's still reported once.
+func (s *scope) declare(name *ast.Identifier, reportUnused bool) *variable {
+	if v, ok := s.vars[name.Value]; ok {
+		return v
+	}
+	v := &variable{name: name, reportUnused: reportUnused}
+	s.vars[name.Value] = v
+	return v
+}
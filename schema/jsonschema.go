@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hudsn/pipelang/object"
+)
+
+// the JSON Schema type names, and the type each one maps to
+var jsonSchemaTypes = map[string][]object.ObjectType{
+	"string":  {object.STRING_OBJ},
+	"integer": {object.INTEGER_OBJ, object.UINTEGER_OBJ},
+	"number":  {object.INTEGER_OBJ, object.UINTEGER_OBJ, object.FLOAT_OBJ, object.DECIMAL_OBJ},
+	"boolean": {object.BOOLEAN_OBJ},
+	"object":  {object.MAP_OBJ},
+	"array":   {object.ARRAY_OBJ},
+	"null":    {object.NULL_OBJ},
+}
+
+type jsonSchema struct {
+	Type       any                    `json:"type"` // a type name, or a list of them
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *jsonSchema            `json:"items"`
+	Enum       []json.RawMessage      `json:"enum"` // kept raw so numbers are decoded exactly
+	MaxLength  int                    `json:"maxLength"`
+}
+
+// FromJSONSchema reads the subset of JSON Schema that maps onto schema fields: type, properties, required, items, enum and maxLength.
+// properties that aren't required are optional, and other keywords are ignored.
+func FromJSONSchema(data []byte) (*Schema, error) {
+	var root jsonSchema
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	spec, err := root.typeSpec("")
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(spec.Kinds, []object.ObjectType{object.MAP_OBJ}) {
+		return nil, fmt.Errorf("invalid JSON schema: the root type must be object. got=%s", spec.Name)
+	}
+	fields, err := root.fields("")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Fields: fields}, nil
+}
+
+// properties are sorted by name, since JSON objects have no order
+func (js *jsonSchema) fields(prefix string) (Fields, error) {
+	names := []string{}
+	for name := range js.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := Fields{}
+	for _, name := range names {
+		prop := js.Properties[name]
+		if prop == nil {
+			return nil, fmt.Errorf("invalid JSON schema: property %s%s must be an object", prefix, name)
+		}
+		spec, err := prop.typeSpec(prefix + name)
+		if err != nil {
+			return nil, err
+		}
+		f := &Field{Name: name, Type: spec, Optional: !slices.Contains(js.Required, name), MaxLength: prop.MaxLength}
+		for _, raw := range prop.Enum {
+			val, err := object.DecodeJSON(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON schema: enum of %s%s: %w", prefix, name, err)
+			}
+			f.Enum = append(f.Enum, val)
+		}
+		if prop.Properties != nil {
+			if f.Fields, err = prop.fields(prefix + name + "."); err != nil {
+				return nil, err
+			}
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+func (js *jsonSchema) typeSpec(path string) (*object.TypeSpec, error) {
+	names := []string{}
+	switch t := js.Type.(type) {
+	case nil:
+		if js.Properties != nil {
+			names = append(names, "object")
+		}
+	case string:
+		names = append(names, t)
+	case []any:
+		for _, name := range t {
+			s, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON schema: type of %s must be a string or a list of strings", describePath(path))
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid JSON schema: type of %s must be a string or a list of strings", describePath(path))
+	}
+	if len(names) == 0 {
+		return object.NewTypeSpec("any", nil)
+	}
+
+	ret := &object.TypeSpec{Name: strings.Join(names, "|")}
+	for _, name := range names {
+		kinds, ok := jsonSchemaTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid JSON schema: unknown type %q for %s", name, describePath(path))
+		}
+		for _, k := range kinds {
+			if !slices.Contains(ret.Kinds, k) {
+				ret.Kinds = append(ret.Kinds, k)
+			}
+		}
+	}
+	if js.Items != nil && slices.Equal(names, []string{"array"}) {
+		elem, err := js.Items.typeSpec(path + "[]")
+		if err != nil {
+			return nil, err
+		}
+		ret.Elem = elem
+	}
+	return ret, nil
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the root"
+	}
+	return path
+}
@@ -0,0 +1,307 @@
+// Package schema describes the fields a document is expected to have, and validates documents against them.
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hudsn/pipelang/ast"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/token"
+)
+
+type Schema struct {
+	Fields Fields
+}
+
+type Field struct {
+	Name     string
+	Type     *object.TypeSpec // a field with nested fields is always a map
+	Optional bool             // optional fields can be missing or null
+	Fields   Fields           // the declared fields of a map, or nil when its fields aren't declared
+
+	Enum      []object.Object // the only values the field can have. empty for any value of its type.
+	MaxLength int             // the most characters a string field can have. 0 for no limit.
+}
+
+type Fields []*Field
+
+// Get returns the field with the given name, or nil if it isn't declared.
+func (fs Fields) Get(name string) *Field {
+	for _, f := range fs {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Suggest returns the declared name closest to a name that isn't declared, for catching typos.
+// returns an empty string when nothing is close.
+func (fs Fields) Suggest(name string) string {
+	best, bestDistance := "", 3 // anything further than 2 edits away is more likely a different name than a typo
+	for _, f := range fs {
+		if d := editDistance(name, f.Name); d < bestDistance {
+			best, bestDistance = f.Name, d
+		}
+	}
+	return best
+}
+
+// Policy is what a pipeline does with a record that doesn't match its schema.
+type Policy int
+
+const (
+	Abort Policy = iota // fails the run with a *ValidationError
+	Drop                // skips the record without evaluating it
+	Tag                 // evaluates the record anyway, and reports the violations with its result
+)
+
+type Violation struct {
+	Path    string // the dotted path of the field, like user.name. empty for the document itself.
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+type ValidationError struct {
+	Target     string // the document that didn't match, like $src
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	violations := []string{}
+	for _, v := range e.Violations {
+		violations = append(violations, v.String())
+	}
+	return fmt.Sprintf("%s does not match its schema: %s", e.Target, strings.Join(violations, "; "))
+}
+
+// Validate returns every way doc doesn't match the schema, or nil if it matches.
+// fields the schema doesn't declare are allowed.
+func (s *Schema) Validate(doc object.Object) []Violation {
+	m, ok := doc.(*object.Map)
+	if !ok {
+		return []Violation{{Message: fmt.Sprintf("document must be a MAP. got=%s", doc.Type())}}
+	}
+	var ret []Violation
+	validateFields(s.Fields, m, "", &ret)
+	return ret
+}
+
+func validateFields(fields Fields, m *object.Map, prefix string, violations *[]Violation) {
+	for _, f := range fields {
+		path := prefix + f.Name
+		val, found := m.Pairs[f.Name]
+		if (!found || val.Type() == object.NULL_OBJ) && f.Optional {
+			continue
+		}
+		if !found {
+			*violations = append(*violations, Violation{Path: path, Message: "missing required field"})
+			continue
+		}
+		if mismatch := f.Type.Check(val); mismatch != nil {
+			*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("must be %s. got=%s", f.Type.String(), mismatch)})
+			continue
+		}
+		if len(f.Enum) > 0 && !containsValue(f.Enum, val) {
+			*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("must be one of %s. got=%s", describeValues(f.Enum), describeValue(val))})
+			continue
+		}
+		if str, ok := val.(*object.String); ok && f.MaxLength > 0 {
+			if length := utf8.RuneCountInString(str.Value); length > f.MaxLength {
+				*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("must be at most %d characters. got=%d", f.MaxLength, length)})
+			}
+		}
+		if nested, ok := val.(*object.Map); ok && f.Fields != nil {
+			validateFields(f.Fields, nested, path+".", violations)
+		}
+	}
+}
+
+// FromProgram returns the schema a top-level schema block declares for the target, like $src or $dest,
+// or nil if the program doesn't declare one.
+func FromProgram(program *ast.Program, input []rune, target string) (*Schema, error) {
+	var ret *Schema
+	for _, statement := range program.Statements {
+		node, ok := statement.(*ast.SchemaStatement)
+		if !ok || node.Target.Value != target {
+			continue
+		}
+		if ret != nil {
+			return nil, newError(input, node.Position(), fmt.Errorf("%s already has a schema", node.Target.Value))
+		}
+		s, err := FromAST(node, input)
+		if err != nil {
+			return nil, err
+		}
+		ret = s
+	}
+	return ret, nil
+}
+
+// FromAST converts a schema block. the input is used for the line and column of errors, the same as for the evaluator.
+func FromAST(node *ast.SchemaStatement, input []rune) (*Schema, error) {
+	fields, err := fieldsFromAST(node.Fields, input)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Fields: fields}, nil
+}
+
+func fieldsFromAST(nodes []*ast.SchemaField, input []rune) (Fields, error) {
+	ret := Fields{}
+	for _, node := range nodes {
+		f := &Field{Name: node.Name.Value, Optional: node.Optional}
+		if node.Type == nil {
+			nested, err := fieldsFromAST(node.Fields, input)
+			if err != nil {
+				return nil, err
+			}
+			f.Type, _ = object.NewTypeSpec("map", nil)
+			f.Fields = nested
+		} else {
+			spec, err := typeFromAST(node.Type, input)
+			if err != nil {
+				return nil, err
+			}
+			f.Type = spec
+		}
+		for _, c := range node.Constraints {
+			if err := applyConstraint(f, c, input); err != nil {
+				return nil, err
+			}
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+func applyConstraint(f *Field, node *ast.SchemaConstraint, input []rune) error {
+	switch node.Name.Value {
+	case "enum":
+		if len(node.Args) == 0 {
+			return newError(input, node.Position(), fmt.Errorf("enum needs at least one value"))
+		}
+		for _, arg := range node.Args {
+			val, ok := literalValue(arg)
+			if !ok {
+				return newError(input, arg.Position(), fmt.Errorf("enum values must be literals. got=%s", arg.String()))
+			}
+			if !f.Type.Matches(val) {
+				return newError(input, arg.Position(), fmt.Errorf("enum value %s is not a %s", describeValue(val), f.Type.String()))
+			}
+			f.Enum = append(f.Enum, val)
+		}
+	case "maxlen":
+		if f.Type.Name != "string" {
+			return newError(input, node.Position(), fmt.Errorf("maxlen only applies to strings. got=%s", f.Type.String()))
+		}
+		var limit *ast.IntegerLiteral
+		if len(node.Args) == 1 {
+			limit, _ = node.Args[0].(*ast.IntegerLiteral)
+		}
+		if limit == nil || limit.Value <= 0 {
+			return newError(input, node.Position(), fmt.Errorf("maxlen takes one positive integer"))
+		}
+		f.MaxLength = int(limit.Value)
+	default:
+		return newError(input, node.Name.Position(), fmt.Errorf("unknown constraint %q. expected enum or maxlen", node.Name.Value))
+	}
+	return nil
+}
+
+func literalValue(node ast.Expression) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}, true
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}, true
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}, true
+	case *ast.Boolean:
+		return &object.Boolean{Value: node.Value}, true
+	}
+	return nil, false
+}
+
+// numbers are compared by value, so an enum of 1 accepts 1.0 too
+func containsValue(values []object.Object, val object.Object) bool {
+	for _, v := range values {
+		if r1, ok := object.ToRat(v); ok {
+			if r2, ok := object.ToRat(val); ok && r1.Cmp(r2) == 0 {
+				return true
+			}
+			continue
+		}
+		if v.Type() == val.Type() && v.Inspect() == val.Inspect() {
+			return true
+		}
+	}
+	return false
+}
+
+func describeValue(val object.Object) string {
+	if str, ok := val.(*object.String); ok {
+		return strconv.Quote(str.Value)
+	}
+	return val.Inspect()
+}
+
+func describeValues(values []object.Object) string {
+	ret := []string{}
+	for _, v := range values {
+		ret = append(ret, describeValue(v))
+	}
+	return strings.Join(ret, ", ")
+}
+
+func typeFromAST(node *ast.TypeAnnotation, input []rune) (*object.TypeSpec, error) {
+	var elem *object.TypeSpec
+	if node.Elem != nil {
+		var err error
+		if elem, err = typeFromAST(node.Elem, input); err != nil {
+			return nil, err
+		}
+	}
+	spec, err := object.NewTypeSpec(node.Name, elem)
+	if err != nil {
+		return nil, newError(input, node.Position(), err)
+	}
+	return spec, nil
+}
+
+func newError(input []rune, pos token.Position, err error) error {
+	start, _ := pos.GetPosition()
+	line, col := token.LineAndColumn(input, max(start, 0))
+	return fmt.Errorf("schema error at %d:%d:\n\t%w", line, col, err)
+}
+
+// the number of single character insertions, deletions or substitutions it takes to turn a into b
+func editDistance(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current := make([]int, len(br)+1)
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hudsn/pipelang/lexer"
+	"github.com/hudsn/pipelang/object"
+	"github.com/hudsn/pipelang/parser"
+	"github.com/hudsn/pipelang/utils/testutils"
+)
+
+func TestValidate(t *testing.T) {
+	s := setupSchemaWithInput(t, "schema $src { user: { name: string, age?: int }, tags?: array<string> }")
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{`{"user": {"name": "a", "age": 3}, "tags": ["x"]}`, nil},
+		{`{"user": {"name": "a", "age": null}, "extra": 1}`, nil},
+		{`{}`, []string{"user: missing required field"}},
+		{`{"user": {"age": "3"}}`, []string{"user.name: missing required field", "user.age: must be int. got=STRING"}},
+		{`{"user": "a", "tags": [1]}`, []string{"user: must be map. got=STRING", "tags: must be array<string>. got=INTEGER at [0]"}},
+		{`[]`, []string{"document must be a MAP. got=ARRAY"}},
+	}
+	for _, tt := range tests {
+		doc, err := object.DecodeJSON([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, v := range s.Validate(doc) {
+			got = append(got, v.String())
+		}
+		if isEq, failMsg := testutils.Equal(strings.Join(tt.want, "; "), strings.Join(got, "; ")); !isEq {
+			t.Errorf("%s: wrong violations: %s", tt.doc, failMsg)
+		}
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	s := setupSchemaWithInput(t, "schema $src { level: string enum('info', 'warn') maxlen(4), code?: number enum(1, 2.5) }")
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{`{"level": "info", "code": 1.0}`, nil},
+		{`{"level": "warn", "code": 2.5}`, nil},
+		{`{"level": "debug", "code": 3}`, []string{`level: must be one of "info", "warn". got="debug"`, "code: must be one of 1, 2.5. got=3"}},
+	}
+	for _, tt := range tests {
+		doc, err := object.DecodeJSON([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, v := range s.Validate(doc) {
+			got = append(got, v.String())
+		}
+		if isEq, failMsg := testutils.Equal(strings.Join(tt.want, "; "), strings.Join(got, "; ")); !isEq {
+			t.Errorf("%s: wrong violations: %s", tt.doc, failMsg)
+		}
+	}
+
+	s = setupSchemaWithInput(t, "schema $src { name: string maxlen(3) }")
+	doc, _ := object.DecodeJSON([]byte(`{"name": "héllo"}`))
+	violations := s.Validate(doc)
+	if len(violations) != 1 {
+		t.Fatalf("expected 1 violation. got=%d", len(violations))
+	}
+	if isEq, failMsg := testutils.Equal("name: must be at most 3 characters. got=5", violations[0].String()); !isEq {
+		t.Errorf("wrong violation: %s", failMsg)
+	}
+}
+
+func TestFromProgramErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"schema $src { a: strng }", "schema error at 1:18:\n\tunknown type \"strng\""},
+		{"schema $src { a: int }\nschema $src { b: int }", "schema error at 2:1:\n\t$src already has a schema"},
+		{"schema $src { a: int enum('x') }", "schema error at 1:27:\n\tenum value \"x\" is not a int"},
+		{"schema $src { a: int enum(b) }", "schema error at 1:27:\n\tenum values must be literals. got=b"},
+		{"schema $src { a: int maxlen(3) }", "schema error at 1:22:\n\tmaxlen only applies to strings. got=int"},
+		{"schema $src { a: string maxlen(0) }", "schema error at 1:25:\n\tmaxlen takes one positive integer"},
+		{"schema $src { a: string pattern('x') }", "schema error at 1:25:\n\tunknown constraint \"pattern\". expected enum or maxlen"},
+	}
+	for _, tt := range tests {
+		l := lexer.New([]rune(tt.input))
+		program, err := parser.New(l).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = FromProgram(program, l.InputRunes(), "$src")
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.input)
+		}
+		if !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: wrong error. want prefix=%q got=%q", tt.input, tt.want, err.Error())
+		}
+	}
+}
+
+func TestFromJSONSchema(t *testing.T) {
+	s, err := FromJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["id", "user"],
+		"properties": {
+			"id": {"type": "integer"},
+			"user": {"properties": {"name": {"type": ["string", "null"]}}, "required": ["name"]},
+			"scores": {"type": "array", "items": {"type": "number"}},
+			"level": {"type": "string", "enum": ["info", "warn"], "maxLength": 4}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, f := range s.Fields {
+		got = append(got, f.Name+":"+f.Type.String())
+	}
+	if isEq, failMsg := testutils.Equal("id:integer, level:string, scores:array<number>, user:object", strings.Join(got, ", ")); !isEq {
+		t.Errorf("wrong fields: %s", failMsg)
+	}
+
+	doc, _ := object.DecodeJSON([]byte(`{"id": 1, "user": {"name": null}, "scores": [1, 2.5]}`))
+	if violations := s.Validate(doc); len(violations) > 0 {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+	doc, _ = object.DecodeJSON([]byte(`{"id": 1.5, "user": {}, "level": "debug"}`))
+	got = nil
+	for _, v := range s.Validate(doc) {
+		got = append(got, v.String())
+	}
+	if isEq, failMsg := testutils.Equal("id: must be integer. got=FLOAT; level: must be one of \"info\", \"warn\". got=\"debug\"; user.name: missing required field", strings.Join(got, "; ")); !isEq {
+		t.Errorf("wrong violations: %s", failMsg)
+	}
+
+	invalid := []struct {
+		input string
+		want  string
+	}{
+		{`{"type": "array"}`, "invalid JSON schema: the root type must be object. got=array"},
+		{`{"properties": {"a": {"type": "text"}}}`, "invalid JSON schema: unknown type \"text\" for a"},
+		{`{"properties": {"a": {"type": 5}}}`, "invalid JSON schema: type of a must be a string or a list of strings"},
+	}
+	for _, tt := range invalid {
+		_, err := FromJSONSchema([]byte(tt.input))
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.input)
+		}
+		if isEq, failMsg := testutils.Equal(tt.want, err.Error()); !isEq {
+			t.Errorf("wrong error: %s", failMsg)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	fields := Fields{{Name: "user"}, {Name: "timestamp"}}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"usr", "user"},
+		{"timestmap", "timestamp"},
+		{"host", ""},
+	}
+	for _, tt := range tests {
+		if isEq, failMsg := testutils.Equal(tt.want, fields.Suggest(tt.name)); !isEq {
+			t.Errorf("%s: wrong suggestion: %s", tt.name, failMsg)
+		}
+	}
+}
+
+func setupSchemaWithInput(t *testing.T, input string) *Schema {
+	l := lexer.New([]rune(input))
+	program, err := parser.New(l).ParseProgram()
+	if err != nil {
+		t.Fatalf("setupSchemaWithInput: %s", err.Error())
+	}
+	s, err := FromProgram(program, l.InputRunes(), "$src")
+	if err != nil {
+		t.Fatalf("setupSchemaWithInput: %s", err.Error())
+	}
+	return s
+}
@@ -33,6 +33,25 @@ func (p *Position) SetPosition(start int, end int) {
 	p.end = end
 }
 
+// returns the 1-indexed line and column of the target rune index, since that's more useful for people-facing errors in editors
+func LineAndColumn(inputRunes []rune, targetIdx int) (int, int) {
+	if targetIdx > len(inputRunes) {
+		targetIdx = len(inputRunes)
+	}
+	line := 1
+	col := 1
+	for _, r := range inputRunes[:targetIdx] {
+		switch r {
+		case '\n': // reset if newline
+			line++
+			col = 1
+		default:
+			col++
+		}
+	}
+	return line, col
+}
+
 type TokenType int
 
 func (t TokenType) HumanString() string {
@@ -73,8 +92,10 @@ const (
 
 	//delimiters
 	DOT       // "."
+	ELLIPSIS  // "..."
 	COMMA     // ","
 	COLON     // ":"
+	QUESTION  // "?"
 	SEMICOLON // ";"
 	LSQUARE   // "["
 	RSQUARE   // "]"
@@ -90,6 +111,14 @@ const (
 	NULL
 	TRUE
 	FALSE
+	TRY
+	CATCH
+	SCHEMA
+	LET
+	CONST
+	IMPORT
+	AS
+	FNDEF // "fn"
 
 	//mem accessors
 	ENV  // "$env"
@@ -109,17 +138,31 @@ func LookupKeyword(ident string) TokenType {
 	return IDENT
 }
 
+// IsKeyword reports whether word is a keyword like if or try. fields can still have those names after a dot, like $src.try
+func IsKeyword(word string) bool {
+	_, ok := keywordTable[word]
+	return ok && word[0] != '$'
+}
+
 var keywordTable = map[string]TokenType{
-	"pipe":  PIPEDEF,
-	"$env":  ENV,
-	"$var":  VAR,
-	"$src":  SRC,
-	"$dest": DEST,
-	"if":    IF,
-	"else":  ELSE,
-	"null":  NULL,
-	"true":  TRUE,
-	"false": FALSE,
+	"pipe":   PIPEDEF,
+	"fn":     FNDEF,
+	"$env":   ENV,
+	"$var":   VAR,
+	"$src":   SRC,
+	"$dest":  DEST,
+	"if":     IF,
+	"else":   ELSE,
+	"null":   NULL,
+	"true":   TRUE,
+	"false":  FALSE,
+	"try":    TRY,
+	"catch":  CATCH,
+	"schema": SCHEMA,
+	"let":    LET,
+	"const":  CONST,
+	"import": IMPORT,
+	"as":     AS,
 }
 
 var stringTable = map[TokenType]string{
@@ -142,8 +185,10 @@ var stringTable = map[TokenType]string{
 	GTEQ:        `greater than or equal to (">=")`,
 	LTEQ:        `less than or equal to ("<=")`,
 	DOT:         `dot (".")`,
+	ELLIPSIS:    `ellipsis ("...")`,
 	COMMA:       `comma (",")`,
 	COLON:       `colon (":")`,
+	QUESTION:    `question mark ("?")`,
 	SEMICOLON:   `semicolon (";")`,
 	LSQUARE:     `left square bracket ("[")`,
 	RSQUARE:     `right square bracket ("]")`,
@@ -156,6 +201,14 @@ var stringTable = map[TokenType]string{
 	ELSE:        `else statement ("else")`,
 	TRUE:        "true",
 	FALSE:       "false",
+	TRY:         `try statement ("try")`,
+	CATCH:       `catch statement ("catch")`,
+	SCHEMA:      `schema declaration ("schema")`,
+	LET:         `variable declaration ("let")`,
+	CONST:       `constant declaration ("const")`,
+	IMPORT:      `import statement ("import")`,
+	AS:          `import alias ("as")`,
+	FNDEF:       `function definition ("fn")`,
 	NULL:        "null token",
 	ENV:         "$env",
 	VAR:         "$var",
@@ -0,0 +1,55 @@
+package mathutils
+
+import "math"
+
+// checked int64 arithmetic. each returns false instead of silently wrapping around when the result overflows.
+
+func AddInt64(a int64, b int64) (int64, bool) {
+	ret := a + b
+	if (a > 0 && b > 0 && ret < 0) || (a < 0 && b < 0 && ret >= 0) {
+		return 0, false
+	}
+	return ret, true
+}
+
+func SubInt64(a int64, b int64) (int64, bool) {
+	ret := a - b
+	if (a >= 0 && b < 0 && ret < 0) || (a < 0 && b > 0 && ret >= 0) {
+		return 0, false
+	}
+	return ret, true
+}
+
+func MulInt64(a int64, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	ret := a * b
+	if ret/b != a {
+		return 0, false
+	}
+	return ret, true
+}
+
+// callers are expected to have already checked for a zero divisor
+func DivInt64(a int64, b int64) (int64, bool) {
+	if a == math.MinInt64 && b == -1 {
+		return 0, false
+	}
+	return a / b, true
+}
+
+func NegInt64(a int64) (int64, bool) {
+	if a == math.MinInt64 {
+		return 0, false
+	}
+	return -a, true
+}
+
+// reports whether a float can be converted to an int64 without overflowing
+func FloatFitsInt64(f float64) bool {
+	return !math.IsNaN(f) && f >= math.MinInt64 && f < math.MaxInt64
+}